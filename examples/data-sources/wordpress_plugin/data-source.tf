@@ -0,0 +1,9 @@
+# WordPress Plugin Data Source Example
+
+data "wordpress_plugin" "woocommerce" {
+  name = "woocommerce"
+}
+
+output "woocommerce_version" {
+  value = data.wordpress_plugin.woocommerce.installed ? data.wordpress_plugin.woocommerce.version : "not installed"
+}
@@ -0,0 +1,15 @@
+# WordPress Plugins Data Source Example
+
+data "wordpress_plugins" "active" {
+  status = "active"
+}
+
+locals {
+  woocommerce_active = contains(data.wordpress_plugins.active.plugins[*].name, "woocommerce")
+}
+
+resource "wordpress_plugin" "woocommerce_payments" {
+  count  = local.woocommerce_active ? 1 : 0
+  name   = "woocommerce-payments"
+  active = true
+}
@@ -12,4 +12,92 @@ provider "wordpress" {
   ssh_target  = "user@example.com" # SSH target for WordPress host
   remote_path = "/var/www/html"    # Path to WordPress installation
   allow_root  = true               # Use --allow-root flag with WP-CLI
-}
\ No newline at end of file
+}
+
+# Run wp on the host over the built-in SSH client; no local PHP or WP-CLI needed
+provider "wordpress" {
+  alias       = "ssh"
+  transport   = "ssh"
+  remote_path = "/var/www/html"
+
+  # Retry read-only commands when the bastion drops the connection
+  retry_attempts = 5
+  retry_delay    = "2s"
+
+  # Record every change made to production for change management
+  audit_log_path = "${path.root}/wordpress-audit.jsonl"
+
+  ssh = {
+    host             = "wp.example.com"
+    user             = "deploy"
+    private_key_path = pathexpand("~/.ssh/id_ed25519")
+
+    jump_host = {
+      host = "bastion.example.com"
+    }
+  }
+}
+
+# Run wp inside a container through the Docker Engine API
+provider "wordpress" {
+  alias       = "docker"
+  transport   = "docker"
+  remote_path = "/var/www/html"
+
+  # Keep managing WordPress when a plugin causes a fatal error
+  skip_plugins = true
+  user         = "admin"
+
+  env = {
+    WP_CLI_CACHE_DIR = "/tmp/wp-cli-cache"
+  }
+
+  docker = {
+    container = "wordpress"
+    user      = "www-data"
+  }
+}
+
+# Run wp in a ready pod selected by label through the Kubernetes pod exec API
+provider "wordpress" {
+  alias       = "kubernetes"
+  transport   = "kubernetes"
+  remote_path = "/var/www/html"
+
+  kubernetes = {
+    context        = "production"
+    namespace      = "wordpress"
+    label_selector = "app.kubernetes.io/name=wordpress"
+    container      = "wordpress"
+  }
+}
+
+# Run wp on the machine Terraform runs on, e.g. in a pipeline on the WordPress host
+provider "wordpress" {
+  alias       = "local"
+  transport   = "local"
+  remote_path = "/var/www/html"
+
+  local = {
+    wp_path  = "/usr/local/bin/wp"
+    php_path = "/usr/bin/php8.2"
+    user     = "www-data"
+  }
+}
+
+# Manage plugins and settings through the WordPress REST API instead of WP-CLI
+variable "wordpress_application_password" {
+  type      = string
+  sensitive = true
+}
+
+provider "wordpress" {
+  alias   = "rest"
+  backend = "rest"
+
+  rest = {
+    url                  = "https://example.com"
+    username             = "admin"
+    application_password = var.wordpress_application_password
+  }
+}
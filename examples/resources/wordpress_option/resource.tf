@@ -0,0 +1,32 @@
+# WordPress Option Resource Example
+
+resource "wordpress_option" "blogname" {
+  name               = "blogname"
+  value              = "My WordPress Site"
+  restore_on_destroy = true # Put back the original title instead of deleting a core option
+}
+
+resource "wordpress_option" "permalinks" {
+  name  = "permalink_structure"
+  value = "/%postname%/"
+}
+
+resource "wordpress_option" "plugin_settings" {
+  name = "my_plugin_settings"
+  value_json = jsonencode({
+    enabled = true
+    mode    = "strict"
+  })
+  autoload = false
+}
+
+variable "license_key" {
+  type      = string
+  sensitive = true
+}
+
+resource "wordpress_option" "license" {
+  name      = "my_plugin_license_key"
+  value     = var.license_key
+  sensitive = true # Redact the key from errors, logs and the audit log
+}
@@ -0,0 +1,7 @@
+import {
+  to = wordpress_plugin.akismet
+  identity = {
+    name = "akismet"
+    url  = "https://example.com/blog" # Optional, for multisite subsites
+  }
+}
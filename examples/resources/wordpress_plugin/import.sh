@@ -0,0 +1,5 @@
+# Import a plugin by slug
+terraform import wordpress_plugin.akismet akismet
+
+# Import a plugin on a multisite subsite: <http:// or https:// site URL>/<slug>
+terraform import wordpress_plugin.akismet https://example.com/blog/akismet
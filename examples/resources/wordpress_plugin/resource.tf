@@ -8,4 +8,32 @@ resource "wordpress_plugin" "akismet" {
 resource "wordpress_plugin" "hello_dolly" {
   name   = "hello-dolly"
   active = false # Install but don't activate Hello Dolly
-}
\ No newline at end of file
+}
+
+resource "wordpress_plugin" "classic_editor" {
+  name    = "classic-editor"
+  version = "1.6.5" # Pin a version; changing it upgrades or downgrades in place
+  active  = true
+}
+
+resource "wordpress_plugin" "wordpress_seo" {
+  name          = "wordpress-seo"
+  active        = true
+  update_policy = "minor" # Plan updates within the installed major version
+}
+
+resource "wordpress_plugin" "in_house" {
+  source   = "${path.module}/files/in-house-plugin.zip" # Uploaded to the WordPress host
+  checksum = filesha256("${path.module}/files/in-house-plugin.zip")
+  active   = true
+}
+
+resource "wordpress_plugin" "premium" {
+  name     = "premium-plugin" # Optional; must match the directory inside the archive
+  source   = "https://downloads.example.com/premium-plugin-2.1.0.zip"
+  checksum = "3f5c0e0f4b2f9a7d6c1e8b9a0d2c4e6f8a1b3c5d7e9f0a2b4c6d8e0f1a3b5c7d"
+
+  timeouts {
+    create = "15m" # Large archives can take a while to download and install
+  }
+}
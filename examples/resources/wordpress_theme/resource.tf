@@ -0,0 +1,12 @@
+# WordPress Theme Resource Example
+
+resource "wordpress_theme" "twentytwentyfour" {
+  name   = "twentytwentyfour"
+  active = true # Install and switch the site to this theme
+}
+
+resource "wordpress_theme" "twentytwentythree" {
+  name    = "twentytwentythree"
+  version = "1.5" # Install a specific version without activating it
+  active  = false
+}
@@ -0,0 +1,22 @@
+# WordPress User Resource Example
+
+variable "editor_password" {
+  type      = string
+  sensitive = true
+}
+
+resource "wordpress_user" "editor" {
+  login        = "jane"
+  email        = "jane@example.com"
+  display_name = "Jane Doe"
+  first_name   = "Jane"
+  last_name    = "Doe"
+  roles        = ["editor"]
+
+  # Write-only: never stored in state. Bump the version to rotate the password.
+  password_wo         = var.editor_password
+  password_wo_version = 1
+
+  # Give Jane's posts to the admin user when she is removed
+  reassign_to = 1
+}
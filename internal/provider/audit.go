@@ -0,0 +1,110 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// auditRecord is one line of the audit log, describing a single command run
+// against WordPress. Requests sent by the REST backend are recorded with the
+// HTTP method and route as Argv and the response status as Status.
+type auditRecord struct {
+	Timestamp    time.Time `json:"timestamp"`
+	ResourceType string    `json:"resource_type,omitempty"`
+	ResourceID   string    `json:"resource_id,omitempty"`
+	Argv         []string  `json:"argv"`
+	Target       string    `json:"target"`
+	ExitCode     int       `json:"exit_code"`
+	Status       int       `json:"status,omitempty"`
+	DurationMS   int64     `json:"duration_ms"`
+	Class        string    `json:"class"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// auditLog appends a JSON record for each command run to a file. Mutating
+// commands are always recorded, read-only commands only on request.
+type auditLog struct {
+	path     string
+	readOnly bool
+
+	// mu keeps records written concurrently from interleaving.
+	mu sync.Mutex
+}
+
+// newAuditLog returns the audit log writing to path, making sure the file
+// can be created and appended to.
+func newAuditLog(path string, readOnly bool) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return &auditLog{path: path, readOnly: readOnly}, nil
+}
+
+// record appends rec to the log unless it is a read-only command that is
+// not recorded. A failure to write is logged rather than failing the
+// command, which has already run.
+func (a *auditLog) record(ctx context.Context, rec auditRecord) {
+	if a == nil || (rec.Class == commandReadOnly && !a.readOnly) {
+		return
+	}
+	if resource, ok := ctx.Value(auditResourceKey{}).(auditResource); ok {
+		rec.ResourceType, rec.ResourceID = resource.Type, resource.ID
+	}
+
+	if err := a.write(rec); err != nil {
+		tflog.Error(ctx, "Unable to write audit log", map[string]interface{}{
+			"path":        a.path,
+			logFieldError: err.Error(),
+		})
+	}
+}
+
+func (a *auditLog) write(rec auditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("appending to %s: %w", a.path, err)
+	}
+	return f.Close()
+}
+
+// auditResourceKey is the context key of the resource commands run for.
+type auditResourceKey struct{}
+
+// auditResource identifies the resource or data source a command runs for.
+// Terraform does not tell providers resource addresses, so the resource is
+// identified by its type and the name or ID WordPress knows it by.
+type auditResource struct {
+	Type string
+	ID   string
+}
+
+// withAuditResource returns ctx annotated with the resource commands run
+// with it are recorded under.
+func withAuditResource(ctx context.Context, typeName, id string) context.Context {
+	return context.WithValue(ctx, auditResourceKey{}, auditResource{Type: typeName, ID: id})
+}
@@ -0,0 +1,124 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAuditLog decodes the records in the audit log at path.
+func readAuditLog(t *testing.T, path string) []auditRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var records []auditRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec auditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec), scanner.Text())
+		records = append(records, rec)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func newTestAuditLog(t *testing.T, readOnly bool) *auditLog {
+	t.Helper()
+	audit, err := newAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"), readOnly)
+	require.NoError(t, err)
+	return audit
+}
+
+func TestAuditLog_RecordsMutatingCommands(t *testing.T) {
+	audit := newTestAuditLog(t, false)
+	cfg := &WPConfig{Exec: &mockCommander{output: []byte("[]")}, SSHTarget: "deploy@wp.example.com", audit: audit}
+	ctx := withAuditResource(context.Background(), "wordpress_user", "bob")
+
+	before := time.Now().UTC()
+	require.NoError(t, runWP(ctx, cfg, "user", "create", "bob", "bob@example.com", "--user_pass=hunter2"))
+	_, err := runWPWithOutput(ctx, cfg, "user", "get", "bob", "--format=json")
+	require.NoError(t, err)
+
+	records := readAuditLog(t, audit.path)
+	require.Len(t, records, 1)
+	rec := records[0]
+	assert.Equal(t, "wordpress_user", rec.ResourceType)
+	assert.Equal(t, "bob", rec.ResourceID)
+	assert.Equal(t, []string{"wp", "--ssh=deploy@wp.example.com", "user", "create", "bob", "bob@example.com", "--user_pass=***"}, rec.Argv)
+	assert.Equal(t, "deploy@wp.example.com", rec.Target)
+	assert.Equal(t, 0, rec.ExitCode)
+	assert.Equal(t, commandMutating, rec.Class)
+	assert.False(t, rec.Timestamp.Before(before.Truncate(time.Second)))
+	assert.Empty(t, rec.Error)
+}
+
+func TestAuditLog_ReadOnlyCommands(t *testing.T) {
+	audit := newTestAuditLog(t, true)
+	cfg := &WPConfig{Exec: &mockCommander{output: []byte("[]")}, audit: audit}
+
+	_, err := runWPWithOutput(context.Background(), cfg, "plugin", "list", "--format=json")
+	require.NoError(t, err)
+
+	records := readAuditLog(t, audit.path)
+	require.Len(t, records, 1)
+	assert.Equal(t, commandReadOnly, records[0].Class)
+	assert.Empty(t, records[0].ResourceType)
+}
+
+func TestAuditLog_RecordsFailures(t *testing.T) {
+	audit := newTestAuditLog(t, false)
+	cfg := &WPConfig{Exec: &splitCommander{stderr: []byte("Error: boom"), err: &dockerExitError{ExitCode: 1}}, audit: audit}
+	require.Error(t, runWP(context.Background(), cfg, "plugin", "install", "akismet"))
+
+	cfg = &WPConfig{Exec: blockingCommander{}, CommandTimeout: 20 * time.Millisecond, audit: audit}
+	require.Error(t, runWP(context.Background(), cfg, "plugin", "install", "akismet"))
+
+	records := readAuditLog(t, audit.path)
+	require.Len(t, records, 2)
+	assert.Equal(t, 1, records[0].ExitCode)
+	assert.Empty(t, records[0].Error)
+	assert.Equal(t, -1, records[1].ExitCode)
+	assert.Equal(t, "context deadline exceeded", records[1].Error)
+	// The timeout starts before the command does, so allow for truncation
+	assert.GreaterOrEqual(t, records[1].DurationMS, int64(10))
+}
+
+func TestAuditLog_ConcurrentRecords(t *testing.T) {
+	audit := newTestAuditLog(t, false)
+	cfg := &WPConfig{Exec: &mockCommander{}, audit: audit}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, runWP(context.Background(), cfg, "plugin", "activate", "akismet"))
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, readAuditLog(t, audit.path), 20)
+}
+
+func TestNewAuditLog_InvalidPath(t *testing.T) {
+	_, err := newAuditLog(filepath.Join(t.TempDir(), "missing", "audit.jsonl"), false)
+	assert.Error(t, err)
+}
+
+func TestAuditLog_Nil(t *testing.T) {
+	var audit *auditLog
+	audit.record(context.Background(), auditRecord{Class: commandMutating})
+}
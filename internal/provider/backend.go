@@ -0,0 +1,157 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// Backends select the API resources use to manage WordPress.
+const (
+	// backendWPCLI runs WP-CLI commands through the configured transport.
+	backendWPCLI = "wp-cli"
+
+	// backendREST calls the WordPress REST API with an application password.
+	backendREST = "rest"
+)
+
+// Backend performs the WordPress operations behind the plugin and option
+// resources and data sources. cfg carries per-resource settings such as the
+// multisite URL.
+type Backend interface {
+	// ListPlugins returns the installed plugins, optionally filtered by status.
+	ListPlugins(ctx context.Context, cfg *WPConfig, status string) ([]pluginInfo, error)
+	// GetPlugin returns a single installed plugin, or errPluginNotFound.
+	GetPlugin(ctx context.Context, cfg *WPConfig, slug string) (*pluginInfo, error)
+	// InstallPlugin installs a plugin from wordpress.org or an archive.
+	InstallPlugin(ctx context.Context, cfg *WPConfig, install pluginInstall) error
+	// UpdatePlugin moves a plugin to version, or to the newest version
+	// (within the major version if minor is set) when version is empty.
+	UpdatePlugin(ctx context.Context, cfg *WPConfig, slug, version string, minor bool) error
+	// MinorPluginUpdate returns the newest version of a plugin within its
+	// installed major version, or "" when there is none.
+	MinorPluginUpdate(ctx context.Context, cfg *WPConfig, slug string) (string, error)
+	// SetPluginActive activates or deactivates a plugin.
+	SetPluginActive(ctx context.Context, cfg *WPConfig, slug string, active bool) error
+	// DeletePlugin removes a plugin.
+	DeletePlugin(ctx context.Context, cfg *WPConfig, slug string) error
+
+	// GetOption returns the JSON encoded value of an option, or an error
+	// wrapping errOptionNotFound.
+	GetOption(ctx context.Context, cfg *WPConfig, name string) (string, error)
+	// GetOptionAutoload returns whether WordPress autoloads the option, or
+	// errOptionAutoloadUnsupported when the backend cannot read the flag.
+	GetOptionAutoload(ctx context.Context, cfg *WPConfig, name string) (bool, error)
+	// UpdateOption stores the value and autoload flag planned in m.
+	UpdateOption(ctx context.Context, cfg *WPConfig, m wordpressOptionModel) error
+	// DeleteOption removes an option.
+	DeleteOption(ctx context.Context, cfg *WPConfig, name string) error
+}
+
+// pluginInstall describes a plugin to install. Archive, when set, is a path on
+// the WordPress host to install from instead of the plugin directory.
+type pluginInstall struct {
+	Slug     string
+	Archive  string
+	Version  string
+	Activate bool
+}
+
+// backend returns the Backend that serves resources for cfg.
+func (c *WPConfig) backend() Backend {
+	if c.Backend != nil {
+		return c.Backend
+	}
+	return wpCLIBackend{}
+}
+
+// requireWPCLIBackend reports an error for resources that only the WP-CLI
+// backend can manage.
+func requireWPCLIBackend(cfg *WPConfig, resourceType string, diags *diag.Diagnostics) {
+	if _, ok := cfg.backend().(wpCLIBackend); ok {
+		return
+	}
+	diags.AddError("Unsupported backend",
+		fmt.Sprintf("%s requires the %q backend. The WordPress REST API cannot manage it; "+
+			"configure a separate provider with backend = %q for this resource.", resourceType, backendWPCLI, backendWPCLI))
+}
+
+// validateBackend checks the backend settings, and the transport settings
+// when the WP-CLI backend is selected.
+func validateBackend(data WordpressProviderModel, diags *diag.Diagnostics) {
+	if data.Backend.IsUnknown() {
+		return
+	}
+
+	if defaultStringIfUnset(data.Backend, backendWPCLI) == backendWPCLI {
+		if data.REST != nil {
+			diags.AddAttributeError(path.Root("rest"), "Unexpected rest block",
+				fmt.Sprintf("The rest block is only used when backend is %q.", backendREST))
+		}
+		validateTransport(data, diags)
+		return
+	}
+
+	if data.REST == nil {
+		diags.AddAttributeError(path.Root("rest"), "Missing rest block",
+			fmt.Sprintf("The rest block is required when backend is %q.", backendREST))
+	}
+
+	if !data.Transport.IsNull() {
+		diags.AddAttributeError(path.Root("transport"), "Unexpected transport",
+			fmt.Sprintf("transport is not used when backend is %q.", backendREST))
+	}
+	if !data.SSHTarget.IsNull() {
+		diags.AddAttributeError(path.Root("ssh_target"), "Unexpected ssh_target",
+			fmt.Sprintf("ssh_target is not used when backend is %q.", backendREST))
+	}
+	wpCLISettings := []struct {
+		name  string
+		value attr.Value
+	}{
+		{"user", data.User},
+		{"skip_plugins", data.SkipPlugins},
+		{"skip_themes", data.SkipThemes},
+		{"context", data.Context},
+		{"extra_args", data.ExtraArgs},
+		{"env", data.Env},
+	}
+	for _, setting := range wpCLISettings {
+		if !setting.value.IsNull() {
+			diags.AddAttributeError(path.Root(setting.name), "Unexpected "+setting.name,
+				fmt.Sprintf("%s is not used when backend is %q.", setting.name, backendREST))
+		}
+	}
+	blocks := transportBlocks(data)
+	for _, name := range transportBlockNames {
+		if blocks[name] {
+			diags.AddAttributeError(path.Root(name), fmt.Sprintf("Unexpected %s block", name),
+				fmt.Sprintf("The %s block is not used when backend is %q.", name, backendREST))
+		}
+	}
+}
+
+// newBackend returns the Backend selected by the provider configuration, or
+// nil for the WP-CLI backend.
+func newBackend(data WordpressProviderModel, diags *diag.Diagnostics) Backend {
+	if defaultStringIfUnset(data.Backend, backendWPCLI) != backendREST {
+		return nil
+	}
+	if data.REST == nil {
+		diags.AddAttributeError(path.Root("rest"), "Missing rest block",
+			fmt.Sprintf("The rest block is required when backend is %q.", backendREST))
+		return nil
+	}
+	b, err := newRESTBackend(data.REST)
+	if err != nil {
+		diags.AddAttributeError(path.Root("rest"), "Invalid REST configuration", err.Error())
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,416 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+var _ Backend = &restBackend{}
+
+// restSettingNames maps core option names to the names /wp/v2/settings
+// exposes them under. Other options are looked up by their own name.
+var restSettingNames = map[string]string{
+	"blogname":        "title",
+	"blogdescription": "description",
+	"siteurl":         "url",
+	"admin_email":     "email",
+	"timezone_string": "timezone",
+	"WPLANG":          "language",
+}
+
+// restBackendModel is the provider's rest block.
+type restBackendModel struct {
+	URL                 types.String `tfsdk:"url"`
+	Username            types.String `tfsdk:"username"`
+	ApplicationPassword types.String `tfsdk:"application_password"`
+}
+
+// restBackendAttribute returns the schema of the provider's rest block.
+func restBackendAttribute() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Optional:            true,
+		MarkdownDescription: "Connection settings for the `rest` backend.",
+		Attributes: map[string]schema.Attribute{
+			"url": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The URL of the WordPress site, e.g. `https://example.com`. The REST API is expected under `/wp-json/`.",
+			},
+			"username": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The user the application password belongs to.",
+			},
+			"application_password": schema.StringAttribute{
+				Required:            true,
+				Sensitive:           true,
+				MarkdownDescription: "An application password for `username`, created under Users > Profile > Application Passwords.",
+			},
+		},
+	}
+}
+
+// restBackend manages WordPress through the REST API, authenticating with
+// an application password.
+type restBackend struct {
+	client   *http.Client
+	url      string
+	username string
+	password string
+}
+
+// newRESTBackend returns the backend for the rest block.
+func newRESTBackend(m *restBackendModel) (*restBackend, error) {
+	siteURL := strings.TrimSuffix(defaultStringIfUnset(m.URL, ""), "/")
+	u, err := url.Parse(siteURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("rest url %q must be an http:// or https:// URL", siteURL)
+	}
+	return &restBackend{
+		client:   &http.Client{},
+		url:      siteURL,
+		username: defaultStringIfUnset(m.Username, ""),
+		password: defaultStringIfUnset(m.ApplicationPassword, ""),
+	}, nil
+}
+
+// restError is an error response from the REST API.
+type restError struct {
+	Method  string
+	Route   string
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *restError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("WordPress REST API %s %s returned %d: %s", e.Method, e.Route, e.Status, e.Message)
+	}
+	return fmt.Sprintf("WordPress REST API %s %s returned %d: %s (%s)", e.Method, e.Route, e.Status, e.Message, e.Code)
+}
+
+// target names the site requests are sent to.
+func (b *restBackend) target() string {
+	return b.url
+}
+
+// request calls a REST route on the site cfg targets, encoding body and
+// decoding the response into out when they are not nil.
+func (b *restBackend) request(ctx context.Context, cfg *WPConfig, method, route string, body, out interface{}) error {
+	ctx = withLogging(ctx)
+	class := commandReadOnly
+	if method != http.MethodGet {
+		class = commandMutating
+		defer cfg.invalidatePlugins()
+	}
+	release, err := cfg.limiter.acquire(ctx, class)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	timeout := cfg.CommandTimeout
+	if timeout <= 0 {
+		timeout = defaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	siteURL := b.url
+	if cfg.URL != "" {
+		siteURL = strings.TrimSuffix(cfg.URL, "/")
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, siteURL+"/wp-json"+route, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(b.username, b.password)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	fields := map[string]interface{}{
+		"method":       method,
+		"route":        route,
+		logFieldTarget: siteURL,
+		logFieldClass:  class,
+	}
+	tflog.SubsystemDebug(ctx, logTransport, "Sending REST API request", fields)
+	start := time.Now()
+	status, data, err := b.send(req)
+	duration := time.Since(start)
+	fields[logFieldDuration] = duration.String()
+	switch {
+	case err != nil:
+		err = fmt.Errorf("WordPress REST API %s %s: %w", method, route, err)
+	case status >= 300:
+		err = newRESTError(method, route, status, data)
+	}
+
+	rec := auditRecord{
+		Timestamp:  start.UTC(),
+		Argv:       []string{method, route},
+		Target:     siteURL,
+		Status:     status,
+		DurationMS: duration.Milliseconds(),
+		Class:      class,
+	}
+	if err != nil {
+		rec.ExitCode = -1
+		rec.Error = err.Error()
+	}
+	cfg.audit.record(ctx, rec)
+
+	if status == 0 {
+		fields[logFieldError] = err.Error()
+		tflog.SubsystemDebug(ctx, logTransport, "REST API request failed", fields)
+		return err
+	}
+	fields["status"] = status
+	tflog.SubsystemDebug(ctx, logTransport, "Received REST API response", fields)
+	if err != nil {
+		return err
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("unable to parse response from %s %s: %v\nOutput: %s", method, route, err, data)
+		}
+	}
+	return nil
+}
+
+// send sends req and returns the response status and body. The status is 0
+// when no response was received.
+func (b *restBackend) send(req *http.Request) (int, []byte, error) {
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, data, err
+}
+
+// newRESTError returns the error for an error response with body data.
+func newRESTError(method, route string, status int, data []byte) *restError {
+	var wpErr struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &wpErr) != nil || wpErr.Message == "" {
+		wpErr.Message = strings.TrimSpace(string(data))
+	}
+	return &restError{Method: method, Route: route, Status: status, Code: wpErr.Code, Message: wpErr.Message}
+}
+
+// restPlugin is a plugin as returned by /wp/v2/plugins.
+type restPlugin struct {
+	Plugin  string `json:"plugin"`
+	Status  string `json:"status"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// info converts the plugin to the form WP-CLI reports. The REST API does not
+// report available updates.
+func (p restPlugin) info() pluginInfo {
+	status := p.Status
+	if status == "network-active" {
+		status = "active-network"
+	}
+	slug, _, _ := strings.Cut(p.Plugin, "/")
+	return pluginInfo{
+		Name:    slug,
+		Title:   p.Name,
+		Status:  status,
+		Version: p.Version,
+		Update:  "none",
+	}
+}
+
+// plugins returns the installed plugins.
+func (b *restBackend) plugins(ctx context.Context, cfg *WPConfig) ([]restPlugin, error) {
+	var plugins []restPlugin
+	if err := b.request(ctx, cfg, http.MethodGet, "/wp/v2/plugins", nil, &plugins); err != nil {
+		return nil, err
+	}
+	return plugins, nil
+}
+
+// plugin returns the installed plugin with the given slug, or errPluginNotFound.
+func (b *restBackend) plugin(ctx context.Context, cfg *WPConfig, slug string) (*restPlugin, error) {
+	plugins, err := b.plugins(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	for i := range plugins {
+		if plugins[i].info().Name == slug {
+			return &plugins[i], nil
+		}
+	}
+	return nil, errPluginNotFound
+}
+
+func (b *restBackend) ListPlugins(ctx context.Context, cfg *WPConfig, status string) ([]pluginInfo, error) {
+	plugins, err := b.plugins(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	infos := []pluginInfo{}
+	for _, p := range plugins {
+		if info := p.info(); status == "" || info.Status == status {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+func (b *restBackend) GetPlugin(ctx context.Context, cfg *WPConfig, slug string) (*pluginInfo, error) {
+	p, err := b.plugin(ctx, cfg, slug)
+	if err != nil {
+		return nil, err
+	}
+	info := p.info()
+	return &info, nil
+}
+
+func (b *restBackend) InstallPlugin(ctx context.Context, cfg *WPConfig, install pluginInstall) error {
+	if install.Archive != "" {
+		return fmt.Errorf("the %s backend cannot install plugins from a source archive", backendREST)
+	}
+	if install.Version != "" {
+		return fmt.Errorf("the %s backend cannot install a specific plugin version; it always installs the latest version", backendREST)
+	}
+	body := map[string]string{"slug": install.Slug, "status": restPluginStatus(install.Activate)}
+	return b.request(ctx, cfg, http.MethodPost, "/wp/v2/plugins", body, nil)
+}
+
+func (b *restBackend) UpdatePlugin(ctx context.Context, cfg *WPConfig, slug, version string, minor bool) error {
+	return fmt.Errorf("the %s backend cannot update plugin %s: the WordPress REST API does not support plugin updates", backendREST, slug)
+}
+
+func (b *restBackend) MinorPluginUpdate(ctx context.Context, cfg *WPConfig, slug string) (string, error) {
+	return "", fmt.Errorf("the %s backend cannot check plugin %s for updates: the WordPress REST API does not support plugin updates", backendREST, slug)
+}
+
+func (b *restBackend) SetPluginActive(ctx context.Context, cfg *WPConfig, slug string, active bool) error {
+	p, err := b.plugin(ctx, cfg, slug)
+	if err != nil {
+		return err
+	}
+	body := map[string]string{"status": restPluginStatus(active)}
+	return b.request(ctx, cfg, http.MethodPost, "/wp/v2/plugins/"+p.Plugin, body, nil)
+}
+
+func (b *restBackend) DeletePlugin(ctx context.Context, cfg *WPConfig, slug string) error {
+	p, err := b.plugin(ctx, cfg, slug)
+	if err != nil {
+		return err
+	}
+	// WordPress refuses to delete an active plugin
+	if p.info().Active() {
+		if err := b.request(ctx, cfg, http.MethodPost, "/wp/v2/plugins/"+p.Plugin, map[string]string{"status": "inactive"}, nil); err != nil {
+			return err
+		}
+	}
+	return b.request(ctx, cfg, http.MethodDelete, "/wp/v2/plugins/"+p.Plugin, nil, nil)
+}
+
+// restPluginStatus returns the REST status for an activation state.
+func restPluginStatus(active bool) string {
+	if active {
+		return "active"
+	}
+	return "inactive"
+}
+
+// restSettingName returns the /wp/v2/settings name of an option.
+func restSettingName(option string) string {
+	if name, ok := restSettingNames[option]; ok {
+		return name
+	}
+	return option
+}
+
+// updateSetting writes a setting and returns its stored JSON value.
+func (b *restBackend) updateSetting(ctx context.Context, cfg *WPConfig, option string, value json.RawMessage) (json.RawMessage, error) {
+	name := restSettingName(option)
+	var settings map[string]json.RawMessage
+	if err := b.request(ctx, cfg, http.MethodPost, "/wp/v2/settings", map[string]json.RawMessage{name: value}, &settings); err != nil {
+		return nil, err
+	}
+	stored, ok := settings[name]
+	if !ok {
+		return nil, errSettingUnavailable(option)
+	}
+	return stored, nil
+}
+
+// errSettingUnavailable reports an option that /wp/v2/settings does not expose.
+func errSettingUnavailable(option string) error {
+	return fmt.Errorf("option %s is not available from /wp/v2/settings; the %s backend can only manage settings registered with show_in_rest", option, backendREST)
+}
+
+func (b *restBackend) GetOption(ctx context.Context, cfg *WPConfig, name string) (string, error) {
+	var settings map[string]json.RawMessage
+	if err := b.request(ctx, cfg, http.MethodGet, "/wp/v2/settings", nil, &settings); err != nil {
+		return "", err
+	}
+	value, ok := settings[restSettingName(name)]
+	if !ok {
+		return "", errSettingUnavailable(name)
+	}
+	return compactJSON(string(value)), nil
+}
+
+// GetOptionAutoload returns errOptionAutoloadUnsupported: the REST API does
+// not expose the flag.
+func (b *restBackend) GetOptionAutoload(ctx context.Context, cfg *WPConfig, name string) (bool, error) {
+	return false, errOptionAutoloadUnsupported
+}
+
+func (b *restBackend) UpdateOption(ctx context.Context, cfg *WPConfig, m wordpressOptionModel) error {
+	if !m.Autoload.IsNull() && !m.Autoload.IsUnknown() {
+		return fmt.Errorf("the %s backend cannot set autoload for option %s", backendREST, m.Name.ValueString())
+	}
+
+	value := json.RawMessage(m.ValueJSON.ValueString())
+	if m.ValueJSON.IsNull() || m.ValueJSON.IsUnknown() {
+		encoded, err := json.Marshal(m.Value.ValueString())
+		if err != nil {
+			return err
+		}
+		value = encoded
+	}
+	_, err := b.updateSetting(ctx, cfg, m.Name.ValueString(), value)
+	return err
+}
+
+// DeleteOption resets the setting; WordPress deletes options set to null.
+func (b *restBackend) DeleteOption(ctx context.Context, cfg *WPConfig, name string) error {
+	_, err := b.updateSetting(ctx, cfg, name, json.RawMessage("null"))
+	return err
+}
@@ -0,0 +1,350 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWordPressAPI emulates the /wp/v2/plugins and /wp/v2/settings routes.
+type fakeWordPressAPI struct {
+	mu       sync.Mutex
+	plugins  []restPlugin
+	settings map[string]json.RawMessage
+	requests []string
+}
+
+func newFakeWordPressAPI() *fakeWordPressAPI {
+	return &fakeWordPressAPI{
+		plugins: []restPlugin{
+			{Plugin: "akismet/akismet", Status: "inactive", Name: "Akismet Anti-spam", Version: "5.3"},
+			{Plugin: "hello", Status: "active", Name: "Hello Dolly", Version: "1.7.2"},
+			{Plugin: "woocommerce/woocommerce", Status: "network-active", Name: "WooCommerce", Version: "9.0.0"},
+		},
+		settings: map[string]json.RawMessage{
+			"title":          json.RawMessage(`"My Site"`),
+			"posts_per_page": json.RawMessage(`10`),
+		},
+	}
+}
+
+func writeWPError(w http.ResponseWriter, status int, code, message string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"code":%q,"message":%q,"data":{"status":%d}}`, code, message, status)
+}
+
+func (f *fakeWordPressAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "abcd efgh ijkl" {
+		writeWPError(w, http.StatusUnauthorized, "rest_not_logged_in", "You are not currently logged in.")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	_, route, _ := strings.Cut(r.URL.Path, "/wp-json")
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case route == "/wp/v2/plugins" && r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(f.plugins)
+
+	case route == "/wp/v2/plugins" && r.Method == http.MethodPost:
+		var body struct{ Slug, Status string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Slug == "missing" {
+			writeWPError(w, http.StatusInternalServerError, "plugins_api_failed", "Plugin not found.")
+			return
+		}
+		plugin := restPlugin{Plugin: body.Slug + "/" + body.Slug, Status: body.Status, Name: body.Slug, Version: "1.0.0"}
+		f.plugins = append(f.plugins, plugin)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(plugin)
+
+	case strings.HasPrefix(route, "/wp/v2/plugins/"):
+		file := strings.TrimPrefix(route, "/wp/v2/plugins/")
+		for i, p := range f.plugins {
+			if p.Plugin != file {
+				continue
+			}
+			if r.Method == http.MethodDelete {
+				if p.Status != "inactive" {
+					writeWPError(w, http.StatusBadRequest, "rest_cannot_delete_active_plugin", "Cannot delete an active plugin.")
+					return
+				}
+				f.plugins = append(f.plugins[:i], f.plugins[i+1:]...)
+				_ = json.NewEncoder(w).Encode(map[string]bool{"deleted": true})
+				return
+			}
+			var body struct{ Status string }
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			f.plugins[i].Status = body.Status
+			_ = json.NewEncoder(w).Encode(f.plugins[i])
+			return
+		}
+		writeWPError(w, http.StatusNotFound, "rest_plugin_not_found", "Plugin not found.")
+
+	case route == "/wp/v2/settings" && r.Method == http.MethodPost:
+		var body map[string]json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		for name, value := range body {
+			if _, ok := f.settings[name]; !ok {
+				continue
+			}
+			// WordPress deletes options set to null and reports their default
+			if string(value) == "null" {
+				value = json.RawMessage(`""`)
+			}
+			f.settings[name] = value
+		}
+		_ = json.NewEncoder(w).Encode(f.settings)
+
+	case route == "/wp/v2/settings":
+		_ = json.NewEncoder(w).Encode(f.settings)
+
+	default:
+		writeWPError(w, http.StatusNotFound, "rest_no_route", "No route was found matching the URL and request method.")
+	}
+}
+
+// newTestRESTBackend starts the fake API and returns a backend connected to it.
+func newTestRESTBackend(t *testing.T, api *fakeWordPressAPI, password string) (*restBackend, string) {
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	backend, err := newRESTBackend(&restBackendModel{
+		URL:                 types.StringValue(server.URL + "/"),
+		Username:            types.StringValue("admin"),
+		ApplicationPassword: types.StringValue(password),
+	})
+	require.NoError(t, err)
+	return backend, server.URL
+}
+
+func TestRESTBackend_ListPlugins(t *testing.T) {
+	api := newFakeWordPressAPI()
+	backend, _ := newTestRESTBackend(t, api, "abcd efgh ijkl")
+	cfg := &WPConfig{Backend: backend}
+
+	plugins, err := listPlugins(context.Background(), cfg, "")
+	require.NoError(t, err)
+	assert.Equal(t, []pluginInfo{
+		{Name: "akismet", Title: "Akismet Anti-spam", Status: "inactive", Version: "5.3", Update: "none"},
+		{Name: "hello", Title: "Hello Dolly", Status: "active", Version: "1.7.2", Update: "none"},
+		{Name: "woocommerce", Title: "WooCommerce", Status: "active-network", Version: "9.0.0", Update: "none"},
+	}, plugins)
+
+	plugins, err = listPlugins(context.Background(), cfg, "active-network")
+	require.NoError(t, err)
+	require.Len(t, plugins, 1)
+	assert.Equal(t, "woocommerce", plugins[0].Name)
+
+	plugins, err = listPlugins(context.Background(), cfg, "must-use")
+	require.NoError(t, err)
+	assert.Empty(t, plugins)
+
+	info, err := getPlugin(context.Background(), cfg, "hello")
+	require.NoError(t, err)
+	assert.True(t, info.Active())
+
+	_, err = getPlugin(context.Background(), cfg, "missing")
+	assert.ErrorIs(t, err, errPluginNotFound)
+}
+
+func TestRESTBackend_PluginLifecycle(t *testing.T) {
+	api := newFakeWordPressAPI()
+	backend, _ := newTestRESTBackend(t, api, "abcd efgh ijkl")
+	cfg := &WPConfig{Backend: backend}
+	ctx := context.Background()
+
+	require.NoError(t, backend.InstallPlugin(ctx, cfg, pluginInstall{Slug: "classic-editor", Activate: true}))
+	info, err := backend.GetPlugin(ctx, cfg, "classic-editor")
+	require.NoError(t, err)
+	assert.True(t, info.Active())
+
+	require.NoError(t, backend.SetPluginActive(ctx, cfg, "akismet", true))
+	info, err = backend.GetPlugin(ctx, cfg, "akismet")
+	require.NoError(t, err)
+	assert.True(t, info.Active())
+
+	// Active plugins are deactivated before they are deleted
+	require.NoError(t, backend.DeletePlugin(ctx, cfg, "classic-editor"))
+	_, err = backend.GetPlugin(ctx, cfg, "classic-editor")
+	assert.ErrorIs(t, err, errPluginNotFound)
+
+	assert.Equal(t, []string{
+		"POST /wp-json/wp/v2/plugins",
+		"GET /wp-json/wp/v2/plugins",
+		"GET /wp-json/wp/v2/plugins",
+		"POST /wp-json/wp/v2/plugins/akismet/akismet",
+		"GET /wp-json/wp/v2/plugins",
+		"GET /wp-json/wp/v2/plugins",
+		"POST /wp-json/wp/v2/plugins/classic-editor/classic-editor",
+		"DELETE /wp-json/wp/v2/plugins/classic-editor/classic-editor",
+		"GET /wp-json/wp/v2/plugins",
+	}, api.requests)
+
+	err = backend.InstallPlugin(ctx, cfg, pluginInstall{Slug: "missing"})
+	var restErr *restError
+	require.ErrorAs(t, err, &restErr)
+	assert.Equal(t, "plugins_api_failed", restErr.Code)
+	assert.EqualError(t, err, "WordPress REST API POST /wp/v2/plugins returned 500: Plugin not found. (plugins_api_failed)")
+
+	assert.ErrorIs(t, backend.SetPluginActive(ctx, cfg, "missing", true), errPluginNotFound)
+}
+
+func TestRESTBackend_AuditLog(t *testing.T) {
+	api := newFakeWordPressAPI()
+	backend, serverURL := newTestRESTBackend(t, api, "abcd efgh ijkl")
+	audit := newTestAuditLog(t, false)
+	cfg := &WPConfig{Backend: backend, audit: audit}
+	ctx := withAuditResource(context.Background(), "wordpress_plugin", "classic-editor")
+
+	require.NoError(t, backend.InstallPlugin(ctx, cfg, pluginInstall{Slug: "classic-editor"}))
+	require.Error(t, backend.InstallPlugin(ctx, cfg, pluginInstall{Slug: "missing"}))
+	require.NoError(t, backend.UpdateOption(ctx, cfg, wordpressOptionModel{
+		Name:      types.StringValue("blogname"),
+		Value:     types.StringValue("New Title"),
+		ValueJSON: types.StringNull(),
+		Autoload:  types.BoolUnknown(),
+	}))
+
+	// Read-only requests such as the plugin list are not recorded by default
+	records := readAuditLog(t, audit.path)
+	require.Len(t, records, 3)
+
+	assert.Equal(t, "wordpress_plugin", records[0].ResourceType)
+	assert.Equal(t, "classic-editor", records[0].ResourceID)
+	assert.Equal(t, []string{"POST", "/wp/v2/plugins"}, records[0].Argv)
+	assert.Equal(t, serverURL, records[0].Target)
+	assert.Equal(t, http.StatusCreated, records[0].Status)
+	assert.Equal(t, 0, records[0].ExitCode)
+	assert.Equal(t, commandMutating, records[0].Class)
+	assert.Empty(t, records[0].Error)
+
+	assert.Equal(t, http.StatusInternalServerError, records[1].Status)
+	assert.Equal(t, -1, records[1].ExitCode)
+	assert.Contains(t, records[1].Error, "Plugin not found.")
+
+	assert.Equal(t, []string{"POST", "/wp/v2/settings"}, records[2].Argv)
+	assert.Equal(t, http.StatusOK, records[2].Status)
+	for _, rec := range records {
+		assert.NotContains(t, fmt.Sprint(rec), "abcd efgh ijkl")
+	}
+}
+
+func TestRESTBackend_UnsupportedPluginOperations(t *testing.T) {
+	backend, _ := newTestRESTBackend(t, newFakeWordPressAPI(), "abcd efgh ijkl")
+	cfg := &WPConfig{Backend: backend}
+	ctx := context.Background()
+
+	assert.ErrorContains(t, backend.InstallPlugin(ctx, cfg, pluginInstall{Slug: "akismet", Archive: "/tmp/akismet.zip"}),
+		"cannot install plugins from a source archive")
+	assert.ErrorContains(t, backend.InstallPlugin(ctx, cfg, pluginInstall{Slug: "akismet", Version: "5.3"}),
+		"cannot install a specific plugin version")
+	assert.ErrorContains(t, backend.UpdatePlugin(ctx, cfg, "akismet", "5.4", false),
+		"the WordPress REST API does not support plugin updates")
+	_, err := backend.MinorPluginUpdate(ctx, cfg, "akismet")
+	assert.ErrorContains(t, err, "the WordPress REST API does not support plugin updates")
+}
+
+func TestRESTBackend_Unauthorized(t *testing.T) {
+	backend, _ := newTestRESTBackend(t, newFakeWordPressAPI(), "wrong")
+
+	_, err := backend.ListPlugins(context.Background(), &WPConfig{}, "")
+	var restErr *restError
+	require.ErrorAs(t, err, &restErr)
+	assert.Equal(t, http.StatusUnauthorized, restErr.Status)
+	assert.Equal(t, "rest_not_logged_in", restErr.Code)
+}
+
+func TestRESTBackend_SiteURL(t *testing.T) {
+	api := newFakeWordPressAPI()
+	backend, serverURL := newTestRESTBackend(t, api, "abcd efgh ijkl")
+
+	cfg := (&WPConfig{Backend: backend}).forSite(types.StringValue(serverURL + "/blog/"))
+	_, err := getPlugin(context.Background(), cfg, "akismet")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GET /blog/wp-json/wp/v2/plugins"}, api.requests)
+}
+
+func TestRESTBackend_Options(t *testing.T) {
+	api := newFakeWordPressAPI()
+	backend, _ := newTestRESTBackend(t, api, "abcd efgh ijkl")
+	cfg := &WPConfig{Backend: backend}
+	ctx := context.Background()
+
+	// Core options are mapped to their settings names
+	value, err := getOptionJSON(ctx, cfg, "blogname")
+	require.NoError(t, err)
+	assert.Equal(t, `"My Site"`, value)
+
+	// The REST API does not expose the autoload flag
+	autoload, err := getOptionAutoload(ctx, cfg, "blogname")
+	require.NoError(t, err)
+	assert.True(t, autoload.IsNull())
+	_, err = backend.GetOptionAutoload(ctx, cfg, "blogname")
+	assert.ErrorIs(t, err, errOptionAutoloadUnsupported)
+
+	require.NoError(t, backend.UpdateOption(ctx, cfg, wordpressOptionModel{
+		Name:      types.StringValue("blogname"),
+		Value:     types.StringValue("Renamed"),
+		ValueJSON: types.StringNull(),
+		Autoload:  types.BoolUnknown(),
+	}))
+	assert.JSONEq(t, `"Renamed"`, string(api.settings["title"]))
+
+	require.NoError(t, backend.UpdateOption(ctx, cfg, wordpressOptionModel{
+		Name:      types.StringValue("posts_per_page"),
+		Value:     types.StringNull(),
+		ValueJSON: types.StringValue(`25`),
+		Autoload:  types.BoolNull(),
+	}))
+	value, err = getOptionJSON(ctx, cfg, "posts_per_page")
+	require.NoError(t, err)
+	assert.Equal(t, `25`, value)
+
+	require.NoError(t, backend.DeleteOption(ctx, cfg, "blogname"))
+	assert.JSONEq(t, `""`, string(api.settings["title"]))
+
+	err = backend.UpdateOption(ctx, cfg, wordpressOptionModel{
+		Name:      types.StringValue("blogname"),
+		Value:     types.StringValue("x"),
+		ValueJSON: types.StringNull(),
+		Autoload:  types.BoolValue(false),
+	})
+	assert.ErrorContains(t, err, "cannot set autoload for option blogname")
+
+	// Options that are not registered with show_in_rest cannot be managed
+	_, err = getOptionJSON(ctx, cfg, "my_plugin_settings")
+	assert.ErrorContains(t, err, "option my_plugin_settings is not available from /wp/v2/settings")
+	assert.NotErrorIs(t, err, errOptionNotFound)
+
+	err = backend.UpdateOption(ctx, cfg, wordpressOptionModel{
+		Name:      types.StringValue("my_plugin_settings"),
+		Value:     types.StringNull(),
+		ValueJSON: types.StringValue(`{"enabled":true}`),
+		Autoload:  types.BoolNull(),
+	})
+	assert.ErrorContains(t, err, "option my_plugin_settings is not available from /wp/v2/settings")
+}
+
+func TestNewRESTBackend_InvalidURL(t *testing.T) {
+	for _, url := range []string{"", "example.com", "ftp://example.com", "https://"} {
+		_, err := newRESTBackend(&restBackendModel{URL: types.StringValue(url)})
+		assert.ErrorContains(t, err, "must be an http:// or https:// URL", url)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBackend(t *testing.T) {
+	restBlock := &restBackendModel{URL: types.StringValue("https://example.com")}
+
+	cases := map[string]struct {
+		data     WordpressProviderModel
+		expected []string
+	}{
+		"default validates the transport": {
+			data:     WordpressProviderModel{Backend: types.StringNull(), Transport: types.StringNull(), SSHTarget: types.StringNull()},
+			expected: []string{"Missing ssh_target"},
+		},
+		"wp-cli with rest block": {
+			data:     WordpressProviderModel{Backend: types.StringValue("wp-cli"), Transport: types.StringNull(), SSHTarget: types.StringValue("docker:wp"), REST: restBlock},
+			expected: []string{"Unexpected rest block"},
+		},
+		"rest": {
+			data: WordpressProviderModel{Backend: types.StringValue("rest"), Transport: types.StringNull(), SSHTarget: types.StringNull(), REST: restBlock},
+		},
+		"rest without block": {
+			data:     WordpressProviderModel{Backend: types.StringValue("rest"), Transport: types.StringNull(), SSHTarget: types.StringNull()},
+			expected: []string{"Missing rest block"},
+		},
+		"rest with transport settings": {
+			data: WordpressProviderModel{
+				Backend:   types.StringValue("rest"),
+				Transport: types.StringValue("docker"),
+				SSHTarget: types.StringValue("docker:wp"),
+				Docker:    &dockerTransportModel{},
+				REST:      restBlock,
+			},
+			expected: []string{"Unexpected transport", "Unexpected ssh_target", "Unexpected docker block"},
+		},
+		"rest with WP-CLI settings": {
+			data: WordpressProviderModel{
+				Backend:     types.StringValue("rest"),
+				Transport:   types.StringNull(),
+				SSHTarget:   types.StringNull(),
+				URL:         types.StringValue("https://example.com/blog"),
+				User:        types.StringValue("admin"),
+				SkipPlugins: types.BoolValue(true),
+				ExtraArgs:   types.ListValueMust(types.StringType, []attr.Value{types.StringValue("--debug")}),
+				REST:        restBlock,
+			},
+			expected: []string{"Unexpected user", "Unexpected skip_plugins", "Unexpected extra_args"},
+		},
+		"unknown backend": {
+			data: WordpressProviderModel{Backend: types.StringUnknown(), Transport: types.StringNull(), SSHTarget: types.StringNull()},
+		},
+	}
+	for name, tc := range cases {
+		var diags diag.Diagnostics
+		validateBackend(tc.data, &diags)
+
+		var summaries []string
+		for _, d := range diags {
+			summaries = append(summaries, d.Summary())
+		}
+		assert.Equal(t, tc.expected, summaries, name)
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	var diags diag.Diagnostics
+	assert.Nil(t, newBackend(WordpressProviderModel{Backend: types.StringNull()}, &diags))
+	assert.IsType(t, wpCLIBackend{}, (&WPConfig{}).backend())
+
+	backend := newBackend(WordpressProviderModel{
+		Backend: types.StringValue("rest"),
+		REST: &restBackendModel{
+			URL:                 types.StringValue("https://example.com/"),
+			Username:            types.StringValue("admin"),
+			ApplicationPassword: types.StringValue("abcd efgh"),
+		},
+	}, &diags)
+	assert.False(t, diags.HasError())
+	if assert.IsType(t, &restBackend{}, backend) {
+		assert.Equal(t, "https://example.com", backend.(*restBackend).url)
+	}
+
+	newBackend(WordpressProviderModel{
+		Backend: types.StringValue("rest"),
+		REST:    &restBackendModel{URL: types.StringValue("example.com")},
+	}, &diags)
+	assert.Equal(t, "Invalid REST configuration", diags[0].Summary())
+}
+
+func TestRequireWPCLIBackend(t *testing.T) {
+	var diags diag.Diagnostics
+	requireWPCLIBackend(&WPConfig{}, "wordpress_theme", &diags)
+	assert.False(t, diags.HasError())
+
+	requireWPCLIBackend(&WPConfig{Backend: &restBackend{}}, "wordpress_theme", &diags)
+	assert.True(t, diags.HasError())
+	assert.Equal(t, "Unsupported backend", diags[0].Summary())
+	assert.Contains(t, diags[0].Detail(), `wordpress_theme requires the "wp-cli" backend`)
+}
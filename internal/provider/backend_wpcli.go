@@ -0,0 +1,179 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var _ Backend = wpCLIBackend{}
+
+// wpCLIBackend manages WordPress by running WP-CLI commands through the
+// configured transport.
+type wpCLIBackend struct{}
+
+func (wpCLIBackend) ListPlugins(ctx context.Context, cfg *WPConfig, status string) ([]pluginInfo, error) {
+	args := []string{"plugin", "list", "--fields=" + pluginListFields, "--format=json"}
+	if status != "" {
+		args = append(args, "--status="+status)
+	}
+
+	output, err := runWPWithOutput(ctx, cfg, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseOutput(ctx, "plugin list", output, parsePluginList)
+}
+
+func (wpCLIBackend) GetPlugin(ctx context.Context, cfg *WPConfig, slug string) (*pluginInfo, error) {
+	output, err := runWPWithOutput(ctx, cfg, "plugin", "list", "--name="+slug, "--fields="+pluginListFields, "--format=json")
+	if err != nil {
+		return nil, err
+	}
+
+	plugins, err := parseOutput(ctx, "plugin list", output, parsePluginList)
+	if err != nil {
+		return nil, err
+	}
+	return findPlugin(plugins, slug)
+}
+
+func (wpCLIBackend) InstallPlugin(ctx context.Context, cfg *WPConfig, install pluginInstall) error {
+	if err := checkPositional(install.Slug, install.Archive); err != nil {
+		return err
+	}
+
+	args := []string{"plugin", "install", install.Slug}
+	if install.Archive != "" {
+		// --force lets the archive replace a plugin directory left behind earlier
+		args = []string{"plugin", "install", install.Archive, "--force"}
+	}
+	if install.Version != "" {
+		args = append(args, "--version="+install.Version)
+	}
+	if install.Activate {
+		args = append(args, "--activate")
+	}
+	return runWP(ctx, cfg, args...)
+}
+
+func (wpCLIBackend) UpdatePlugin(ctx context.Context, cfg *WPConfig, slug, version string, minor bool) error {
+	if err := checkPositional(slug); err != nil {
+		return err
+	}
+
+	args := []string{"plugin", "update", slug}
+	if version != "" {
+		args = append(args, "--version="+version)
+	} else if minor {
+		args = append(args, "--minor")
+	}
+	return runWP(ctx, cfg, args...)
+}
+
+func (wpCLIBackend) MinorPluginUpdate(ctx context.Context, cfg *WPConfig, slug string) (string, error) {
+	if err := checkPositional(slug); err != nil {
+		return "", err
+	}
+
+	output, err := runWPWithOutput(ctx, cfg, "plugin", "update", slug, "--minor", "--dry-run", "--format=json")
+	if err != nil {
+		return "", err
+	}
+	// Without updates WP-CLI prints a message instead of an empty list
+	output = strings.TrimSpace(output)
+	if !strings.HasPrefix(output, "[") {
+		return "", nil
+	}
+	// The dry run lists the updates in the shape of wp plugin list
+	updates, err := parseOutput(ctx, "plugin update --dry-run", output, parsePluginList)
+	if err != nil || len(updates) == 0 {
+		return "", err
+	}
+	return updates[0].UpdateVersion, nil
+}
+
+func (wpCLIBackend) SetPluginActive(ctx context.Context, cfg *WPConfig, slug string, active bool) error {
+	if err := checkPositional(slug); err != nil {
+		return err
+	}
+	if active {
+		return runWP(ctx, cfg, "plugin", "activate", slug)
+	}
+	return runWP(ctx, cfg, "plugin", "deactivate", slug)
+}
+
+func (wpCLIBackend) DeletePlugin(ctx context.Context, cfg *WPConfig, slug string) error {
+	if err := checkPositional(slug); err != nil {
+		return err
+	}
+	return runWP(ctx, cfg, "plugin", "delete", slug)
+}
+
+func (wpCLIBackend) GetOption(ctx context.Context, cfg *WPConfig, name string) (string, error) {
+	if err := checkPositional(name); err != nil {
+		return "", err
+	}
+
+	output, err := runWPWithOutput(ctx, cfg, "option", "get", name, "--format=json")
+	if optionMissing(err, name) {
+		return "", fmt.Errorf("%w: %w", errOptionNotFound, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	output = strings.TrimSpace(output)
+	if !json.Valid([]byte(output)) {
+		return "", fmt.Errorf("unable to parse value of option %s\nOutput: %s", name, output)
+	}
+	return output, nil
+}
+
+func (wpCLIBackend) GetOptionAutoload(ctx context.Context, cfg *WPConfig, name string) (bool, error) {
+	output, err := runWPWithOutput(ctx, cfg, "option", "list", "--search="+name, "--fields=option_name,autoload", "--format=json")
+	if err != nil {
+		return false, err
+	}
+	return parseOutput(ctx, "option list", output, func(output string) (bool, error) {
+		return parseOptionAutoload(output, name)
+	})
+}
+
+func (wpCLIBackend) UpdateOption(ctx context.Context, cfg *WPConfig, m wordpressOptionModel) error {
+	if err := checkPositional(m.Name.ValueString()); err != nil {
+		return err
+	}
+	if cfg.forwardsStdin() {
+		// WP-CLI reads the value from stdin when it is omitted, which keeps
+		// it out of the process list of the host
+		return runWP(withStdin(ctx, []byte(optionValue(m))), cfg, optionUpdateArgs(m, true)...)
+	}
+	// the value is positional too
+	if err := checkPositional(optionValue(m)); err != nil {
+		return err
+	}
+	return runWP(ctx, cfg, optionUpdateArgs(m, false)...)
+}
+
+func (wpCLIBackend) DeleteOption(ctx context.Context, cfg *WPConfig, name string) error {
+	if err := checkPositional(name); err != nil {
+		return err
+	}
+	return runWP(ctx, cfg, "option", "delete", name)
+}
+
+// optionMissing reports whether err is WP-CLI failing `wp option get` because
+// the option does not exist, rather than for any other reason such as a
+// database error or a PHP fatal error.
+func optionMissing(err error, name string) bool {
+	var cmdErr *commandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != 1 {
+		return false
+	}
+	return strings.Contains(cmdErr.Output(), "Could not get '"+name+"' option")
+}
@@ -0,0 +1,201 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWPCLIBackend_PluginCommands(t *testing.T) {
+	recorder := &recordingCommander{}
+	cfg := &WPConfig{Exec: recorder}
+	backend := wpCLIBackend{}
+	ctx := context.Background()
+
+	require.NoError(t, backend.InstallPlugin(ctx, cfg, pluginInstall{Slug: "akismet", Version: "5.3", Activate: true}))
+	require.NoError(t, backend.InstallPlugin(ctx, cfg, pluginInstall{Slug: "my-plugin", Archive: "/tmp/my-plugin.zip"}))
+	require.NoError(t, backend.UpdatePlugin(ctx, cfg, "akismet", "5.4", false))
+	require.NoError(t, backend.UpdatePlugin(ctx, cfg, "akismet", "", true))
+	require.NoError(t, backend.UpdatePlugin(ctx, cfg, "akismet", "", false))
+	require.NoError(t, backend.SetPluginActive(ctx, cfg, "akismet", true))
+	require.NoError(t, backend.SetPluginActive(ctx, cfg, "akismet", false))
+	require.NoError(t, backend.DeletePlugin(ctx, cfg, "akismet"))
+
+	assert.Equal(t, [][]string{
+		{"wp", "plugin", "install", "akismet", "--version=5.3", "--activate"},
+		{"wp", "plugin", "install", "/tmp/my-plugin.zip", "--force"},
+		{"wp", "plugin", "update", "akismet", "--version=5.4"},
+		{"wp", "plugin", "update", "akismet", "--minor"},
+		{"wp", "plugin", "update", "akismet"},
+		{"wp", "plugin", "activate", "akismet"},
+		{"wp", "plugin", "deactivate", "akismet"},
+		{"wp", "plugin", "delete", "akismet"},
+	}, recorder.calls)
+}
+
+func TestWPCLIBackend_MinorPluginUpdate(t *testing.T) {
+	ctx := context.Background()
+	backend := wpCLIBackend{}
+
+	recorder := &recordingCommander{}
+	_, err := backend.MinorPluginUpdate(ctx, &WPConfig{Exec: recorder}, "akismet")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"wp", "plugin", "update", "akismet", "--minor", "--dry-run", "--format=json"}}, recorder.calls)
+
+	cfg := &WPConfig{Exec: mockCommander{output: []byte(`[{"name":"akismet","status":"active","version":"5.3.1","update_version":"5.3.5"}]`)}}
+	version, err := backend.MinorPluginUpdate(ctx, cfg, "akismet")
+	require.NoError(t, err)
+	assert.Equal(t, "5.3.5", version)
+
+	// WP-CLI prints a message rather than JSON when there is no update
+	cfg = &WPConfig{Exec: mockCommander{output: []byte("No plugin updates available.\n")}}
+	version, err = backend.MinorPluginUpdate(ctx, cfg, "akismet")
+	require.NoError(t, err)
+	assert.Empty(t, version)
+
+	cfg = &WPConfig{Exec: mockCommander{output: []byte("[{")}}
+	_, err = backend.MinorPluginUpdate(ctx, cfg, "akismet")
+	assert.Error(t, err)
+}
+
+func TestWPCLIBackend_OptionCommands(t *testing.T) {
+	recorder := &recordingCommander{}
+	cfg := &WPConfig{Exec: recorder}
+	backend := wpCLIBackend{}
+	ctx := context.Background()
+
+	require.NoError(t, backend.UpdateOption(ctx, cfg, wordpressOptionModel{
+		Name:      types.StringValue("blogname"),
+		Value:     types.StringValue("My Site"),
+		ValueJSON: types.StringNull(),
+		Autoload:  types.BoolNull(),
+	}))
+	require.NoError(t, backend.DeleteOption(ctx, cfg, "blogname"))
+
+	assert.Equal(t, [][]string{
+		{"wp", "option", "update", "blogname"},
+		{"wp", "option", "delete", "blogname"},
+	}, recorder.calls)
+}
+
+func TestWPCLIBackend_UpdateOptionStdin(t *testing.T) {
+	m := wordpressOptionModel{
+		Name:      types.StringValue("license_key"),
+		Value:     types.StringValue("--s3cr3t"),
+		ValueJSON: types.StringNull(),
+		Autoload:  types.BoolNull(),
+	}
+
+	// The value goes to stdin, where it cannot be read as a flag either
+	commander := &stdinCommander{}
+	require.NoError(t, wpCLIBackend{}.UpdateOption(context.Background(), &WPConfig{Exec: commander, Transport: transportSSH}, m))
+	assert.Equal(t, [][]string{{"wp", "option", "update", "license_key"}}, commander.calls)
+	assert.Equal(t, []string{"--s3cr3t"}, commander.stdin)
+
+	commander = &stdinCommander{}
+	require.NoError(t, wpCLIBackend{}.UpdateOption(context.Background(), &WPConfig{Exec: commander}, m))
+	assert.Equal(t, []string{"--s3cr3t"}, commander.stdin)
+
+	// WP-CLI's --ssh may drop stdin, so the value stays positional
+	m.Value = types.StringValue("s3cr3t")
+	commander = &stdinCommander{}
+	cfg := &WPConfig{Exec: commander, SSHTarget: "docker:wordpress"}
+	require.NoError(t, wpCLIBackend{}.UpdateOption(context.Background(), cfg, m))
+	assert.Equal(t, [][]string{{"wp", "--ssh=docker:wordpress", "option", "update", "license_key", "s3cr3t"}}, commander.calls)
+
+	m.Value = types.StringValue("--s3cr3t")
+	assert.ErrorContains(t, wpCLIBackend{}.UpdateOption(context.Background(), cfg, m), "would be read as a flag")
+}
+
+func TestWPCLIBackend_GetOptionNotFound(t *testing.T) {
+	cfg := &WPConfig{Exec: splitCommander{
+		stderr: []byte("Error: Could not get 'nope' option. Does it exist?"),
+		err:    &dockerExitError{ExitCode: 1},
+	}}
+	_, err := wpCLIBackend{}.GetOption(context.Background(), cfg, "nope")
+	assert.ErrorIs(t, err, errOptionNotFound)
+
+	// Other WP-CLI failures are not a missing option
+	failures := map[string]string{
+		"database":     "Error: Error establishing a database connection.",
+		"php fatal":    "PHP Fatal error:  Uncaught Error: Call to undefined function foo()",
+		"bad url":      "Error: Site 'https://example.com/missing' not found. Verify `--url=<url>` matches an existing site.",
+		"other option": "Error: Could not get 'nope_other' option. Does it exist?",
+	}
+	for name, stderr := range failures {
+		cfg.Exec = splitCommander{stderr: []byte(stderr), err: &dockerExitError{ExitCode: 1}}
+		_, err = wpCLIBackend{}.GetOption(context.Background(), cfg, "nope")
+		require.Error(t, err, name)
+		assert.NotErrorIs(t, err, errOptionNotFound, name)
+		assert.Contains(t, err.Error(), stderr, name)
+	}
+	cfg.Exec = splitCommander{stderr: []byte("Error: Could not get 'nope' option."), err: &dockerExitError{ExitCode: 255}}
+	_, err = wpCLIBackend{}.GetOption(context.Background(), cfg, "nope")
+	assert.NotErrorIs(t, err, errOptionNotFound)
+
+	// A connection failure is not a missing option
+	cfg.Exec = splitCommander{
+		stderr: []byte("ssh: Could not resolve hostname wp.example.com: Name or service not known"),
+		err:    &dockerExitError{ExitCode: 255},
+	}
+	_, err = wpCLIBackend{}.GetOption(context.Background(), cfg, "blogname")
+	assert.True(t, hostUnreachable(err))
+	assert.NotErrorIs(t, err, errOptionNotFound)
+
+	cfg.Exec = blockingCommander{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = wpCLIBackend{}.GetOption(ctx, cfg, "blogname")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, errOptionNotFound)
+}
+
+func TestWPCLIBackend_RejectsFlagLikeValues(t *testing.T) {
+	backend := wpCLIBackend{}
+	ctx := context.Background()
+	hostile := "--activate-network"
+
+	calls := map[string]func(cfg *WPConfig) error{
+		"install": func(cfg *WPConfig) error {
+			return backend.InstallPlugin(ctx, cfg, pluginInstall{Slug: hostile})
+		},
+		"install archive": func(cfg *WPConfig) error {
+			return backend.InstallPlugin(ctx, cfg, pluginInstall{Slug: "my-plugin", Archive: "--exec=phpinfo();"})
+		},
+		"update":     func(cfg *WPConfig) error { return backend.UpdatePlugin(ctx, cfg, hostile, "", true) },
+		"activate":   func(cfg *WPConfig) error { return backend.SetPluginActive(ctx, cfg, hostile, true) },
+		"deactivate": func(cfg *WPConfig) error { return backend.SetPluginActive(ctx, cfg, hostile, false) },
+		"delete":     func(cfg *WPConfig) error { return backend.DeletePlugin(ctx, cfg, hostile) },
+		"get option": func(cfg *WPConfig) error {
+			_, err := backend.GetOption(ctx, cfg, "--skip-plugins")
+			return err
+		},
+		"delete option": func(cfg *WPConfig) error { return backend.DeleteOption(ctx, cfg, "--skip-themes") },
+		"option value": func(cfg *WPConfig) error {
+			// The value is only positional when stdin may not reach wp;
+			// otherwise it is sent on stdin, where it is never a flag
+			cfg.SSHTarget = "docker:wordpress"
+			return backend.UpdateOption(ctx, cfg, wordpressOptionModel{
+				Name:      types.StringValue("blogname"),
+				Value:     types.StringValue("--require=/tmp/evil.php"),
+				ValueJSON: types.StringNull(),
+				Autoload:  types.BoolNull(),
+			})
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			recorder := &recordingCommander{}
+			err := call(&WPConfig{Exec: recorder})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "would be read as a flag")
+			assert.Empty(t, recorder.calls)
+		})
+	}
+}
@@ -4,21 +4,222 @@
 package provider
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"os"
 	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
+// defaultCommandTimeout bounds a single WP-CLI command unless the provider
+// configures a different command_timeout.
+const defaultCommandTimeout = 10 * time.Minute
+
+// commandWaitDelay is how long to wait for output pipes to close after the
+// process was killed, e.g. when ssh left a child holding them open.
+const commandWaitDelay = 5 * time.Second
+
 // Commander is an abstraction for executing external commands.
 type Commander interface {
-	CombinedOutput(name string, args ...string) ([]byte, error)
+	CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// outputCommander is implemented by Commanders that can return stdout and
+// stderr separately. Output from other Commanders is treated as stdout.
+type outputCommander interface {
+	Output(ctx context.Context, name string, args ...string) (stdout, stderr []byte, err error)
 }
 
 // defaultCommander uses os/exec for real command execution.
-type defaultCommander struct{}
+type defaultCommander struct {
+	// env holds NAME=value pairs added to the environment of commands.
+	env []string
+}
 
 // CombinedOutput runs the command and returns combined stdout and stderr.
-func (defaultCommander) CombinedOutput(name string, args ...string) ([]byte, error) {
-	return exec.Command(name, args...).CombinedOutput()
+// The process is killed when the context is canceled or its deadline expires.
+func (c defaultCommander) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return c.command(ctx, name, args).CombinedOutput()
+}
+
+// Output runs the command and returns stdout and stderr separately.
+func (c defaultCommander) Output(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	return runSeparated(c.command(ctx, name, args))
+}
+
+func (c defaultCommander) command(ctx context.Context, name string, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = commandWaitDelay
+	if stdin := commandStdin(ctx); stdin != nil {
+		cmd.Stdin = stdin
+	}
+	if len(c.env) > 0 {
+		cmd.Env = append(os.Environ(), c.env...)
+	}
+	return cmd
+}
+
+// stdinKey is the context key of the input commands read from stdin.
+type stdinKey struct{}
+
+// withStdin returns ctx whose commands are given input on stdin. Secrets such
+// as passwords are passed this way so that they do not appear on a command
+// line, which other users of the host can read.
+func withStdin(ctx context.Context, input []byte) context.Context {
+	return context.WithValue(ctx, stdinKey{}, input)
+}
+
+// commandStdin returns a reader for the stdin input ctx carries, or nil. Each
+// call starts from the beginning, so retried commands get the whole input.
+func commandStdin(ctx context.Context) io.Reader {
+	input, ok := ctx.Value(stdinKey{}).([]byte)
+	if !ok {
+		return nil
+	}
+	return bytes.NewReader(input)
+}
+
+// runSeparated runs cmd, capturing stdout and stderr in separate buffers.
+func runSeparated(cmd *exec.Cmd) ([]byte, []byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// commandError reports a command that failed or could not be run. Stdout and
+// Stderr hold what the command printed before it failed.
+type commandError struct {
+	// Command is the command line, with secrets redacted.
+	Command string
+	// Transport names the transport the command was run through.
+	Transport string
+	// ExitCode is the command's exit status, or -1 when it did not exit,
+	// e.g. because the host could not be reached.
+	ExitCode int
+	// Attempts is how many times the command was tried, when it was retried.
+	Attempts int
+	Stdout   []byte
+	Stderr   []byte
+	Err      error
+}
+
+func (e *commandError) Error() string {
+	msg := e.Command + " failed"
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" after %d attempts", e.Attempts)
+	}
+	if e.ExitCode >= 0 {
+		msg += fmt.Sprintf(" with exit code %d", e.ExitCode)
+	} else {
+		msg += ": " + e.Err.Error()
+	}
+	if output := e.Output(); output != "" {
+		msg += "\nOutput: " + output
+	}
+	return msg
+}
+
+func (e *commandError) Unwrap() error {
+	return e.Err
+}
+
+// Output returns stderr followed by stdout, trimmed of surrounding space.
+func (e *commandError) Output() string {
+	return strings.TrimSpace(strings.TrimSpace(string(e.Stderr)) + "\n" + strings.TrimSpace(string(e.Stdout)))
+}
+
+// exitCode returns the exit status carried by err, or -1 if the command did
+// not exit.
+func exitCode(err error) int {
+	var execErr *exec.ExitError
+	if errors.As(err, &execErr) {
+		return execErr.ExitCode()
+	}
+	// Implemented by the ssh, docker and kubernetes exit errors
+	var statusErr interface{ ExitStatus() int }
+	if errors.As(err, &statusErr) {
+		return statusErr.ExitStatus()
+	}
+	return -1
+}
+
+// sshConnectionFailures are printed by the ssh client wp-cli runs for
+// --ssh=<target> when it cannot reach the host.
+var sshConnectionFailures = []string{
+	"ssh: connect to host",
+	"ssh: Could not resolve hostname",
+	"Connection refused",
+	"Connection timed out",
+	"Connection closed by",
+	"Permission denied (publickey",
+	"Host key verification failed",
+}
+
+// hostUnreachable reports whether err means the command could not reach the
+// WordPress host, as opposed to WP-CLI running and reporting an error.
+func hostUnreachable(err error) bool {
+	var cmdErr *commandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	if cmdErr.ExitCode < 0 {
+		var netErr net.Error
+		return errors.As(err, &netErr) || strings.Contains(cmdErr.Err.Error(), "SSH handshake")
+	}
+	// ssh exits with 255 when the connection fails
+	if cmdErr.ExitCode == 255 && cmdErr.Transport == transportWPCLI {
+		for _, failure := range sshConnectionFailures {
+			if strings.Contains(string(cmdErr.Stderr), failure) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// commandTimeoutError reports a command that did not finish in time.
+type commandTimeoutError struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (e *commandTimeoutError) Error() string {
+	if e.Timeout > 0 {
+		return fmt.Sprintf("%s did not finish within %s and was stopped", e.Command, e.Timeout)
+	}
+	return fmt.Sprintf("%s did not finish before the operation timed out and was stopped", e.Command)
+}
+
+// interrupted reports whether err means a command was stopped by a timeout
+// or cancellation rather than failing on its own.
+func interrupted(err error) bool {
+	var timeout *commandTimeoutError
+	return errors.As(err, &timeout) || errors.Is(err, context.Canceled)
+}
+
+// addCommandError adds err to diags under summary, or under a distinct
+// summary when the command timed out or could not reach the host.
+func addCommandError(diags *diag.Diagnostics, summary string, err error) {
+	var timeout *commandTimeoutError
+	if errors.As(err, &timeout) {
+		diags.AddError("WP-CLI command timed out", fmt.Sprintf("%s: %s", summary, err))
+		return
+	}
+	if hostUnreachable(err) {
+		diags.AddError("Unable to reach WordPress host", fmt.Sprintf("%s: %s", summary, err))
+		return
+	}
+	diags.AddError(summary, err.Error())
 }
 
 // cmdExec can be mocked in tests, otherwise uses the real executor.
@@ -40,23 +241,203 @@ func buildWPArgs(cfg *WPConfig, args ...string) []string {
 		allArgs = append(allArgs, "--path="+cfg.RemotePath)
 	}
 
+	if cfg.URL != "" {
+		allArgs = append(allArgs, "--url="+cfg.URL)
+	}
+
+	if cfg.User != "" {
+		allArgs = append(allArgs, "--user="+cfg.User)
+	}
+
+	if cfg.SkipPlugins {
+		allArgs = append(allArgs, "--skip-plugins")
+	}
+
+	if cfg.SkipThemes {
+		allArgs = append(allArgs, "--skip-themes")
+	}
+
+	if cfg.Context != "" {
+		allArgs = append(allArgs, "--context="+cfg.Context)
+	}
+
+	allArgs = append(allArgs, cfg.ExtraArgs...)
 	allArgs = append(allArgs, args...)
 	return allArgs
 }
 
-// runWP runs a wp-cli command and returns only an error (used for Create, Delete, Activate).
-func runWP(cfg *WPConfig, args ...string) error {
-	allArgs := buildWPArgs(cfg, args...)
-	output, err := cmdExec.CombinedOutput("wp", allArgs...)
-	if err != nil {
-		return fmt.Errorf("wp %v failed: %s", allArgs, string(output))
+// checkPositional makes sure values that come from configuration, state or
+// an import ID can be passed to WP-CLI as positional arguments. WP-CLI has no
+// end of options marker ("--" is itself taken as a positional argument), so a
+// value such as "--activate-network" or "--exec=..." would be read as a flag.
+// Schema validators reject these values at plan time, but imported IDs and
+// values derived at apply time are not validated.
+func checkPositional(values ...string) error {
+	for _, v := range values {
+		if strings.HasPrefix(v, "--") {
+			return fmt.Errorf("refusing to pass %q to WP-CLI: it would be read as a flag rather than a value", v)
+		}
+		if strings.ContainsRune(v, 0) {
+			return fmt.Errorf("refusing to pass %q to WP-CLI: it contains a NUL byte", v)
+		}
 	}
 	return nil
 }
 
-// runWPWithOutput runs a wp-cli command and returns both output and error (used for status checks).
-func runWPWithOutput(cfg *WPConfig, args ...string) (string, error) {
-	allArgs := buildWPArgs(cfg, args...)
-	output, err := cmdExec.CombinedOutput("wp", allArgs...)
+// transport returns the name of the transport commands for cfg run through.
+func (c *WPConfig) transport() string {
+	if c.Transport != "" {
+		return c.Transport
+	}
+	return transportWPCLI
+}
+
+// forwardsStdin reports whether the stdin of commands run for cfg reaches
+// wp. WP-CLI's own --ssh does not forward it for every kind of target, such
+// as docker:, so the wp-cli transport with an ssh_target does not count.
+func (c *WPConfig) forwardsStdin() bool {
+	return c.transport() != transportWPCLI || c.SSHTarget == ""
+}
+
+// commander returns the Commander that runs commands for cfg.
+func (c *WPConfig) commander() Commander {
+	if c.Exec != nil {
+		return c.Exec
+	}
+	return cmdExec
+}
+
+// runCommand runs a command and returns its stdout, retrying transient
+// failures as allowed by the retry policy.
+func runCommand(ctx context.Context, cfg *WPConfig, name string, args ...string) ([]byte, error) {
+	redact := newRedactor(ctx, cfg)
+	ctx = redact.mask(withLogging(ctx))
+	if classifyCommand(name, args) == commandMutating {
+		// Even a failed command may have changed WordPress
+		defer cfg.invalidatePlugins()
+	}
+
+	attempts, delay := retryPolicy(cfg, name, args)
+	for attempt := 1; ; attempt++ {
+		output, err := runCommandOnce(ctx, cfg, redact, name, args...)
+		if err == nil || attempt == attempts || !transientError(err) {
+			var cmdErr *commandError
+			if attempt > 1 && errors.As(err, &cmdErr) {
+				cmdErr.Attempts = attempt
+			}
+			return output, err
+		}
+
+		tflog.SubsystemWarn(ctx, logWPCLI, "Retrying command after transient failure", map[string]interface{}{
+			logFieldCommand: strings.Join(redact.args(append([]string{name}, args...)), " "),
+			logFieldTarget:  cfg.target(),
+			logFieldAttempt: attempt,
+			logFieldError:   err.Error(),
+			"retry_delay":   delay.String(),
+		})
+		select {
+		case <-ctx.Done():
+			return output, err
+		case <-time.After(delay):
+		}
+		delay = nextRetryDelay(delay)
+	}
+}
+
+// runCommandOnce runs a command with the configured per-command timeout and
+// returns its stdout. A timeout or cancellation is converted into an error
+// naming the command, and any other failure into a *commandError. Secrets
+// known to redact are kept out of errors, logs and the audit log.
+func runCommandOnce(ctx context.Context, cfg *WPConfig, redact redactor, name string, args ...string) ([]byte, error) {
+	argv := redact.args(append([]string{name}, args...))
+	command := strings.Join(argv, " ")
+	class := classifyCommand(name, args)
+	fields := map[string]interface{}{
+		logFieldCommand: command,
+		logFieldTarget:  cfg.target(),
+		logFieldClass:   class,
+	}
+	release, err := cfg.limiter.acquire(ctx, class)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, &commandTimeoutError{Command: command}
+		}
+		return nil, fmt.Errorf("%s was canceled while waiting for other commands: %w", command, err)
+	}
+	defer release()
+
+	timeout := cfg.CommandTimeout
+	if timeout <= 0 {
+		timeout = defaultCommandTimeout
+	}
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tflog.SubsystemDebug(ctx, logWPCLI, "Running command", fields)
+	start := time.Now()
+	var stdout, stderr []byte
+	if c, ok := cfg.commander().(outputCommander); ok {
+		stdout, stderr, err = c.Output(cmdCtx, name, args...)
+	} else {
+		stdout, err = cfg.commander().CombinedOutput(cmdCtx, name, args...)
+	}
+	duration := time.Since(start)
+	rec := auditRecord{
+		Timestamp:  start.UTC(),
+		Argv:       argv,
+		Target:     cfg.target(),
+		DurationMS: duration.Milliseconds(),
+		Class:      class,
+	}
+	fields[logFieldDuration] = duration.String()
+	fields[logFieldExitCode] = 0
+	stderr = redact.bytes(stderr)
+	if err != nil {
+		rec.ExitCode = exitCode(err)
+		err = redact.error(err)
+		if rec.ExitCode < 0 {
+			rec.Error = err.Error()
+		}
+		fields[logFieldExitCode] = rec.ExitCode
+		fields[logFieldError] = err.Error()
+	}
+	cfg.audit.record(ctx, rec)
+	if len(stderr) > 0 {
+		fields["stderr"] = string(stderr)
+	}
+	if err == nil {
+		tflog.SubsystemDebug(ctx, logWPCLI, "Command finished", fields)
+		return stdout, nil
+	}
+	tflog.SubsystemDebug(ctx, logWPCLI, "Command failed", fields)
+
+	switch {
+	case ctx.Err() == context.Canceled:
+		return stdout, fmt.Errorf("%s was canceled: %w", command, ctx.Err())
+	case ctx.Err() == context.DeadlineExceeded:
+		return stdout, &commandTimeoutError{Command: command}
+	case cmdCtx.Err() == context.DeadlineExceeded:
+		return stdout, &commandTimeoutError{Command: command, Timeout: timeout}
+	}
+	return stdout, &commandError{
+		Command:   command,
+		Transport: cfg.transport(),
+		ExitCode:  rec.ExitCode,
+		Stdout:    redact.bytes(stdout),
+		Stderr:    stderr,
+		Err:       err,
+	}
+}
+
+// runWP runs a wp-cli command and returns only an error (used for Create, Delete, Activate).
+func runWP(ctx context.Context, cfg *WPConfig, args ...string) error {
+	_, err := runCommand(ctx, cfg, "wp", buildWPArgs(cfg, args...)...)
+	return err
+}
+
+// runWPWithOutput runs a wp-cli command and returns its stdout, leaving
+// warnings printed to stderr out of the output that is parsed.
+func runWPWithOutput(ctx context.Context, cfg *WPConfig, args ...string) (string, error) {
+	output, err := runCommand(ctx, cfg, "wp", buildWPArgs(cfg, args...)...)
 	return string(output), err
 }
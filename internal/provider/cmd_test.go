@@ -4,10 +4,15 @@
 package provider
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,10 +22,34 @@ type mockCommander struct {
 	err    error
 }
 
-func (m mockCommander) CombinedOutput(name string, args ...string) ([]byte, error) {
+func (m mockCommander) CombinedOutput(_ context.Context, name string, args ...string) ([]byte, error) {
 	return m.output, m.err
 }
 
+// recordingCommander records every command it is asked to run and succeeds.
+type recordingCommander struct {
+	calls [][]string
+}
+
+func (r *recordingCommander) CombinedOutput(_ context.Context, name string, args ...string) ([]byte, error) {
+	r.calls = append(r.calls, append([]string{name}, args...))
+	return nil, nil
+}
+
+// splitCommander mocks a command that prints to stdout and stderr separately.
+type splitCommander struct {
+	stdout, stderr []byte
+	err            error
+}
+
+func (m splitCommander) CombinedOutput(_ context.Context, name string, args ...string) ([]byte, error) {
+	return append(append([]byte{}, m.stdout...), m.stderr...), m.err
+}
+
+func (m splitCommander) Output(_ context.Context, name string, args ...string) ([]byte, []byte, error) {
+	return m.stdout, m.stderr, m.err
+}
+
 // getTestConfig returns a WPConfig with a dynamic container name if provided.
 func getTestConfig() *WPConfig {
 	container := os.Getenv("WP_CONTAINER_NAME")
@@ -59,12 +88,87 @@ func TestBuildWPArgs_SomeOptions(t *testing.T) {
 	assert.Equal(t, []string{"--path=/foo", "theme", "install"}, args)
 }
 
+func TestBuildWPArgs_URL(t *testing.T) {
+	cfg := &WPConfig{RemotePath: "/foo", URL: "https://example.com/blog"}
+	args := buildWPArgs(cfg, "plugin", "list")
+	assert.Equal(t, []string{"--path=/foo", "--url=https://example.com/blog", "plugin", "list"}, args)
+}
+
+func TestBuildWPArgs_GlobalParameters(t *testing.T) {
+	cfg := &WPConfig{
+		SSHTarget:   "user@host",
+		URL:         "https://example.com/blog",
+		User:        "admin",
+		SkipPlugins: true,
+		SkipThemes:  true,
+		Context:     "admin",
+		ExtraArgs:   []string{"--skip-packages", "--require=/opt/wp/fix.php"},
+	}
+	args := buildWPArgs(cfg, "plugin", "list")
+	assert.Equal(t, []string{
+		"--ssh=user@host", "--url=https://example.com/blog", "--user=admin", "--skip-plugins", "--skip-themes",
+		"--context=admin", "--skip-packages", "--require=/opt/wp/fix.php", "plugin", "list",
+	}, args)
+	assert.Equal(t, commandReadOnly, classifyCommand("wp", args))
+}
+
+func TestDefaultCommander_Env(t *testing.T) {
+	t.Setenv("WP_INHERITED", "kept")
+	c := defaultCommander{env: []string{"WP_CLI_CACHE_DIR=/tmp/wp-cli"}}
+
+	output, err := c.CombinedOutput(context.Background(), "sh", "-c", `echo "$WP_CLI_CACHE_DIR $WP_INHERITED"`)
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/wp-cli kept\n", string(output))
+}
+
+func TestDefaultCommander_Stdin(t *testing.T) {
+	c := defaultCommander{}
+	ctx := withStdin(context.Background(), []byte("s3cret\n"))
+
+	stdout, _, err := c.Output(ctx, "sh", "-c", "read line; echo \"got $line\"")
+	assert.NoError(t, err)
+	assert.Equal(t, "got s3cret\n", string(stdout))
+
+	// Each command reads the input from the start
+	output, err := c.CombinedOutput(ctx, "cat")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret\n", string(output))
+
+	// Without input stdin is empty
+	output, err = c.CombinedOutput(context.Background(), "cat")
+	assert.NoError(t, err)
+	assert.Empty(t, output)
+}
+
 func TestBuildWPArgs_NoOptions(t *testing.T) {
 	cfg := &WPConfig{}
 	args := buildWPArgs(cfg, "theme", "status")
 	assert.Equal(t, []string{"theme", "status"}, args)
 }
 
+func TestCheckPositional(t *testing.T) {
+	cases := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"akismet", false},
+		{"My Site", false},
+		{"-1", false},
+		{"- bullet", false},
+		{"", false},
+		{"--", true},
+		{"--activate-network", true},
+		{"--exec=system('id')", true},
+		{"--require=/tmp/evil.php", true},
+		{"--ssh=attacker.example.com", true},
+		{"foo\x00--bar", true},
+	}
+	for _, tc := range cases {
+		err := checkPositional("blogname", tc.value)
+		assert.Equal(t, tc.wantErr, err != nil, "%q", tc.value)
+	}
+}
+
 func TestRunWP_Success(t *testing.T) {
 	prev := cmdExec
 	defer func() { cmdExec = prev }()
@@ -74,7 +178,7 @@ func TestRunWP_Success(t *testing.T) {
 		err:    nil,
 	}
 
-	err := runWP(getTestConfig(), "plugin", "install", "akismet", "--activate")
+	err := runWP(context.Background(), getTestConfig(), "plugin", "install", "akismet", "--activate")
 	assert.NoError(t, err)
 }
 
@@ -87,7 +191,7 @@ func TestRunWP_Failure(t *testing.T) {
 		err:    errors.New("exit code 1"),
 	}
 
-	err := runWP(getTestConfig(), "plugin", "install", "akismet")
+	err := runWP(context.Background(), getTestConfig(), "plugin", "install", "akismet")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "wp")
 	assert.Contains(t, err.Error(), "plugin install akismet")
@@ -103,7 +207,7 @@ func TestRunWPWithOutput_Success(t *testing.T) {
 		err:    nil,
 	}
 
-	output, err := runWPWithOutput(getTestConfig(), "plugin", "status")
+	output, err := runWPWithOutput(context.Background(), getTestConfig(), "plugin", "status")
 	assert.NoError(t, err)
 	assert.Equal(t, "plugin status ok", output)
 }
@@ -117,13 +221,160 @@ func TestRunWPWithOutput_Error(t *testing.T) {
 		err:    errors.New("fail"),
 	}
 
-	output, err := runWPWithOutput(getTestConfig(), "plugin", "status")
+	output, err := runWPWithOutput(context.Background(), getTestConfig(), "plugin", "status")
 	assert.Error(t, err)
 	assert.Equal(t, "something went wrong", output)
 }
 
 func TestDefaultCommander_CombinedOutput(t *testing.T) {
-	out, err := defaultCommander{}.CombinedOutput("echo", "hello")
+	out, err := defaultCommander{}.CombinedOutput(context.Background(), "echo", "hello")
 	assert.NoError(t, err)
 	assert.Contains(t, string(out), "hello")
 }
+
+func TestDefaultCommander_KilledOnCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := defaultCommander{}.CombinedOutput(ctx, "sleep", "10")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+// blockingCommander blocks until the context is done.
+type blockingCommander struct{}
+
+func (blockingCommander) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRunWP_CommandTimeout(t *testing.T) {
+	orig := cmdExec
+	cmdExec = blockingCommander{}
+	defer func() { cmdExec = orig }()
+
+	cfg := &WPConfig{RemotePath: "/var/www/html", CommandTimeout: 10 * time.Millisecond}
+	err := runWP(context.Background(), cfg, "plugin", "install", "akismet")
+
+	var timeout *commandTimeoutError
+	assert.ErrorAs(t, err, &timeout)
+	assert.Equal(t, "wp --path=/var/www/html plugin install akismet", timeout.Command)
+	assert.EqualError(t, err, "wp --path=/var/www/html plugin install akismet did not finish within 10ms and was stopped")
+
+	var diags diag.Diagnostics
+	addCommandError(&diags, "Failed to install plugin", err)
+	assert.Equal(t, "WP-CLI command timed out", diags[0].Summary())
+	assert.Contains(t, diags[0].Detail(), "plugin install akismet")
+}
+
+func TestRunWP_OperationDeadline(t *testing.T) {
+	orig := cmdExec
+	cmdExec = blockingCommander{}
+	defer func() { cmdExec = orig }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := runWPWithOutput(ctx, &WPConfig{}, "plugin", "list")
+	var timeout *commandTimeoutError
+	assert.ErrorAs(t, err, &timeout)
+	assert.Contains(t, err.Error(), "before the operation timed out")
+}
+
+func TestRunWP_Canceled(t *testing.T) {
+	orig := cmdExec
+	cmdExec = blockingCommander{}
+	defer func() { cmdExec = orig }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := runWP(ctx, &WPConfig{}, "plugin", "list")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, interrupted(err))
+	assert.False(t, interrupted(errors.New("wp failed")))
+}
+
+func TestAddCommandError(t *testing.T) {
+	var diags diag.Diagnostics
+	addCommandError(&diags, "Failed to install plugin", errors.New("wp failed"))
+	assert.Equal(t, "Failed to install plugin", diags[0].Summary())
+	assert.Equal(t, "wp failed", diags[0].Detail())
+
+	addCommandError(&diags, "Failed to install plugin", &commandError{
+		Command:   "wp --ssh=wp.example.com plugin install akismet",
+		Transport: transportWPCLI,
+		ExitCode:  255,
+		Stderr:    []byte("ssh: connect to host wp.example.com port 22: Connection refused"),
+		Err:       errors.New("exit status 255"),
+	})
+	assert.Equal(t, "Unable to reach WordPress host", diags[1].Summary())
+	assert.Contains(t, diags[1].Detail(), "Connection refused")
+}
+
+func TestRunCommand_SeparatesOutput(t *testing.T) {
+	cfg := &WPConfig{Exec: defaultCommander{}, Transport: transportLocal}
+
+	stdout, err := runCommand(context.Background(), cfg, "sh", "-c", `echo '[]'; echo 'PHP Warning: deprecated' >&2`)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]\n", string(stdout))
+
+	_, err = runCommand(context.Background(), cfg, "sh", "-c", `echo partial; echo 'Error: failed' >&2; exit 3`)
+	var cmdErr *commandError
+	if assert.ErrorAs(t, err, &cmdErr) {
+		assert.Equal(t, 3, cmdErr.ExitCode)
+		assert.Equal(t, transportLocal, cmdErr.Transport)
+		assert.Equal(t, "partial\n", string(cmdErr.Stdout))
+		assert.Equal(t, "Error: failed\n", string(cmdErr.Stderr))
+	}
+	assert.EqualError(t, err, "sh -c echo partial; echo 'Error: failed' >&2; exit 3 failed with exit code 3\nOutput: Error: failed\npartial")
+	assert.False(t, hostUnreachable(err))
+}
+
+func TestRunCommand_FallsBackToCombinedOutput(t *testing.T) {
+	cfg := &WPConfig{Exec: mockCommander{output: []byte("Error: failed"), err: assert.AnError}}
+
+	_, err := runCommand(context.Background(), cfg, "wp", "user", "create", "jane", "jane@example.com", "--user_pass=hunter2")
+	var cmdErr *commandError
+	if assert.ErrorAs(t, err, &cmdErr) {
+		assert.Equal(t, -1, cmdErr.ExitCode)
+		assert.Equal(t, transportWPCLI, cmdErr.Transport)
+		assert.Equal(t, "Error: failed", string(cmdErr.Stdout))
+		assert.Empty(t, cmdErr.Stderr)
+		assert.Equal(t, "wp user create jane jane@example.com --user_pass=***", cmdErr.Command)
+	}
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NotContains(t, err.Error(), "hunter2")
+}
+
+func TestHostUnreachable(t *testing.T) {
+	cases := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"not a command error": {err: errors.New("connection refused")},
+		"wp-cli error": {
+			err: &commandError{Transport: transportWPCLI, ExitCode: 1, Stderr: []byte("Error: The 'x' plugin could not be found."), Err: errors.New("exit status 1")},
+		},
+		"ssh connection refused": {
+			err:      &commandError{Transport: transportWPCLI, ExitCode: 255, Stderr: []byte("ssh: connect to host wp port 22: Connection refused"), Err: errors.New("exit status 255")},
+			expected: true,
+		},
+		"PHP fatal error": {
+			err: &commandError{Transport: transportWPCLI, ExitCode: 255, Stderr: []byte("PHP Fatal error: Allowed memory size exhausted"), Err: errors.New("exit status 255")},
+		},
+		"dial failure": {
+			err:      &commandError{Transport: transportSSH, ExitCode: -1, Err: fmt.Errorf("unable to connect to wp:22: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")})},
+			expected: true,
+		},
+		"ssh handshake failure": {
+			err:      &commandError{Transport: transportSSH, ExitCode: -1, Err: errors.New("SSH handshake with wp:22 failed: unable to authenticate")},
+			expected: true,
+		},
+	}
+	for name, tc := range cases {
+		assert.Equal(t, tc.expected, hostUnreachable(tc.err), name)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import "strings"
+
+// Command classes tell commands that only read WordPress state apart from
+// commands that change it.
+const (
+	// commandReadOnly commands can be repeated or run concurrently safely.
+	commandReadOnly = "read-only"
+
+	// commandMutating commands change the WordPress installation or the host.
+	commandMutating = "mutating"
+)
+
+// readOnlyWPCommands lists the WP-CLI subcommands that do not change
+// WordPress, keyed by their leading positional words.
+var readOnlyWPCommands = map[string]bool{
+	"cli info":            true,
+	"cli version":         true,
+	"core check-update":   true,
+	"core is-installed":   true,
+	"core version":        true,
+	"option get":          true,
+	"option list":         true,
+	"option pluck":        true,
+	"plugin get":          true,
+	"plugin is-active":    true,
+	"plugin is-installed": true,
+	"plugin list":         true,
+	"plugin path":         true,
+	"plugin search":       true,
+	"plugin status":       true,
+	"theme get":           true,
+	"theme is-active":     true,
+	"theme is-installed":  true,
+	"theme list":          true,
+	"theme path":          true,
+	"theme search":        true,
+	"theme status":        true,
+	"user get":            true,
+	"user list":           true,
+	"user list-caps":      true,
+	"user meta get":       true,
+	"user meta list":      true,
+	"user meta pluck":     true,
+	"user session list":   true,
+}
+
+// classifyCommand returns commandReadOnly for WP-CLI commands known not to
+// change WordPress, or run with --dry-run, and commandMutating for everything
+// else, including commands other than wp such as file uploads.
+func classifyCommand(name string, args []string) string {
+	if name != "wp" {
+		return commandMutating
+	}
+
+	for _, arg := range args {
+		if arg == "--dry-run" {
+			return commandReadOnly
+		}
+	}
+
+	var words []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		words = append(words, arg)
+		if len(words) == 3 {
+			break
+		}
+	}
+	for n := 2; n <= len(words); n++ {
+		if readOnlyWPCommands[strings.Join(words[:n], " ")] {
+			return commandReadOnly
+		}
+	}
+	return commandMutating
+}
@@ -0,0 +1,37 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyCommand(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"wp", []string{"--ssh=docker:wp", "--path=/var/www/html", "plugin", "list", "--format=json"}, commandReadOnly},
+		{"wp", []string{"plugin", "list", "--name=akismet"}, commandReadOnly},
+		{"wp", []string{"option", "get", "blogname", "--format=json"}, commandReadOnly},
+		{"wp", []string{"user", "meta", "list", "5", "--format=json"}, commandReadOnly},
+		{"wp", []string{"theme", "is-installed", "twentytwentyfour"}, commandReadOnly},
+		{"wp", []string{"plugin", "update", "akismet", "--minor", "--dry-run", "--format=json"}, commandReadOnly},
+		{"wp", []string{"plugin", "install", "akismet", "--activate"}, commandMutating},
+		{"wp", []string{"plugin", "update", "akismet", "--minor"}, commandMutating},
+		{"wp", []string{"option", "update", "blogname", "list"}, commandMutating},
+		{"wp", []string{"user", "meta", "update", "5", "nickname", "jane"}, commandMutating},
+		{"wp", []string{"user", "create", "jane", "jane@example.com", "--user_pass=list"}, commandMutating},
+		{"wp", []string{"plugin"}, commandMutating},
+		{"wp", nil, commandMutating},
+		{"scp", []string{"plugin.zip", "wp:/tmp/plugin.zip"}, commandMutating},
+		{"rm", []string{"-f", "/tmp/plugin.zip"}, commandMutating},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, classifyCommand(tc.name, tc.args), "%s %v", tc.name, tc.args)
+	}
+}
@@ -3,13 +3,90 @@
 
 package provider
 
-import "github.com/hashicorp/terraform-plugin-framework/types"
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
 
 // WPConfig holds the configuration for executing WP-CLI commands.
 type WPConfig struct {
 	SSHTarget  string
 	RemotePath string
 	AllowRoot  bool
+	URL        string
+
+	// User, SkipPlugins, SkipThemes and Context set the WP-CLI global
+	// parameters of the same names. ExtraArgs are further global flags
+	// added to every command.
+	User        string
+	SkipPlugins bool
+	SkipThemes  bool
+	Context     string
+	ExtraArgs   []string
+
+	// PollInterval and PollMaxWait control how plugin status is re-checked
+	// after a change. Zero values select the defaults.
+	PollInterval time.Duration
+	PollMaxWait  time.Duration
+
+	// Exec runs commands for this provider instance. When nil, commands run
+	// through cmdExec.
+	Exec Commander
+
+	// Transport names the transport Exec implements, for error reporting.
+	// Empty means the wp-cli transport.
+	Transport string
+
+	// Backend serves resources for this provider instance. When nil,
+	// resources run WP-CLI commands.
+	Backend Backend
+
+	// CommandTimeout bounds each command run on behalf of a resource. Zero
+	// selects the default.
+	CommandTimeout time.Duration
+
+	// RetryAttempts and RetryDelay control how commands failing with a
+	// transient error are retried. Zero values select the defaults.
+	RetryAttempts int
+	RetryDelay    time.Duration
+	// RetryMutatingCommands allows retrying commands that change WordPress.
+	RetryMutatingCommands bool
+
+	// inventory caches plugin lists for this provider instance. It is a
+	// pointer so that copies made by forSite share it. When nil, every
+	// lookup queries WordPress.
+	inventory *pluginInventory
+
+	// limiter bounds the commands run for this provider instance at once.
+	// Copies made by forSite share it, since multisite subsites share one
+	// installation. When nil, commands are not limited.
+	limiter *commandLimiter
+
+	// audit records the commands run for this provider instance. When nil,
+	// commands are not recorded.
+	audit *auditLog
+
+	// secrets are the values of the provider's sensitive settings, which
+	// are redacted from anything reported about a command.
+	secrets []string
+
+	// variables are the provider's env setting as NAME=value pairs. Values
+	// may be secrets but also short settings such as "1", so they are only
+	// redacted where the whole pair appears.
+	variables []string
+}
+
+// forSite returns the configuration to use for a resource scoped to a
+// multisite URL. An unset URL leaves the configuration unchanged.
+func (c *WPConfig) forSite(url types.String) *WPConfig {
+	if c == nil || url.IsNull() || url.IsUnknown() || url.ValueString() == "" {
+		return c
+	}
+	site := *c
+	site.URL = url.ValueString()
+	return &site
 }
 
 // defaultStringIfUnset returns the default value if the input is null or unknown.
@@ -27,3 +104,19 @@ func defaultBoolIfUnset(val types.Bool, def bool) bool {
 	}
 	return val.ValueBool()
 }
+
+// parseDurationIfSet parses a duration string, returning zero when it is unset.
+func parseDurationIfSet(val types.String) (time.Duration, error) {
+	s := defaultStringIfUnset(val, "")
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration %q must not be negative", s)
+	}
+	return d, nil
+}
@@ -5,6 +5,7 @@ package provider
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/stretchr/testify/assert"
@@ -45,3 +46,35 @@ func TestDefaultBoolIfUnset(t *testing.T) {
 	val = types.BoolValue(false)
 	assert.Equal(t, false, defaultBoolIfUnset(val, true), "should return false for false value")
 }
+
+func TestWPConfigForSite(t *testing.T) {
+	cfg := &WPConfig{SSHTarget: "docker:wp", RemotePath: "/var/www/html"}
+
+	assert.Same(t, cfg, cfg.forSite(types.StringNull()))
+	assert.Same(t, cfg, cfg.forSite(types.StringValue("")))
+
+	site := cfg.forSite(types.StringValue("https://example.com/blog"))
+	assert.Equal(t, "https://example.com/blog", site.URL)
+	assert.Equal(t, "docker:wp", site.SSHTarget)
+	assert.Equal(t, "", cfg.URL, "the provider configuration must not be modified")
+
+	cfg.URL = "https://example.com"
+	assert.Equal(t, "https://example.com/shop", cfg.forSite(types.StringValue("https://example.com/shop")).URL,
+		"a resource's url takes precedence over the provider's")
+}
+
+func TestParseDurationIfSet(t *testing.T) {
+	d, err := parseDurationIfSet(types.StringNull())
+	assert.NoError(t, err)
+	assert.Zero(t, d)
+
+	d, err = parseDurationIfSet(types.StringValue("500ms"))
+	assert.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, d)
+
+	_, err = parseDurationIfSet(types.StringValue("soon"))
+	assert.Error(t, err)
+
+	_, err = parseDurationIfSet(types.StringValue("-1s"))
+	assert.ErrorContains(t, err, "must not be negative")
+}
@@ -0,0 +1,156 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func NewPluginDataSource() datasource.DataSource {
+	return &wordpressPluginDataSource{}
+}
+
+type wordpressPluginDataSource struct {
+	config *WPConfig
+}
+
+// wordpressPluginDataModel describes a single plugin. It is shared with the
+// elements of the wordpress_plugins data source.
+type wordpressPluginDataModel struct {
+	Name            types.String `tfsdk:"name"`
+	Title           types.String `tfsdk:"title"`
+	Status          types.String `tfsdk:"status"`
+	Version         types.String `tfsdk:"version"`
+	Active          types.Bool   `tfsdk:"active"`
+	UpdateAvailable types.Bool   `tfsdk:"update_available"`
+	UpdateVersion   types.String `tfsdk:"update_version"`
+	AutoUpdate      types.Bool   `tfsdk:"auto_update"`
+}
+
+// wordpressPluginLookupModel is the wordpress_plugin data source, which also
+// reports whether the plugin is installed.
+type wordpressPluginLookupModel struct {
+	wordpressPluginDataModel
+	Installed types.Bool `tfsdk:"installed"`
+}
+
+// pluginDataAttributes returns the computed plugin attributes shared by both plugin data sources.
+func pluginDataAttributes() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"title": schema.StringAttribute{
+			Computed:    true,
+			Description: "The human readable plugin name.",
+		},
+		"status": schema.StringAttribute{
+			Computed:    true,
+			Description: "The plugin status ('active', 'inactive', 'active-network', 'must-use' or 'dropin').",
+		},
+		"version": schema.StringAttribute{
+			Computed:    true,
+			Description: "The installed plugin version.",
+		},
+		"active": schema.BoolAttribute{
+			Computed:    true,
+			Description: "Whether the plugin is active on the site or network wide.",
+		},
+		"update_available": schema.BoolAttribute{
+			Computed:    true,
+			Description: "Whether a newer version of the plugin is available.",
+		},
+		"update_version": schema.StringAttribute{
+			Computed:    true,
+			Description: "The version available for update, if any.",
+		},
+		"auto_update": schema.BoolAttribute{
+			Computed:    true,
+			Description: "Whether WordPress automatically updates the plugin.",
+		},
+	}
+}
+
+// newPluginDataModel converts WP-CLI plugin details into the data source model.
+func newPluginDataModel(info pluginInfo) wordpressPluginDataModel {
+	return wordpressPluginDataModel{
+		Name:            types.StringValue(info.Name),
+		Title:           types.StringValue(info.Title),
+		Status:          types.StringValue(info.Status),
+		Version:         types.StringValue(info.Version),
+		Active:          types.BoolValue(info.Active()),
+		UpdateAvailable: types.BoolValue(info.UpdateAvailable()),
+		UpdateVersion:   types.StringValue(info.UpdateVersion),
+		AutoUpdate:      types.BoolValue(info.AutoUpdateEnabled()),
+	}
+}
+
+func (d *wordpressPluginDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_plugin"
+}
+
+func (d *wordpressPluginDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	attributes := pluginDataAttributes()
+	attributes["name"] = schema.StringAttribute{
+		Required:    true,
+		Description: "The WP‑CLI plugin slug (e.g., 'woocommerce').",
+		Validators: []validator.String{
+			stringvalidator.RegexMatches(slugPattern, "must be a plugin slug of letters, digits, '.', '_' and '-', not starting with '-' or '.'"),
+		},
+	}
+
+	attributes["installed"] = schema.BoolAttribute{
+		Computed:    true,
+		Description: "Whether the plugin is installed. When false, the other plugin attributes are null.",
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "Looks up a single plugin, which need not be installed.",
+		Attributes:  attributes,
+	}
+}
+
+func (d *wordpressPluginDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	cfg, ok := req.ProviderData.(*WPConfig)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected Provider Data Type", "Expected *WPConfig")
+		return
+	}
+	d.config = cfg
+}
+
+func (d *wordpressPluginDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data wordpressPluginLookupModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "data.wordpress_plugin", data.Name.ValueString())
+
+	info, err := getPlugin(ctx, d.config, data.Name.ValueString())
+	if errors.Is(err, errPluginNotFound) {
+		// The other attributes stay null, as read from the configuration
+		data.Installed = types.BoolValue(false)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to read plugin", err)
+		return
+	}
+
+	data = wordpressPluginLookupModel{
+		wordpressPluginDataModel: newPluginDataModel(*info),
+		Installed:                types.BoolValue(true),
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
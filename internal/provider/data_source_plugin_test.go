@@ -0,0 +1,110 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+)
+
+// readDataSource runs a data source Read against the given configuration values.
+func readDataSource(t *testing.T, d datasource.DataSource, config map[string]tftypes.Value) *datasource.ReadResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	schemaResp := &datasource.SchemaResponse{}
+	d.Schema(ctx, datasource.SchemaRequest{}, schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := map[string]tftypes.Value{}
+	for name, typ := range objType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	for name, v := range config {
+		values[name] = v
+	}
+
+	req := datasource.ReadRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, values)},
+	}
+	resp := &datasource.ReadResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, nil)},
+	}
+	d.Read(ctx, req, resp)
+	return resp
+}
+
+func TestWordpressPluginDataSource_Metadata(t *testing.T) {
+	d := &wordpressPluginDataSource{}
+	resp := &datasource.MetadataResponse{}
+	d.Metadata(context.Background(), datasource.MetadataRequest{ProviderTypeName: "wordpress"}, resp)
+	assert.Equal(t, "wordpress_plugin", resp.TypeName)
+}
+
+func TestWordpressPluginDataSource_Configure(t *testing.T) {
+	d := &wordpressPluginDataSource{}
+	resp := &datasource.ConfigureResponse{}
+	d.Configure(context.Background(), datasource.ConfigureRequest{ProviderData: 42}, resp)
+	assert.True(t, resp.Diagnostics.HasError())
+	assert.Nil(t, d.config)
+}
+
+func TestWordpressPluginDataSource_Read(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+	cmdExec = mockCommander{output: []byte(testPluginListOutput)}
+
+	d := &wordpressPluginDataSource{config: &WPConfig{}}
+	resp := readDataSource(t, d, map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "akismet"),
+	})
+	assert.False(t, resp.Diagnostics.HasError(), "%v", resp.Diagnostics)
+
+	var data wordpressPluginLookupModel
+	resp.State.Get(context.Background(), &data)
+	assert.True(t, data.Installed.ValueBool())
+	assert.Equal(t, "Akismet Anti-spam", data.Title.ValueString())
+	assert.Equal(t, "inactive", data.Status.ValueString())
+	assert.True(t, data.UpdateAvailable.ValueBool())
+}
+
+func TestWordpressPluginDataSource_ReadNotFound(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+	cmdExec = mockCommander{output: []byte("[]")}
+
+	d := &wordpressPluginDataSource{config: &WPConfig{}}
+	resp := readDataSource(t, d, map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "woocommerce"),
+	})
+	assert.False(t, resp.Diagnostics.HasError(), "%v", resp.Diagnostics)
+
+	var data wordpressPluginLookupModel
+	resp.State.Get(context.Background(), &data)
+	assert.Equal(t, "woocommerce", data.Name.ValueString())
+	assert.Equal(t, types.BoolValue(false), data.Installed)
+	assert.True(t, data.Version.IsNull())
+	assert.True(t, data.Status.IsNull())
+	assert.True(t, data.Active.IsNull())
+}
+
+func TestWordpressPluginDataSource_ReadError(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+	cmdExec = mockCommander{output: []byte("PHP Fatal error:  Uncaught Error: Call to undefined function foo()"), err: assert.AnError}
+
+	// Failures other than a missing plugin are not reported as not installed
+	d := &wordpressPluginDataSource{config: &WPConfig{}}
+	resp := readDataSource(t, d, map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "woocommerce"),
+	})
+	assert.True(t, resp.Diagnostics.HasError())
+	assert.Equal(t, "Failed to read plugin", resp.Diagnostics.Errors()[0].Summary())
+}
@@ -0,0 +1,94 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func NewPluginsDataSource() datasource.DataSource {
+	return &wordpressPluginsDataSource{}
+}
+
+type wordpressPluginsDataSource struct {
+	config *WPConfig
+}
+
+type wordpressPluginsDataModel struct {
+	Status  types.String               `tfsdk:"status"`
+	Plugins []wordpressPluginDataModel `tfsdk:"plugins"`
+}
+
+func (d *wordpressPluginsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_plugins"
+}
+
+func (d *wordpressPluginsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	attributes := pluginDataAttributes()
+	attributes["name"] = schema.StringAttribute{
+		Computed:    true,
+		Description: "The WP‑CLI plugin slug.",
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "Lists the plugins installed on the site.",
+		Attributes: map[string]schema.Attribute{
+			"status": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only return plugins with this status ('active', 'inactive', 'active-network', 'must-use' or 'dropin').",
+				Validators: []validator.String{
+					stringvalidator.OneOf("active", "inactive", "active-network", "must-use", "dropin"),
+				},
+			},
+			"plugins": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The installed plugins.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: attributes,
+				},
+			},
+		},
+	}
+}
+
+func (d *wordpressPluginsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	cfg, ok := req.ProviderData.(*WPConfig)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected Provider Data Type", "Expected *WPConfig")
+		return
+	}
+	d.config = cfg
+}
+
+func (d *wordpressPluginsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data wordpressPluginsDataModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "data.wordpress_plugins", defaultStringIfUnset(data.Status, ""))
+
+	plugins, err := listPlugins(ctx, d.config, defaultStringIfUnset(data.Status, ""))
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to list plugins", err)
+		return
+	}
+
+	data.Plugins = make([]wordpressPluginDataModel, 0, len(plugins))
+	for _, p := range plugins {
+		data.Plugins = append(data.Plugins, newPluginDataModel(p))
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
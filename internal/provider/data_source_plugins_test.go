@@ -0,0 +1,49 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordpressPluginsDataSource_Metadata(t *testing.T) {
+	d := &wordpressPluginsDataSource{}
+	resp := &datasource.MetadataResponse{}
+	d.Metadata(context.Background(), datasource.MetadataRequest{ProviderTypeName: "wordpress"}, resp)
+	assert.Equal(t, "wordpress_plugins", resp.TypeName)
+}
+
+func TestWordpressPluginsDataSource_Read(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+	cmdExec = mockCommander{output: []byte(testPluginListOutput)}
+
+	d := &wordpressPluginsDataSource{config: &WPConfig{}}
+	resp := readDataSource(t, d, nil)
+	assert.False(t, resp.Diagnostics.HasError(), "%v", resp.Diagnostics)
+
+	var data wordpressPluginsDataModel
+	resp.State.Get(context.Background(), &data)
+	assert.Len(t, data.Plugins, 2)
+	assert.Equal(t, "woocommerce", data.Plugins[1].Name.ValueString())
+	assert.True(t, data.Plugins[1].Active.ValueBool())
+}
+
+func TestWordpressPluginsDataSource_ReadError(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+	cmdExec = mockCommander{output: []byte("Error: This does not seem to be a WordPress installation."), err: assert.AnError}
+
+	d := &wordpressPluginsDataSource{config: &WPConfig{}}
+	resp := readDataSource(t, d, map[string]tftypes.Value{
+		"status": tftypes.NewValue(tftypes.String, "active"),
+	})
+	assert.True(t, resp.Diagnostics.HasError())
+	assert.Equal(t, "Failed to list plugins", resp.Diagnostics.Errors()[0].Summary())
+}
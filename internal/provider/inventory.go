@@ -0,0 +1,86 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"sync"
+)
+
+// pluginInventory caches the plugin list of each site for the lifetime of a
+// provider instance, so that refreshing many plugins costs a single
+// `wp plugin list`. Any command that changes WordPress invalidates it.
+type pluginInventory struct {
+	mu    sync.Mutex
+	sites map[string]*inventoryEntry
+}
+
+// inventoryEntry is the plugin list of one site, fetched once.
+type inventoryEntry struct {
+	ready   chan struct{}
+	plugins []pluginInfo
+	err     error
+}
+
+func newPluginInventory() *pluginInventory {
+	return &pluginInventory{sites: map[string]*inventoryEntry{}}
+}
+
+// plugins returns the plugin list of the site cfg targets, calling fetch
+// when it is not cached. Concurrent callers share one fetch; a failed fetch
+// is not cached.
+func (inv *pluginInventory) plugins(ctx context.Context, cfg *WPConfig, fetch func() ([]pluginInfo, error)) ([]pluginInfo, error) {
+	for {
+		inv.mu.Lock()
+		entry, cached := inv.sites[cfg.URL]
+		if !cached {
+			entry = &inventoryEntry{ready: make(chan struct{})}
+			inv.sites[cfg.URL] = entry
+		}
+		inv.mu.Unlock()
+
+		if !cached {
+			entry.plugins, entry.err = fetch()
+			if entry.err != nil {
+				inv.forget(cfg.URL, entry)
+			}
+			close(entry.ready)
+			return entry.plugins, entry.err
+		}
+
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		// Fetch again with this caller's context when the shared fetch failed
+		if entry.err == nil {
+			return entry.plugins, nil
+		}
+	}
+}
+
+// forget drops entry if it is still cached for url.
+func (inv *pluginInventory) forget(url string, entry *inventoryEntry) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if inv.sites[url] == entry {
+		delete(inv.sites, url)
+	}
+}
+
+// invalidate drops the plugin lists of all sites. Network wide changes
+// affect every site, so the lists are not dropped per site.
+func (inv *pluginInventory) invalidate() {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.sites = map[string]*inventoryEntry{}
+}
+
+// invalidatePlugins drops the cached plugin lists after cfg changed WordPress.
+func (c *WPConfig) invalidatePlugins() {
+	if c.inventory != nil {
+		c.inventory.invalidate()
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPluginInventory = `[
+	{"name":"akismet","status":"active","version":"5.3"},
+	{"name":"hello","status":"inactive","version":"1.7.2"},
+	{"name":"woocommerce","status":"active-network","version":"9.0.0"}
+]`
+
+func TestPluginInventory_ServesReadsFromOneList(t *testing.T) {
+	seq := &sequenceCommander{outputs: []string{testPluginInventory}}
+	cfg := &WPConfig{Exec: seq, inventory: newPluginInventory()}
+	ctx := context.Background()
+
+	info, err := getPlugin(ctx, cfg, "akismet")
+	require.NoError(t, err)
+	assert.True(t, info.Active())
+	info, err = getPlugin(ctx, cfg, "hello")
+	require.NoError(t, err)
+	assert.False(t, info.Active())
+	_, err = getPlugin(ctx, cfg, "missing")
+	assert.ErrorIs(t, err, errPluginNotFound)
+
+	active, err := listPlugins(ctx, cfg, "active-network")
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "woocommerce", active[0].Name)
+
+	assert.Equal(t, 1, seq.calls)
+}
+
+func TestPluginInventory_InvalidatedByMutatingCommands(t *testing.T) {
+	seq := &sequenceCommander{outputs: []string{testPluginInventory}}
+	cfg := &WPConfig{Exec: seq, inventory: newPluginInventory()}
+	ctx := context.Background()
+
+	_, err := getPlugin(ctx, cfg, "akismet")
+	require.NoError(t, err)
+
+	// Read-only commands keep the list
+	_, err = runWPWithOutput(ctx, cfg, "option", "get", "blogname")
+	require.NoError(t, err)
+	_, err = getPlugin(ctx, cfg, "akismet")
+	require.NoError(t, err)
+	assert.Equal(t, 2, seq.calls)
+
+	require.NoError(t, runWP(ctx, cfg, "plugin", "deactivate", "akismet"))
+	_, err = getPlugin(ctx, cfg, "akismet")
+	require.NoError(t, err)
+	assert.Equal(t, 4, seq.calls)
+}
+
+func TestPluginInventory_PerSite(t *testing.T) {
+	recorder := &recordingCommander{}
+	cfg := &WPConfig{Exec: recorder, inventory: newPluginInventory()}
+	ctx := context.Background()
+
+	// Sites share the inventory but not their plugin lists
+	site := cfg.forSite(types.StringValue("https://example.com/shop"))
+	_, _ = listPlugins(ctx, cfg, "")
+	_, _ = listPlugins(ctx, site, "")
+
+	require.Len(t, recorder.calls, 2)
+	assert.NotContains(t, recorder.calls[0], "--url=https://example.com/shop")
+	assert.Contains(t, recorder.calls[1], "--url=https://example.com/shop")
+}
+
+func TestPluginInventory_SharesConcurrentFetches(t *testing.T) {
+	inv := newPluginInventory()
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	fetch := func() ([]pluginInfo, error) {
+		fetches.Add(1)
+		<-release
+		return []pluginInfo{{Name: "akismet"}}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			plugins, err := inv.plugins(context.Background(), &WPConfig{}, fetch)
+			assert.NoError(t, err)
+			assert.Len(t, plugins, 1)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), fetches.Load())
+}
+
+func TestPluginInventory_DoesNotCacheFailures(t *testing.T) {
+	inv := newPluginInventory()
+	cfg := &WPConfig{}
+
+	_, err := inv.plugins(context.Background(), cfg, func() ([]pluginInfo, error) {
+		return nil, errors.New("connection refused")
+	})
+	assert.Error(t, err)
+
+	plugins, err := inv.plugins(context.Background(), cfg, func() ([]pluginInfo, error) {
+		return []pluginInfo{{Name: "akismet"}}, nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, plugins, 1)
+}
+
+func TestPluginInventory_PollingBypassesCache(t *testing.T) {
+	seq := &sequenceCommander{outputs: []string{testInactivePlugin, testInactivePlugin, testActivePlugin}}
+	cfg := &WPConfig{Exec: seq, inventory: newPluginInventory(), PollInterval: time.Millisecond, PollMaxWait: time.Second}
+	ctx := context.Background()
+
+	_, err := getPlugin(ctx, cfg, "akismet")
+	require.NoError(t, err)
+
+	info, err := waitForPluginActive(ctx, cfg, "akismet", true)
+	require.NoError(t, err)
+	assert.True(t, info.Active())
+}
+
+func TestPluginInventory_InvalidatedByRESTChanges(t *testing.T) {
+	backend, _ := newTestRESTBackend(t, newFakeWordPressAPI(), "abcd efgh ijkl")
+	cfg := &WPConfig{Backend: backend, inventory: newPluginInventory()}
+	ctx := context.Background()
+
+	info, err := getPlugin(ctx, cfg, "akismet")
+	require.NoError(t, err)
+	assert.False(t, info.Active())
+
+	require.NoError(t, backend.SetPluginActive(ctx, cfg, "akismet", true))
+	info, err = getPlugin(ctx, cfg, "akismet")
+	require.NoError(t, err)
+	assert.True(t, info.Active())
+}
@@ -0,0 +1,187 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// lockFilePollInterval is how often a mutating command retries a lock file
+// held by another provider process.
+const lockFilePollInterval = 50 * time.Millisecond
+
+// commandLimiter bounds the commands a provider instance runs against its
+// WordPress installation at once. Terraform applies resources in parallel,
+// and concurrent installs and updates race on wp-content/upgrade, so
+// commands that change WordPress can be made to wait for each other while
+// read-only commands keep running in parallel.
+type commandLimiter struct {
+	// slots holds one token per running command. It is nil when the
+	// number of commands is not limited.
+	slots chan struct{}
+
+	// mutating is held while a mutating command runs. It is nil when
+	// mutating commands may run concurrently.
+	mutating *targetLock
+}
+
+// newCommandLimiter returns a limiter running at most maxParallel commands
+// at once, or any number when maxParallel is zero, and mutating commands
+// one at a time when serializeMutating is set. Limiters with the same
+// non-empty target serialize their mutating commands together, so provider
+// aliases for subsites of one multisite install do not race on its
+// wp-content.
+func newCommandLimiter(maxParallel int, serializeMutating bool, target string) *commandLimiter {
+	l := &commandLimiter{}
+	if maxParallel > 0 {
+		l.slots = make(chan struct{}, maxParallel)
+	}
+	if serializeMutating {
+		l.mutating = sharedTargetLock(target)
+	}
+	return l
+}
+
+// acquire waits until a command of the given class may run and returns the
+// function that releases its place. It fails when ctx is done first.
+func (l *commandLimiter) acquire(ctx context.Context, class string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	// Wait for the mutating lock before taking a slot, so that queued
+	// mutating commands do not hold slots read-only commands could use
+	var held []func()
+	release := func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			held[i]()
+		}
+	}
+	if class == commandMutating && l.mutating != nil {
+		unlock, err := l.mutating.lock(ctx)
+		if err != nil {
+			return nil, err
+		}
+		held = append(held, unlock)
+	}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			held = append(held, func() { <-l.slots })
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// targetLock serializes mutating commands against one WordPress
+// installation: across provider instances in this process through sem, and
+// across the plugin processes Terraform starts for each provider
+// configuration through an exclusive lock on the file at path.
+type targetLock struct {
+	sem chan struct{}
+	// path is empty when the lock is not shared with other processes.
+	path string
+}
+
+// targetLocks holds the lock of each target, shared by every provider
+// instance in this process.
+var (
+	targetLocksMu sync.Mutex
+	targetLocks   = map[string]*targetLock{}
+)
+
+// sharedTargetLock returns the lock for target, or a lock of its own when
+// target is empty.
+func sharedTargetLock(target string) *targetLock {
+	if target == "" {
+		return &targetLock{sem: make(chan struct{}, 1)}
+	}
+
+	targetLocksMu.Lock()
+	defer targetLocksMu.Unlock()
+	if l, ok := targetLocks[target]; ok {
+		return l
+	}
+	l := &targetLock{sem: make(chan struct{}, 1), path: targetLockPath(target)}
+	targetLocks[target] = l
+	return l
+}
+
+// targetLockPath returns the lock file for target in the user's cache
+// directory, or "" when there is none.
+func targetLockPath(target string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	dir = filepath.Join(dir, "terraform-provider-wordpress", "locks")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(target))
+	return filepath.Join(dir, hex.EncodeToString(sum[:16])+".lock")
+}
+
+// lock waits for the lock and returns the function that releases it. It
+// fails when ctx is done first.
+func (l *targetLock) lock(ctx context.Context) (func(), error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if l.path == "" {
+		return func() { <-l.sem }, nil
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		// Still serialize the commands of this process
+		tflog.Warn(ctx, "Unable to open the command lock file", map[string]interface{}{
+			"path":        l.path,
+			logFieldError: err.Error(),
+		})
+		return func() { <-l.sem }, nil
+	}
+	unlock := func() {
+		_ = unlockFile(f)
+		_ = f.Close()
+		<-l.sem
+	}
+
+	ticker := time.NewTicker(lockFilePollInterval)
+	defer ticker.Stop()
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			tflog.Warn(ctx, "Unable to lock the command lock file", map[string]interface{}{
+				"path":        l.path,
+				logFieldError: err.Error(),
+			})
+			_ = f.Close()
+			return func() { <-l.sem }, nil
+		}
+		if locked {
+			return unlock, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			_ = f.Close()
+			<-l.sem
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrencyCommander records the most commands that ran at once.
+type concurrencyCommander struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (c *concurrencyCommander) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	c.mu.Lock()
+	c.running++
+	c.peak = max(c.peak, c.running)
+	c.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+	return nil, nil
+}
+
+// runConcurrently runs n copies of the wp command args at once.
+func runConcurrently(t *testing.T, cfg *WPConfig, n int, args ...string) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, runWP(context.Background(), cfg, args...))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCommandLimiter_SerializesMutatingCommands(t *testing.T) {
+	exec := &concurrencyCommander{}
+	cfg := &WPConfig{Exec: exec, limiter: newCommandLimiter(0, true, "")}
+
+	runConcurrently(t, cfg, 4, "plugin", "install", "akismet")
+	assert.Equal(t, 1, exec.peak)
+}
+
+func TestCommandLimiter_ReadOnlyCommandsRunInParallel(t *testing.T) {
+	exec := &concurrencyCommander{}
+	cfg := &WPConfig{Exec: exec, limiter: newCommandLimiter(0, true, "")}
+
+	runConcurrently(t, cfg, 4, "plugin", "list", "--format=json")
+	assert.Greater(t, exec.peak, 1)
+}
+
+func TestCommandLimiter_MaxParallel(t *testing.T) {
+	exec := &concurrencyCommander{}
+	cfg := &WPConfig{Exec: exec, limiter: newCommandLimiter(2, true, "")}
+
+	runConcurrently(t, cfg, 6, "plugin", "list", "--format=json")
+	assert.Equal(t, 2, exec.peak)
+}
+
+func TestCommandLimiter_MutatingInParallelWhenNotSerialized(t *testing.T) {
+	exec := &concurrencyCommander{}
+	cfg := &WPConfig{Exec: exec, limiter: newCommandLimiter(0, false, "")}
+
+	runConcurrently(t, cfg, 4, "plugin", "install", "akismet")
+	assert.Greater(t, exec.peak, 1)
+}
+
+func TestCommandLimiter_SharedAcrossSites(t *testing.T) {
+	exec := &concurrencyCommander{}
+	cfg := &WPConfig{Exec: exec, limiter: newCommandLimiter(0, true, "")}
+	site := *cfg
+	site.URL = "https://example.com/shop"
+
+	var wg sync.WaitGroup
+	for _, c := range []*WPConfig{cfg, &site, cfg, &site} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, runWP(context.Background(), c, "plugin", "activate", "akismet"))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, exec.peak)
+}
+
+func TestCommandLimiter_SharedAcrossProviders(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	// Two aliases for subsites of the same install serialize their
+	// mutating commands, while another install does not wait for them
+	exec := &concurrencyCommander{}
+	shop := &WPConfig{Exec: exec, URL: "https://example.com/shop", limiter: newCommandLimiter(0, true, t.Name()+"/html")}
+	blog := &WPConfig{Exec: exec, URL: "https://example.com/blog", limiter: newCommandLimiter(0, true, t.Name()+"/html")}
+
+	var wg sync.WaitGroup
+	for _, c := range []*WPConfig{shop, blog, shop, blog} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, runWP(context.Background(), c, "plugin", "install", "akismet"))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, exec.peak)
+
+	other := newCommandLimiter(0, true, t.Name()+"/other")
+	release, err := shop.limiter.acquire(context.Background(), commandMutating)
+	require.NoError(t, err)
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	releaseOther, err := other.acquire(ctx, commandMutating)
+	require.NoError(t, err)
+	releaseOther()
+}
+
+func TestCommandLimiter_SharedAcrossProcesses(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	limiter := newCommandLimiter(0, true, t.Name())
+	require.NotEmpty(t, limiter.mutating.path)
+
+	// Another plugin process holding the lock file makes mutating commands
+	// wait for it
+	f, err := os.OpenFile(limiter.mutating.path, os.O_CREATE|os.O_RDWR, 0o600)
+	require.NoError(t, err)
+	defer f.Close()
+	locked, err := tryLockFile(f)
+	require.NoError(t, err)
+	require.True(t, locked)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = limiter.acquire(ctx, commandMutating)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Read-only commands do not take the lock
+	release, err := limiter.acquire(context.Background(), commandReadOnly)
+	require.NoError(t, err)
+	release()
+
+	require.NoError(t, unlockFile(f))
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	release, err = limiter.acquire(ctx, commandMutating)
+	require.NoError(t, err)
+
+	// The lock is held until the command is done
+	locked, err = tryLockFile(f)
+	require.NoError(t, err)
+	assert.False(t, locked)
+	release()
+	locked, err = tryLockFile(f)
+	require.NoError(t, err)
+	assert.True(t, locked)
+}
+
+func TestCommandLimiter_WaitingRespectsContext(t *testing.T) {
+	limiter := newCommandLimiter(0, true, "")
+	release, err := limiter.acquire(context.Background(), commandMutating)
+	require.NoError(t, err)
+	defer release()
+
+	cfg := &WPConfig{Exec: &concurrencyCommander{}, limiter: limiter}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = runWP(ctx, cfg, "plugin", "install", "akismet")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "wp plugin install akismet was canceled while waiting for other commands")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = runWP(ctx, cfg, "plugin", "install", "akismet")
+	var timeout *commandTimeoutError
+	assert.ErrorAs(t, err, &timeout)
+
+	// Read-only commands do not wait for mutating ones
+	assert.NoError(t, runWP(context.Background(), cfg, "plugin", "list"))
+}
+
+func TestCommandLimiter_ReleasesSlotOnCancel(t *testing.T) {
+	limiter := newCommandLimiter(1, true, "")
+	release, err := limiter.acquire(context.Background(), commandReadOnly)
+	require.NoError(t, err)
+
+	// A mutating command holding the mutating token while waiting for a
+	// slot must give the token back when it stops waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = limiter.acquire(ctx, commandMutating)
+	assert.ErrorIs(t, err, context.Canceled)
+	release()
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	release, err = limiter.acquire(ctx, commandMutating)
+	require.NoError(t, err)
+	release()
+}
+
+func TestCommandLimiter_Nil(t *testing.T) {
+	var limiter *commandLimiter
+	release, err := limiter.acquire(context.Background(), commandMutating)
+	require.NoError(t, err)
+	release()
+}
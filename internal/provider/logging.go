@@ -0,0 +1,99 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Logging subsystems. Their level can be set apart from the provider's with
+// TF_LOG_PROVIDER_WORDPRESS_<SUBSYSTEM>, e.g. TF_LOG_PROVIDER_WORDPRESS_WP_CLI.
+const (
+	// logTransport logs how commands and requests reach the WordPress host.
+	logTransport = "transport"
+
+	// logWPCLI logs the WP-CLI commands run and their results.
+	logWPCLI = "wp-cli"
+
+	// logParser logs decoding of WP-CLI output.
+	logParser = "parser"
+)
+
+// Structured log field keys.
+const (
+	logFieldCommand  = "command"
+	logFieldTarget   = "target"
+	logFieldDuration = "duration"
+	logFieldExitCode = "exit_code"
+	logFieldClass    = "class"
+	logFieldAttempt  = "attempt"
+	logFieldError    = "error"
+	logFieldPlugin   = "plugin"
+)
+
+// sensitiveLogFields are field keys whose values are masked in all logs.
+var sensitiveLogFields = []string{"password", "user_pass", "application_password", "passphrase", "private_key"}
+
+// loggingKey marks a context that already carries the logging subsystems.
+type loggingKey struct{}
+
+// withLogging returns ctx with the provider's logging subsystems and
+// masking set up. It returns ctx unchanged when that was already done.
+func withLogging(ctx context.Context) context.Context {
+	if ctx.Value(loggingKey{}) != nil {
+		return ctx
+	}
+
+	ctx = tflog.MaskFieldValuesWithFieldKeys(ctx, sensitiveLogFields...)
+	ctx = tflog.MaskAllFieldValuesRegexes(ctx, sensitiveFlagValues)
+	for _, subsystem := range []string{logTransport, logWPCLI, logParser} {
+		env := strings.ReplaceAll(subsystem, "-", "_")
+		ctx = tflog.NewSubsystem(ctx, subsystem, tflog.WithLevelFromEnv("TF_LOG_PROVIDER_WORDPRESS", env))
+		ctx = tflog.SubsystemMaskFieldValuesWithFieldKeys(ctx, subsystem, sensitiveLogFields...)
+		ctx = tflog.SubsystemMaskAllFieldValuesRegexes(ctx, subsystem, sensitiveFlagValues)
+	}
+	return context.WithValue(ctx, loggingKey{}, true)
+}
+
+// targeter is implemented by transports and backends that can name the
+// host they run commands on.
+type targeter interface {
+	target() string
+}
+
+// target names the host cfg runs commands on, for logs.
+func (c *WPConfig) target() string {
+	if t, ok := c.Backend.(targeter); ok {
+		return t.target()
+	}
+	if t, ok := c.commander().(targeter); ok {
+		return t.target()
+	}
+	if c.SSHTarget != "" {
+		return c.SSHTarget
+	}
+	return c.transport()
+}
+
+// parseOutput decodes the output of a WP-CLI command with parse, logging the
+// outcome to the parser subsystem.
+func parseOutput[T any](ctx context.Context, command, output string, parse func(string) (T, error)) (T, error) {
+	ctx = withLogging(ctx)
+	v, err := parse(output)
+	if err != nil {
+		tflog.SubsystemDebug(ctx, logParser, "Unable to parse WP-CLI output", map[string]interface{}{
+			logFieldCommand: command,
+			logFieldError:   err.Error(),
+		})
+		return v, err
+	}
+	tflog.SubsystemTrace(ctx, logParser, "Parsed WP-CLI output", map[string]interface{}{
+		logFieldCommand: command,
+		"bytes":         len(output),
+	})
+	return v, nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logEntries decodes the JSON log lines written to buf.
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	entries, err := tflogtest.MultilineJSONDecode(buf)
+	require.NoError(t, err)
+	return entries
+}
+
+// findLogEntry returns the first entry with the given message.
+func findLogEntry(t *testing.T, entries []map[string]interface{}, msg string) map[string]interface{} {
+	t.Helper()
+	for _, entry := range entries {
+		if entry["@message"] == msg {
+			return entry
+		}
+	}
+	require.Failf(t, "log entry not found", "no entry %q in %v", msg, entries)
+	return nil
+}
+
+func TestRunCommand_LogsCommand(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &buf)
+	cfg := &WPConfig{Exec: &splitCommander{stdout: []byte("[]"), stderr: []byte("PHP Notice: deprecated")}, SSHTarget: "deploy@wp.example.com"}
+
+	_, err := runWPWithOutput(ctx, cfg, "plugin", "list", "--format=json")
+	require.NoError(t, err)
+
+	entries := logEntries(t, &buf)
+	start := findLogEntry(t, entries, "Running command")
+	assert.Equal(t, "provider.wp-cli", start["@module"])
+	assert.Equal(t, "wp --ssh=deploy@wp.example.com plugin list --format=json", start[logFieldCommand])
+	assert.Equal(t, "deploy@wp.example.com", start[logFieldTarget])
+	assert.Equal(t, commandReadOnly, start[logFieldClass])
+
+	done := findLogEntry(t, entries, "Command finished")
+	assert.Equal(t, float64(0), done[logFieldExitCode])
+	assert.NotEmpty(t, done[logFieldDuration])
+	assert.Equal(t, "PHP Notice: deprecated", done["stderr"])
+}
+
+func TestRunCommand_LogsFailure(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &buf)
+	cfg := &WPConfig{Exec: &splitCommander{stderr: []byte("Error: boom"), err: &dockerExitError{ExitCode: 1}}}
+
+	err := runWP(ctx, cfg, "user", "create", "bob", "bob@example.com", "--user_pass=hunter2")
+	require.Error(t, err)
+
+	failed := findLogEntry(t, logEntries(t, &buf), "Command failed")
+	assert.Equal(t, float64(1), failed[logFieldExitCode])
+	assert.Equal(t, commandMutating, failed[logFieldClass])
+	assert.NotContains(t, failed[logFieldCommand], "hunter2")
+	assert.Equal(t, "Error: boom", failed["stderr"])
+}
+
+func TestWithLogging_MasksSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := withLogging(tflogtest.RootLogger(context.Background(), &buf))
+
+	tflog.Debug(ctx, "root", map[string]interface{}{"password": "hunter2"})
+	tflog.SubsystemDebug(ctx, logWPCLI, "subsystem", map[string]interface{}{
+		"application_password": "abcd efgh",
+		"stderr":               "usage: wp user create bob --user_pass=hunter2",
+	})
+
+	output := buf.String()
+	assert.NotContains(t, output, "hunter2")
+	assert.NotContains(t, output, "abcd efgh")
+	assert.Contains(t, output, "usage: wp user create bob ***")
+}
+
+func TestWithLogging_Idempotent(t *testing.T) {
+	ctx := withLogging(context.Background())
+	assert.Equal(t, ctx, withLogging(ctx))
+}
+
+func TestParseOutput_Logs(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &buf)
+
+	_, err := parseOutput(ctx, "plugin list", "not json", parsePluginList)
+	require.Error(t, err)
+	plugins, err := parseOutput(ctx, "plugin list", `[{"name":"akismet"}]`, parsePluginList)
+	require.NoError(t, err)
+	assert.Len(t, plugins, 1)
+
+	entries := logEntries(t, &buf)
+	failed := findLogEntry(t, entries, "Unable to parse WP-CLI output")
+	assert.Equal(t, "provider.parser", failed["@module"])
+	assert.Equal(t, "plugin list", failed[logFieldCommand])
+	parsed := findLogEntry(t, entries, "Parsed WP-CLI output")
+	assert.Equal(t, "trace", parsed["@level"])
+}
+
+func TestWPConfig_Target(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *WPConfig
+		want string
+	}{
+		{"wp-cli", &WPConfig{SSHTarget: "docker:wordpress"}, "docker:wordpress"},
+		{"wp-cli without target", &WPConfig{}, transportWPCLI},
+		{"docker", &WPConfig{Exec: &dockerTransport{container: "wordpress"}}, "docker:wordpress"},
+		{"kubernetes pod", &WPConfig{Exec: &kubernetesTransport{namespace: "web", pod: "wordpress-0"}}, "web/wordpress-0"},
+		{"kubernetes selector", &WPConfig{Exec: &kubernetesTransport{namespace: "web", labelSelector: "app=wordpress"}}, "web/app=wordpress"},
+		{"local", &WPConfig{Exec: &localTransport{}}, "localhost"},
+		{"rest", &WPConfig{Backend: &restBackend{url: "https://example.com"}}, "https://example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cfg.target())
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// pluginDownloadTimeout bounds how long fetching a plugin archive from a URL may take.
+const pluginDownloadTimeout = 5 * time.Minute
+
+// isURLSource reports whether a plugin source is a URL rather than a local path.
+func isURLSource(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// fetchPluginArchive makes a plugin source available as a local zip file.
+// URLs are downloaded to a temporary file that is removed by the returned
+// cleanup function; local paths are used as-is.
+func fetchPluginArchive(ctx context.Context, source string) (string, func(), error) {
+	noop := func() {}
+
+	if !isURLSource(source) {
+		if _, err := os.Stat(source); err != nil {
+			return "", noop, fmt.Errorf("plugin archive %s is not readable: %w", source, err)
+		}
+		return source, noop, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pluginDownloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return "", noop, fmt.Errorf("invalid plugin source URL %s: %w", source, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to download plugin archive from %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", noop, fmt.Errorf("failed to download plugin archive from %s: HTTP %s", source, resp.Status)
+	}
+
+	file, err := os.CreateTemp("", "terraform-wordpress-*.zip")
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() { os.Remove(file.Name()) }
+
+	// CreateTemp makes the file readable by its owner only, but the local
+	// transport may run wp as another user through sudo
+	if err := file.Chmod(0o644); err != nil {
+		file.Close()
+		cleanup()
+		return "", noop, err
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		cleanup()
+		return "", noop, fmt.Errorf("failed to download plugin archive from %s: %w", source, err)
+	}
+	if err := file.Close(); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+
+	return file.Name(), cleanup, nil
+}
+
+// verifyChecksum checks that the file's SHA-256 digest matches the expected hex value.
+func verifyChecksum(filePath, expected string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch: expected sha256 %s, got %s", strings.ToLower(expected), actual)
+	}
+	return nil
+}
+
+// pluginSlugFromArchive derives the plugin slug from a zip archive. WordPress
+// installs the archive's top-level directory as the plugin directory.
+func pluginSlugFromArchive(filePath string) (string, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("plugin source is not a valid zip archive: %w", err)
+	}
+	defer archive.Close()
+
+	slug := ""
+	for _, f := range archive.File {
+		name := strings.TrimPrefix(path.Clean(strings.ReplaceAll(f.Name, "\\", "/")), "/")
+		if strings.HasPrefix(name, "__MACOSX") {
+			continue
+		}
+
+		top, _, nested := strings.Cut(name, "/")
+		if !nested && !f.FileInfo().IsDir() {
+			// A single-file plugin at the archive root, e.g. hello.php
+			if strings.HasSuffix(top, ".php") && len(archive.File) == 1 {
+				return strings.TrimSuffix(top, ".php"), nil
+			}
+			return "", fmt.Errorf("plugin archive must contain a single top-level directory, found file %s", name)
+		}
+
+		if slug != "" && slug != top {
+			return "", fmt.Errorf("plugin archive must contain a single top-level directory, found %s and %s", slug, top)
+		}
+		slug = top
+	}
+
+	if slug == "" {
+		return "", fmt.Errorf("plugin archive is empty")
+	}
+	return slug, nil
+}
@@ -0,0 +1,143 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestZip creates a zip archive with the given file names and returns its path.
+func writeTestZip(t *testing.T, names ...string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "plugin.zip")
+	f, err := os.Create(p)
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	for _, name := range names {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+		if strings.HasSuffix(name, "/") {
+			continue
+		}
+		_, err = fw.Write([]byte("<?php // " + name))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+	return p
+}
+
+func fileSHA256(t *testing.T, p string) string {
+	t.Helper()
+	data, err := os.ReadFile(p)
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestPluginSlugFromArchive(t *testing.T) {
+	slug, err := pluginSlugFromArchive(writeTestZip(t, "my-plugin/", "my-plugin/my-plugin.php", "my-plugin/inc/a.php", "__MACOSX/._my-plugin"))
+	assert.NoError(t, err)
+	assert.Equal(t, "my-plugin", slug)
+
+	slug, err = pluginSlugFromArchive(writeTestZip(t, "hello.php"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", slug)
+
+	_, err = pluginSlugFromArchive(writeTestZip(t, "one/a.php", "two/b.php"))
+	assert.ErrorContains(t, err, "single top-level directory")
+
+	_, err = pluginSlugFromArchive(writeTestZip(t, "readme.txt", "plugin/plugin.php"))
+	assert.Error(t, err)
+
+	notZip := filepath.Join(t.TempDir(), "plugin.zip")
+	require.NoError(t, os.WriteFile(notZip, []byte("not a zip"), 0o600))
+	_, err = pluginSlugFromArchive(notZip)
+	assert.ErrorContains(t, err, "not a valid zip archive")
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	p := writeTestZip(t, "my-plugin/my-plugin.php")
+	sum := fileSHA256(t, p)
+
+	assert.NoError(t, verifyChecksum(p, sum))
+	err := verifyChecksum(p, "0000000000000000000000000000000000000000000000000000000000000000")
+	assert.ErrorContains(t, err, "checksum mismatch")
+}
+
+func TestFetchPluginArchive_Local(t *testing.T) {
+	p := writeTestZip(t, "my-plugin/my-plugin.php")
+	got, cleanup, err := fetchPluginArchive(context.Background(), p)
+	defer cleanup()
+	assert.NoError(t, err)
+	assert.Equal(t, p, got)
+
+	_, _, err = fetchPluginArchive(context.Background(), filepath.Join(t.TempDir(), "missing.zip"))
+	assert.Error(t, err)
+}
+
+func TestFetchPluginArchive_URL(t *testing.T) {
+	p := writeTestZip(t, "premium-plugin/premium-plugin.php")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/premium-plugin.zip" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, p)
+	}))
+	defer server.Close()
+
+	got, cleanup, err := fetchPluginArchive(context.Background(), server.URL+"/premium-plugin.zip")
+	assert.NoError(t, err)
+	assert.Equal(t, fileSHA256(t, p), fileSHA256(t, got))
+	// The user wp runs as through sudo can read the archive
+	info, err := os.Stat(got)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+	cleanup()
+	_, err = os.Stat(got)
+	assert.True(t, os.IsNotExist(err), "downloaded archive should be removed by cleanup")
+
+	_, cleanup, err = fetchPluginArchive(context.Background(), server.URL+"/missing.zip")
+	cleanup()
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestPreparePluginSource(t *testing.T) {
+	p := writeTestZip(t, "in-house/in-house.php")
+
+	plan := wordpressPluginModel{
+		Name:     types.StringUnknown(),
+		Source:   types.StringValue(p),
+		Checksum: types.StringValue(fileSHA256(t, p)),
+	}
+	remote, cleanup, err := preparePluginSource(context.Background(), &WPConfig{}, &plan)
+	defer cleanup()
+	assert.NoError(t, err)
+	assert.Equal(t, p, remote)
+	assert.Equal(t, "in-house", plan.Name.ValueString())
+
+	mismatch := wordpressPluginModel{Name: types.StringValue("other"), Source: types.StringValue(p)}
+	_, cleanup, err = preparePluginSource(context.Background(), &WPConfig{}, &mismatch)
+	cleanup()
+	assert.ErrorContains(t, err, `contains plugin "in-house"`)
+
+	badSum := wordpressPluginModel{Source: types.StringValue(p), Checksum: types.StringValue("ab")}
+	_, cleanup, err = preparePluginSource(context.Background(), &WPConfig{}, &badSum)
+	cleanup()
+	assert.ErrorContains(t, err, "checksum mismatch")
+}
@@ -0,0 +1,99 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// pluginListFields are the fields requested from `wp plugin list`.
+const pluginListFields = "name,title,status,version,update,update_version,auto_update"
+
+// errPluginNotFound is returned by getPlugin when the plugin is not installed.
+var errPluginNotFound = errors.New("plugin not found")
+
+// pluginInfo is a plugin as reported by `wp plugin list --format=json`.
+type pluginInfo struct {
+	Name          string `json:"name"`
+	Title         string `json:"title"`
+	Status        string `json:"status"`
+	Version       string `json:"version"`
+	Update        string `json:"update"`
+	UpdateVersion string `json:"update_version"`
+	AutoUpdate    string `json:"auto_update"`
+}
+
+// Active reports whether the plugin is active on the site or network wide.
+func (p pluginInfo) Active() bool {
+	return p.Status == "active" || p.Status == "active-network"
+}
+
+// UpdateAvailable reports whether wordpress.org offers a newer version.
+func (p pluginInfo) UpdateAvailable() bool {
+	return p.Update == "available"
+}
+
+// AutoUpdateEnabled reports whether WordPress auto-updates the plugin.
+func (p pluginInfo) AutoUpdateEnabled() bool {
+	return p.AutoUpdate == "on"
+}
+
+// listPlugins returns the installed plugins, optionally filtered by status.
+// The list is served from the plugin inventory when the provider keeps one.
+func listPlugins(ctx context.Context, cfg *WPConfig, status string) ([]pluginInfo, error) {
+	if cfg.inventory == nil {
+		return cfg.backend().ListPlugins(ctx, cfg, status)
+	}
+
+	plugins, err := cfg.inventory.plugins(ctx, cfg, func() ([]pluginInfo, error) {
+		return cfg.backend().ListPlugins(ctx, cfg, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	filtered := []pluginInfo{}
+	for _, p := range plugins {
+		if status == "" || p.Status == status {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
+
+// getPlugin returns a single installed plugin, or errPluginNotFound. The
+// plugin is looked up in the plugin inventory when the provider keeps one.
+func getPlugin(ctx context.Context, cfg *WPConfig, slug string) (*pluginInfo, error) {
+	if cfg.inventory == nil {
+		return cfg.backend().GetPlugin(ctx, cfg, slug)
+	}
+
+	plugins, err := listPlugins(ctx, cfg, "")
+	if err != nil {
+		return nil, err
+	}
+	return findPlugin(plugins, slug)
+}
+
+// findPlugin returns the plugin named slug, or errPluginNotFound.
+func findPlugin(plugins []pluginInfo, slug string) (*pluginInfo, error) {
+	for i := range plugins {
+		if plugins[i].Name == slug {
+			return &plugins[i], nil
+		}
+	}
+	return nil, errPluginNotFound
+}
+
+// parsePluginList decodes the JSON output of `wp plugin list --format=json`.
+func parsePluginList(output string) ([]pluginInfo, error) {
+	var plugins []pluginInfo
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &plugins); err != nil {
+		return nil, fmt.Errorf("unable to parse plugin list: %v\nOutput: %s", err, output)
+	}
+	return plugins, nil
+}
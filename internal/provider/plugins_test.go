@@ -0,0 +1,73 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginListOutput = `[
+  {"name":"akismet","title":"Akismet Anti-spam","status":"inactive","version":"5.3","update":"available","update_version":"5.4","auto_update":"off"},
+  {"name":"woocommerce","title":"WooCommerce","status":"active","version":"9.0.0","update":"none","update_version":"","auto_update":"on"}
+]`
+
+func TestParsePluginList(t *testing.T) {
+	plugins, err := parsePluginList(testPluginListOutput)
+	assert.NoError(t, err)
+	assert.Len(t, plugins, 2)
+
+	assert.Equal(t, "akismet", plugins[0].Name)
+	assert.False(t, plugins[0].Active())
+	assert.True(t, plugins[0].UpdateAvailable())
+	assert.Equal(t, "5.4", plugins[0].UpdateVersion)
+	assert.False(t, plugins[0].AutoUpdateEnabled())
+
+	assert.Equal(t, "WooCommerce", plugins[1].Title)
+	assert.True(t, plugins[1].Active())
+	assert.False(t, plugins[1].UpdateAvailable())
+	assert.True(t, plugins[1].AutoUpdateEnabled())
+}
+
+func TestParsePluginList_Invalid(t *testing.T) {
+	_, err := parsePluginList("PHP Warning: something\n")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to parse plugin list")
+}
+
+func TestPluginInfo_NetworkActive(t *testing.T) {
+	assert.True(t, pluginInfo{Status: "active-network"}.Active())
+	assert.False(t, pluginInfo{Status: "must-use"}.Active())
+}
+
+func TestGetPlugin(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+
+	cmdExec = mockCommander{output: []byte(testPluginListOutput)}
+	info, err := getPlugin(context.Background(), &WPConfig{}, "woocommerce")
+	assert.NoError(t, err)
+	assert.Equal(t, "9.0.0", info.Version)
+
+	cmdExec = mockCommander{output: []byte("[]")}
+	_, err = getPlugin(context.Background(), &WPConfig{}, "missing")
+	assert.ErrorIs(t, err, errPluginNotFound)
+
+	cmdExec = mockCommander{output: []byte("Error: boom"), err: assert.AnError}
+	_, err = getPlugin(context.Background(), &WPConfig{}, "akismet")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, errPluginNotFound)
+}
+
+func TestListPlugins(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+
+	cmdExec = mockCommander{output: []byte(testPluginListOutput)}
+	plugins, err := listPlugins(context.Background(), &WPConfig{}, "")
+	assert.NoError(t, err)
+	assert.Len(t, plugins, 2)
+}
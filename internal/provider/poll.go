@@ -0,0 +1,71 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultPollInterval is the delay between status checks while waiting.
+	defaultPollInterval = 1 * time.Second
+
+	// defaultPollMaxWait bounds how long a status check is retried.
+	defaultPollMaxWait = 30 * time.Second
+)
+
+// waitForPlugin polls the plugin status until it is installed and ready
+// reports true, the context is done or the configured maximum wait elapses.
+// The first check happens immediately, so no time is spent waiting when
+// WP-CLI already reports the desired state. want describes the desired state
+// for error messages.
+func waitForPlugin(ctx context.Context, cfg *WPConfig, slug, want string, ready func(*pluginInfo) bool) (*pluginInfo, error) {
+	interval := cfg.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	maxWait := cfg.PollMaxWait
+	if maxWait <= 0 {
+		maxWait = defaultPollMaxWait
+	}
+
+	deadline := time.Now().Add(maxWait)
+	for {
+		// Bypass the plugin inventory, which would keep returning the first result
+		info, err := cfg.backend().GetPlugin(ctx, cfg, slug)
+		if err != nil && !errors.Is(err, errPluginNotFound) {
+			return nil, err
+		}
+		if err == nil && ready(info) {
+			return info, nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			if err != nil {
+				return nil, fmt.Errorf("plugin %s is still not installed after waiting %s", slug, maxWait)
+			}
+			return nil, fmt.Errorf("plugin %s did not become %s within %s (status: %s)", slug, want, maxWait, info.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for plugin %s to become %s: %w", slug, want, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// waitForPluginActive waits until the plugin is installed with the given activation state.
+func waitForPluginActive(ctx context.Context, cfg *WPConfig, slug string, active bool) (*pluginInfo, error) {
+	want := "inactive"
+	if active {
+		want = "active"
+	}
+	return waitForPlugin(ctx, cfg, slug, want, func(info *pluginInfo) bool {
+		return info.Active() == active
+	})
+}
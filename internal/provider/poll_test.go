@@ -0,0 +1,91 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sequenceCommander returns the given outputs in order, repeating the last one.
+type sequenceCommander struct {
+	outputs []string
+	calls   int
+}
+
+func (s *sequenceCommander) CombinedOutput(_ context.Context, name string, args ...string) ([]byte, error) {
+	i := s.calls
+	if i >= len(s.outputs) {
+		i = len(s.outputs) - 1
+	}
+	s.calls++
+	return []byte(s.outputs[i]), nil
+}
+
+const (
+	testInactivePlugin = `[{"name":"akismet","status":"inactive","version":"5.3"}]`
+	testActivePlugin   = `[{"name":"akismet","status":"active","version":"5.3"}]`
+)
+
+func TestWaitForPluginActive_NoWaitWhenReady(t *testing.T) {
+	seq := &sequenceCommander{outputs: []string{testActivePlugin}}
+	orig := cmdExec
+	cmdExec = seq
+	defer func() { cmdExec = orig }()
+
+	cfg := &WPConfig{PollInterval: time.Hour, PollMaxWait: time.Hour}
+	start := time.Now()
+	info, err := waitForPluginActive(context.Background(), cfg, "akismet", true)
+	assert.NoError(t, err)
+	assert.True(t, info.Active())
+	assert.Equal(t, 1, seq.calls)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestWaitForPluginActive_PollsUntilReady(t *testing.T) {
+	seq := &sequenceCommander{outputs: []string{"[]", testInactivePlugin, testActivePlugin}}
+	orig := cmdExec
+	cmdExec = seq
+	defer func() { cmdExec = orig }()
+
+	cfg := &WPConfig{PollInterval: time.Millisecond, PollMaxWait: time.Second}
+	info, err := waitForPluginActive(context.Background(), cfg, "akismet", true)
+	assert.NoError(t, err)
+	assert.True(t, info.Active())
+	assert.Equal(t, 3, seq.calls)
+}
+
+func TestWaitForPluginActive_MaxWait(t *testing.T) {
+	seq := &sequenceCommander{outputs: []string{testInactivePlugin}}
+	orig := cmdExec
+	cmdExec = seq
+	defer func() { cmdExec = orig }()
+
+	cfg := &WPConfig{PollInterval: time.Millisecond, PollMaxWait: 20 * time.Millisecond}
+	_, err := waitForPluginActive(context.Background(), cfg, "akismet", true)
+	assert.ErrorContains(t, err, "did not become active within 20ms")
+
+	seq = &sequenceCommander{outputs: []string{"[]"}}
+	cmdExec = seq
+	_, err = waitForPluginActive(context.Background(), cfg, "akismet", false)
+	assert.ErrorContains(t, err, "still not installed")
+}
+
+func TestWaitForPluginActive_ContextDone(t *testing.T) {
+	seq := &sequenceCommander{outputs: []string{testInactivePlugin}}
+	orig := cmdExec
+	cmdExec = seq
+	defer func() { cmdExec = orig }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := &WPConfig{PollInterval: time.Hour, PollMaxWait: 2 * time.Hour}
+	_, err := waitForPluginActive(ctx, cfg, "akismet", true)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, seq.calls)
+}
@@ -6,27 +6,62 @@ package provider
 import (
 	"context"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 var _ provider.Provider = &WordpressProvider{}
 var _ provider.ProviderWithFunctions = &WordpressProvider{}
 var _ provider.ProviderWithEphemeralResources = &WordpressProvider{}
+var _ provider.ProviderWithValidateConfig = &WordpressProvider{}
 
 type WordpressProvider struct {
 	version string
 }
 
 type WordpressProviderModel struct {
-	SSHTarget  types.String `tfsdk:"ssh_target"`
-	RemotePath types.String `tfsdk:"remote_path"`
-	AllowRoot  types.Bool   `tfsdk:"allow_root"`
+	Backend        types.String `tfsdk:"backend"`
+	Transport      types.String `tfsdk:"transport"`
+	SSHTarget      types.String `tfsdk:"ssh_target"`
+	RemotePath     types.String `tfsdk:"remote_path"`
+	AllowRoot      types.Bool   `tfsdk:"allow_root"`
+	URL            types.String `tfsdk:"url"`
+	User           types.String `tfsdk:"user"`
+	SkipPlugins    types.Bool   `tfsdk:"skip_plugins"`
+	SkipThemes     types.Bool   `tfsdk:"skip_themes"`
+	Context        types.String `tfsdk:"context"`
+	ExtraArgs      types.List   `tfsdk:"extra_args"`
+	Env            types.Map    `tfsdk:"env"`
+	PollInterval   types.String `tfsdk:"poll_interval"`
+	PollMaxWait    types.String `tfsdk:"poll_max_wait"`
+	CommandTimeout types.String `tfsdk:"command_timeout"`
+
+	RetryAttempts         types.Int64  `tfsdk:"retry_attempts"`
+	RetryDelay            types.String `tfsdk:"retry_delay"`
+	RetryMutatingCommands types.Bool   `tfsdk:"retry_mutating_commands"`
+
+	MaxParallelCommands       types.Int64 `tfsdk:"max_parallel_commands"`
+	SerializeMutatingCommands types.Bool  `tfsdk:"serialize_mutating_commands"`
+
+	AuditLogPath          types.String `tfsdk:"audit_log_path"`
+	AuditReadOnlyCommands types.Bool   `tfsdk:"audit_read_only_commands"`
+
+	SSH        *sshTransportModel        `tfsdk:"ssh"`
+	Docker     *dockerTransportModel     `tfsdk:"docker"`
+	Kubernetes *kubernetesTransportModel `tfsdk:"kubernetes"`
+	Local      *localTransportModel      `tfsdk:"local"`
+	REST       *restBackendModel         `tfsdk:"rest"`
 }
 
 func (p *WordpressProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -37,22 +72,162 @@ func (p *WordpressProvider) Metadata(ctx context.Context, req provider.MetadataR
 func (p *WordpressProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Attributes: map[string]schema.Attribute{
+			"backend": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "How resources manage WordPress. `wp-cli` (default) runs WP-CLI commands through the configured `transport`. " +
+					"`rest` calls the WordPress REST API configured in the `rest` block with an application password; it supports " +
+					"`wordpress_plugin`, `wordpress_option` and the plugin data sources, but not themes or users. " +
+					"Plugin `version`, `source` and an `update_policy` other than `pinned` are rejected during plan with it, and option `autoload` is always null.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(backendWPCLI, backendREST),
+				},
+			},
+			"transport": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "How WP-CLI commands reach the WordPress host. `wp-cli` (default) runs the local `wp` binary with `--ssh=<ssh_target>`. " +
+					"`ssh` runs `wp` on the host over a built-in SSH client configured in the `ssh` block, `docker` runs `wp` in the container " +
+					"configured in the `docker` block through the Docker Engine API, and `kubernetes` runs `wp` in a pod selected by the `kubernetes` block " +
+					"through the pod exec API. None of these need PHP or WP-CLI installed locally. `local` runs `wp` directly on the machine Terraform runs on, " +
+					"configured by the optional `local` block.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(transportWPCLI, transportSSH, transportDocker, transportKubernetes, transportLocal),
+				},
+			},
 			"ssh_target": schema.StringAttribute{
-				Required:            true,
-				MarkdownDescription: "The SSH target for remote WordPress execution. E.g., 'docker:container-name' or 'user@host'.",
+				Optional:            true,
+				MarkdownDescription: "The SSH target for remote WordPress execution. E.g., 'docker:container-name' or 'user@host'. Required when `transport` is `wp-cli`.",
 			},
 			"remote_path": schema.StringAttribute{
-				Required:            true,
-				MarkdownDescription: "The path to the WordPress installation on the remote system.",
+				Optional:            true,
+				MarkdownDescription: "The path to the WordPress installation on the remote system. Required when `transport` is `local`.",
 			},
 			"allow_root": schema.BoolAttribute{
 				Optional:            true,
 				MarkdownDescription: "Whether to add --allow-root to WP-CLI commands.",
 			},
+			"url": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "The URL of the site to manage, passed to WP-CLI as `--url`, e.g. a subsite of a multisite network. " +
+					"A resource's own `url` takes precedence. With the `rest` backend it selects the site the REST API is called on.",
+			},
+			"user": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "The WordPress user (login, email or ID) to run WP-CLI commands as, passed as `--user`. " +
+					"Needed by commands and plugins that check capabilities.",
+			},
+			"skip_plugins": schema.BoolAttribute{
+				Optional: true,
+				MarkdownDescription: "Whether to add `--skip-plugins`, so that WP-CLI does not load plugins. This keeps the provider usable " +
+					"when a plugin causes a fatal error. To skip only some plugins, add `--skip-plugins=<slugs>` to `extra_args` instead.",
+			},
+			"skip_themes": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to add `--skip-themes`, so that WP-CLI does not load the active theme.",
+			},
+			"context": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The context WP-CLI loads WordPress in, passed as `--context`: `cli` (WP-CLI's default), `admin`, `auto` or `frontend`.",
+				Validators: []validator.String{
+					stringvalidator.OneOf("cli", "admin", "auto", "frontend"),
+				},
+			},
+			"extra_args": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				MarkdownDescription: "Further WP-CLI global flags added to every command, e.g. `[\"--skip-packages\", \"--require=/opt/wp/fix.php\"]`. " +
+					"Each must start with `--`.",
+				Validators: []validator.List{
+					listvalidator.ValueStringsAre(stringvalidator.RegexMatches(globalFlagPattern, "must be a WP-CLI global flag such as --skip-packages")),
+				},
+			},
+			"env": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+				MarkdownDescription: "Environment variables to set for the `wp` process, e.g. `{ WP_CLI_CACHE_DIR = \"/tmp/wp-cli\" }`. " +
+					"They are set wherever `wp` runs: on the host, in the container or pod, or locally. With the `wp-cli` transport they " +
+					"reach the local `wp`, which does not pass them on over SSH. Values are kept off command lines: the `ssh` and `kubernetes` " +
+					"transports, and the `local` transport with `user` set, send them on the command's stdin, and the `docker` transport through " +
+					"the Engine API. They are still visible to anyone who can read the `wp` process's environment on that machine, such as root. " +
+					"Where a whole `NAME=value` pair appears in errors, logs or the audit log, its value is redacted. Values must not contain line breaks.",
+				Validators: []validator.Map{
+					mapvalidator.KeysAre(stringvalidator.RegexMatches(envNamePattern, "must be an environment variable name")),
+					mapvalidator.ValueStringsAre(stringvalidator.RegexMatches(singleLinePattern, "must not contain line breaks")),
+				},
+			},
+			"poll_interval": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "How often to re-check plugin status while waiting for a change to take effect, as a duration string (e.g., `500ms`). Defaults to `1s`.",
+				Validators:          []validator.String{durationValidator{}},
+			},
+			"poll_max_wait": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The longest time to wait for plugin status to reflect a change, as a duration string (e.g., `2m`). Defaults to `30s`. Resource `timeouts` still apply.",
+				Validators:          []validator.String{durationValidator{}},
+			},
+			"command_timeout": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The longest a single WP-CLI command may run before it is stopped, as a duration string (e.g., `5m`). Defaults to `10m`.",
+				Validators:          []validator.String{durationValidator{}},
+			},
+			"retry_attempts": schema.Int64Attribute{
+				Optional: true,
+				MarkdownDescription: "How many times to try a command that fails with a transient error: a reset connection, a failed SSH handshake, " +
+					"or WordPress reporting `Error establishing a database connection`. Defaults to `3`; `1` disables retries.",
+				Validators: []validator.Int64{int64validator.AtLeast(1)},
+			},
+			"retry_delay": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "How long to wait before retrying a command, as a duration string (e.g., `2s`). The delay doubles with each " +
+					"further attempt, up to `30s`. Defaults to `1s`.",
+				Validators: []validator.String{durationValidator{}},
+			},
+			"retry_mutating_commands": schema.BoolAttribute{
+				Optional: true,
+				MarkdownDescription: "Whether to also retry commands that change WordPress, such as `wp plugin install`. A transient failure " +
+					"can happen after the change was made, so only read-only commands are retried by default.",
+			},
+			"max_parallel_commands": schema.Int64Attribute{
+				Optional: true,
+				MarkdownDescription: "The most commands to run against the WordPress installation at once, across all resources and data " +
+					"sources of this provider. Defaults to no limit beyond Terraform's own `-parallelism`.",
+				Validators: []validator.Int64{int64validator.AtLeast(1)},
+			},
+			"serialize_mutating_commands": schema.BoolAttribute{
+				Optional: true,
+				MarkdownDescription: "Whether commands that change WordPress, such as `wp plugin install`, wait for each other. Concurrent " +
+					"installs and updates race on `wp-content/upgrade`, so this defaults to `true`. Provider configurations for the same host and " +
+					"`remote_path`, such as aliases for subsites of one multisite install, wait for each other too. Read-only commands always run in parallel.",
+			},
+			"audit_log_path": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "A file to append a JSON record to for every command run against WordPress, with its time, resource, " +
+					"command line (with passwords redacted), target, exit code, duration and class (`mutating` or `read-only`). " +
+					"Only mutating commands are recorded unless `audit_read_only_commands` is set.",
+			},
+			"audit_read_only_commands": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to also record read-only commands, such as `wp plugin list`, in the audit log. Defaults to `false`.",
+			},
+			"ssh":        sshTransportAttribute(),
+			"docker":     dockerTransportAttribute(),
+			"kubernetes": kubernetesTransportAttribute(),
+			"local":      localTransportAttribute(),
+			"rest":       restBackendAttribute(),
 		},
 	}
 }
 
+func (p *WordpressProvider) ValidateConfig(ctx context.Context, req provider.ValidateConfigRequest, resp *provider.ValidateConfigResponse) {
+	var data WordpressProviderModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	validateBackend(data, &resp.Diagnostics)
+}
+
 func (p *WordpressProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
 	var data WordpressProviderModel
 	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
@@ -61,11 +236,64 @@ func (p *WordpressProvider) Configure(ctx context.Context, req provider.Configur
 	}
 
 	cfg := &WPConfig{
-		SSHTarget:  defaultStringIfUnset(data.SSHTarget, ""),
-		RemotePath: defaultStringIfUnset(data.RemotePath, ""),
-		AllowRoot:  defaultBoolIfUnset(data.AllowRoot, false),
+		SSHTarget:             defaultStringIfUnset(data.SSHTarget, ""),
+		RemotePath:            defaultStringIfUnset(data.RemotePath, ""),
+		AllowRoot:             defaultBoolIfUnset(data.AllowRoot, false),
+		URL:                   defaultStringIfUnset(data.URL, ""),
+		User:                  defaultStringIfUnset(data.User, ""),
+		SkipPlugins:           defaultBoolIfUnset(data.SkipPlugins, false),
+		SkipThemes:            defaultBoolIfUnset(data.SkipThemes, false),
+		Context:               defaultStringIfUnset(data.Context, ""),
+		RetryMutatingCommands: defaultBoolIfUnset(data.RetryMutatingCommands, false),
+		inventory:             newPluginInventory(),
+		secrets:               providerSecrets(data),
+		variables:             wpEnv(data.Env),
+	}
+	if !data.RetryAttempts.IsNull() && !data.RetryAttempts.IsUnknown() {
+		cfg.RetryAttempts = int(data.RetryAttempts.ValueInt64())
+	}
+	if !data.ExtraArgs.IsNull() && !data.ExtraArgs.IsUnknown() {
+		resp.Diagnostics.Append(data.ExtraArgs.ElementsAs(ctx, &cfg.ExtraArgs, false)...)
+	}
+	var err error
+	if cfg.PollInterval, err = parseDurationIfSet(data.PollInterval); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("poll_interval"), "Invalid poll interval", err.Error())
+	}
+	if cfg.PollMaxWait, err = parseDurationIfSet(data.PollMaxWait); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("poll_max_wait"), "Invalid poll max wait", err.Error())
+	}
+	if cfg.CommandTimeout, err = parseDurationIfSet(data.CommandTimeout); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("command_timeout"), "Invalid command timeout", err.Error())
+	}
+	if cfg.RetryDelay, err = parseDurationIfSet(data.RetryDelay); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("retry_delay"), "Invalid retry delay", err.Error())
+	}
+	if auditPath := defaultStringIfUnset(data.AuditLogPath, ""); auditPath != "" {
+		if cfg.audit, err = newAuditLog(auditPath, defaultBoolIfUnset(data.AuditReadOnlyCommands, false)); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("audit_log_path"), "Invalid audit log path", err.Error())
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	cfg.Backend = newBackend(data, &resp.Diagnostics)
+	if cfg.Backend == nil {
+		cfg.Exec = newTransport(data, &resp.Diagnostics)
+		cfg.Transport = defaultStringIfUnset(data.Transport, transportWPCLI)
+	}
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
+	// Aliases for subsites of one multisite install share its wp-content,
+	// so their limiters are keyed by the installation rather than the site
+	maxParallel := 0
+	if !data.MaxParallelCommands.IsNull() && !data.MaxParallelCommands.IsUnknown() {
+		maxParallel = int(data.MaxParallelCommands.ValueInt64())
+	}
+	cfg.limiter = newCommandLimiter(maxParallel, defaultBoolIfUnset(data.SerializeMutatingCommands, true), cfg.target()+"\x00"+cfg.RemotePath)
+
 	resp.ResourceData = cfg
 	resp.DataSourceData = cfg
 }
@@ -73,11 +301,17 @@ func (p *WordpressProvider) Configure(ctx context.Context, req provider.Configur
 func (p *WordpressProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewPluginResource,
+		NewThemeResource,
+		NewOptionResource,
+		NewUserResource,
 	}
 }
 
 func (p *WordpressProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
-	return []func() datasource.DataSource{}
+	return []func() datasource.DataSource{
+		NewPluginDataSource,
+		NewPluginsDataSource,
+	}
 }
 
 func (p *WordpressProvider) Functions(ctx context.Context) []func() function.Function {
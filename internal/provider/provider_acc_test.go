@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -129,7 +130,7 @@ resource "wordpress_plugin" "example" {
   active = true
 }
 `, sshTarget()),
-				ExpectError: regexp.MustCompile(`The argument "name" is required`),
+				ExpectError: regexp.MustCompile(`Missing plugin name or source`),
 			},
 		},
 	})
@@ -161,3 +162,240 @@ func TestAccWordpressPlugin_deactivate_not_active(t *testing.T) {
 		},
 	})
 }
+
+func testThemeConfig(themeName string, active bool) string {
+	return fmt.Sprintf(`
+provider "wordpress" {
+  ssh_target  = "%s"
+  remote_path = "/var/www/html"
+  allow_root  = true
+}
+
+resource "wordpress_theme" "example" {
+  name   = "%s"
+  active = %t
+}
+`, sshTarget(), themeName, active)
+}
+
+func TestAccWordpressTheme_inactive(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: protoV6Factories(),
+		Steps: []resource.TestStep{
+			{
+				Config: testThemeConfig("twentytwentythree", false),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wordpress_theme.example", "name", "twentytwentythree"),
+					resource.TestCheckResourceAttr("wordpress_theme.example", "active", "false"),
+					resource.TestCheckResourceAttrSet("wordpress_theme.example", "version"),
+					resource.TestCheckResourceAttrSet("wordpress_theme.example", "title"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccWordpressTheme_invalid_theme(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: protoV6Factories(),
+		Steps: []resource.TestStep{
+			{
+				Config:      testThemeConfig("this-theme-does-not-exist", false),
+				ExpectError: regexp.MustCompile(`Failed to install theme`),
+			},
+		},
+	})
+}
+
+func TestAccWordpressOption_json(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: protoV6Factories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "wordpress" {
+  ssh_target  = "%s"
+  remote_path = "/var/www/html"
+  allow_root  = true
+}
+
+resource "wordpress_option" "blogname" {
+  name               = "blogname"
+  value              = "Terraform Test Site"
+  restore_on_destroy = true
+}
+
+resource "wordpress_option" "settings" {
+  name       = "tf_acc_settings"
+  value_json = jsonencode({ enabled = true, items = ["a", "b"] })
+  autoload   = false
+}
+`, sshTarget()),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wordpress_option.blogname", "value", "Terraform Test Site"),
+					resource.TestCheckResourceAttrSet("wordpress_option.blogname", "previous_value_json"),
+					resource.TestCheckResourceAttr("wordpress_option.settings", "autoload", "false"),
+					resource.TestCheckNoResourceAttr("wordpress_option.settings", "previous_value_json"),
+				),
+			},
+		},
+	})
+}
+
+func testUserConfig(email string, roles string) string {
+	return fmt.Sprintf(`
+provider "wordpress" {
+  ssh_target  = "%s"
+  remote_path = "/var/www/html"
+  allow_root  = true
+}
+
+resource "wordpress_user" "example" {
+  login               = "tf-acc-user"
+  email               = "%s"
+  roles               = %s
+  password_wo         = "correct-horse-battery-staple"
+  password_wo_version = 1
+  reassign_to         = 1
+}
+`, sshTarget(), email, roles)
+}
+
+func TestAccWordpressUser_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: protoV6Factories(),
+		Steps: []resource.TestStep{
+			{
+				Config: testUserConfig("tf-acc@example.com", `["editor"]`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("wordpress_user.example", "id"),
+					resource.TestCheckResourceAttr("wordpress_user.example", "roles.#", "1"),
+					resource.TestCheckTypeSetElemAttr("wordpress_user.example", "roles.*", "editor"),
+					resource.TestCheckNoResourceAttr("wordpress_user.example", "password_wo"),
+				),
+			},
+			{
+				Config: testUserConfig("tf-acc-changed@example.com", `["author", "editor"]`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wordpress_user.example", "email", "tf-acc-changed@example.com"),
+					resource.TestCheckResourceAttr("wordpress_user.example", "roles.#", "2"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccWordpressPluginDataSources(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: protoV6Factories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "wordpress" {
+  ssh_target  = "%s"
+  remote_path = "/var/www/html"
+  allow_root  = true
+}
+
+data "wordpress_plugins" "active" {
+  status = "active"
+}
+
+data "wordpress_plugin" "hello" {
+  name = "hello-dolly"
+}
+`, sshTarget()),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.wordpress_plugins.active", "plugins.#"),
+					resource.TestCheckResourceAttr("data.wordpress_plugin.hello", "name", "hello-dolly"),
+					resource.TestCheckResourceAttrSet("data.wordpress_plugin.hello", "version"),
+				),
+			},
+		},
+	})
+}
+
+func testPluginVersionConfig(version string) string {
+	return fmt.Sprintf(`
+provider "wordpress" {
+  ssh_target  = "%s"
+  remote_path = "/var/www/html"
+  allow_root  = true
+}
+
+resource "wordpress_plugin" "example" {
+  name    = "classic-editor"
+  version = "%s"
+  active  = false
+}
+`, sshTarget(), version)
+}
+
+func TestAccWordpressPlugin_version(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: protoV6Factories(),
+		Steps: []resource.TestStep{
+			{
+				Config: testPluginVersionConfig("1.6.5"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wordpress_plugin.example", "installed_version", "1.6.5"),
+				),
+			},
+			{
+				// Downgrade in place
+				Config: testPluginVersionConfig("1.6.3"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wordpress_plugin.example", "installed_version", "1.6.3"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccWordpressPlugin_import(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: protoV6Factories(),
+		Steps: []resource.TestStep{
+			{
+				Config: testConfig("hello-dolly", true),
+			},
+			{
+				ResourceName:                         "wordpress_plugin.example",
+				ImportState:                          true,
+				ImportStateId:                        "hello-dolly",
+				ImportStateVerify:                    true,
+				ImportStateVerifyIdentifierAttribute: "name",
+			},
+		},
+	})
+}
+
+func TestAccWordpressPlugin_dockerTransport(t *testing.T) {
+	container := strings.TrimPrefix(sshTarget(), "docker:")
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: protoV6Factories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "wordpress" {
+  transport   = "docker"
+  remote_path = "/var/www/html"
+  allow_root  = true
+
+  docker = {
+    container = "%s"
+  }
+}
+
+resource "wordpress_plugin" "example" {
+  name   = "hello-dolly"
+  active = true
+}
+`, container),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wordpress_plugin.example", "active", "true"),
+				),
+			},
+		},
+	})
+}
@@ -27,19 +27,27 @@ func TestWordpressProvider_Schema(t *testing.T) {
 	assert.Contains(t, resp.Schema.Attributes, "ssh_target")
 	assert.Contains(t, resp.Schema.Attributes, "remote_path")
 	assert.Contains(t, resp.Schema.Attributes, "allow_root")
+	assert.Contains(t, resp.Schema.Attributes, "poll_interval")
+	assert.Contains(t, resp.Schema.Attributes, "poll_max_wait")
+	assert.Contains(t, resp.Schema.Attributes, "command_timeout")
 }
 
 func TestWordpressProvider_Resources(t *testing.T) {
 	wp := &WordpressProvider{}
 	res := wp.Resources(context.Background())
-	assert.Len(t, res, 1)
-	assert.NotNil(t, res[0])
+	assert.Len(t, res, 4)
+	for _, r := range res {
+		assert.NotNil(t, r())
+	}
 }
 
 func TestWordpressProvider_DataSources(t *testing.T) {
 	wp := &WordpressProvider{}
 	ds := wp.DataSources(context.Background())
-	assert.Empty(t, ds)
+	assert.Len(t, ds, 2)
+	for _, d := range ds {
+		assert.NotNil(t, d())
+	}
 }
 
 func TestWordpressProvider_Functions(t *testing.T) {
@@ -0,0 +1,217 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// redactedValue replaces secrets in command lines, errors, logs and audit
+// records.
+const redactedValue = "***"
+
+// sensitiveFlags lists command line options whose values are secrets,
+// whether or not the provider knows the value.
+var sensitiveFlags = []string{"--user_pass=", "--password=", "--dbpass="}
+
+// sensitiveFlagValues matches sensitiveFlags and their values in free text,
+// such as a command line WP-CLI echoes in its usage message.
+var sensitiveFlagValues = regexp.MustCompile(`(` + strings.Join(quoteAll(sensitiveFlags), "|") + `)\S+`)
+
+func quoteAll(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = regexp.QuoteMeta(v)
+	}
+	return quoted
+}
+
+// secretsKey is the context key of the secret values commands may contain.
+type secretsKey struct{}
+
+// withSecrets returns ctx carrying values, in addition to the secrets it
+// already carries, so that commands run with it redact them.
+func withSecrets(ctx context.Context, values ...string) context.Context {
+	var secrets []string
+	for _, v := range values {
+		if v != "" {
+			secrets = append(secrets, v)
+		}
+	}
+	if len(secrets) == 0 {
+		return ctx
+	}
+	existing, _ := ctx.Value(secretsKey{}).([]string)
+	return context.WithValue(ctx, secretsKey{}, append(append([]string{}, existing...), secrets...))
+}
+
+// configSecrets returns the values set in config for the string attributes
+// its schema marks sensitive or write-only.
+func configSecrets(ctx context.Context, config tfsdk.Config) []string {
+	if config.Schema == nil {
+		return nil
+	}
+
+	var secrets []string
+	for name, attr := range config.Schema.GetAttributes() {
+		if !attr.IsSensitive() && !attr.IsWriteOnly() {
+			continue
+		}
+		if !attr.GetType().Equal(types.StringType) {
+			continue
+		}
+		var value types.String
+		if config.GetAttribute(ctx, path.Root(name), &value).HasError() {
+			continue
+		}
+		secrets = append(secrets, defaultStringIfUnset(value, ""))
+	}
+	return secrets
+}
+
+// providerSecrets returns the values of the sensitive settings in the
+// provider configuration. The env setting is redacted by variablePattern
+// instead.
+func providerSecrets(data WordpressProviderModel) []string {
+	values := []types.String{}
+	if data.REST != nil {
+		values = append(values, data.REST.ApplicationPassword)
+	}
+	if data.SSH != nil {
+		values = append(values, data.SSH.PrivateKey)
+		if data.SSH.JumpHost != nil {
+			values = append(values, data.SSH.JumpHost.PrivateKey)
+		}
+	}
+
+	var secrets []string
+	for _, v := range values {
+		if secret := defaultStringIfUnset(v, ""); secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+// variablePattern matches the NAME=value pair variable as a whole token,
+// capturing what precedes it and its NAME= prefix, so that a value such as
+// "1" is not redacted from inside --version=5.3.1.
+func variablePattern(variable string) *regexp.Regexp {
+	name, value, _ := strings.Cut(variable, "=")
+	return regexp.MustCompile(`(^|[\s"'])(` + regexp.QuoteMeta(name+"=") + `)` + regexp.QuoteMeta(value) + `($|[\s"'])`)
+}
+
+// redactor scrubs secrets from what the execution layer reports about a
+// command.
+type redactor struct {
+	// secrets are the known secret values, longest first so that a secret
+	// containing another is replaced whole.
+	secrets []string
+
+	// variables are the NAME=value pairs whose values are redacted where
+	// the whole pair appears.
+	variables []string
+}
+
+// newRedactor returns the redactor for commands run with ctx on behalf of
+// cfg, knowing the secrets of both.
+func newRedactor(ctx context.Context, cfg *WPConfig) redactor {
+	secrets, _ := ctx.Value(secretsKey{}).([]string)
+	secrets = append(append([]string{}, secrets...), cfg.secrets...)
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return redactor{secrets: secrets, variables: cfg.variables}
+}
+
+// args returns a copy of args with the values of sensitiveFlags and any
+// known secrets redacted.
+func (r redactor) args(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = r.text(arg)
+		for _, flag := range sensitiveFlags {
+			if strings.HasPrefix(arg, flag) {
+				redacted[i] = flag + redactedValue
+			}
+		}
+	}
+	return redacted
+}
+
+// text returns s with known secrets and the values of sensitiveFlags
+// redacted.
+func (r redactor) text(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+	}
+	for _, variable := range r.variables {
+		pattern := variablePattern(variable)
+		// Matches consume the separator after them, so adjacent pairs take
+		// another pass
+		for redacted := ""; redacted != s; {
+			redacted = s
+			s = pattern.ReplaceAllString(s, "${1}${2}"+redactedValue+"${3}")
+		}
+	}
+	return sensitiveFlagValues.ReplaceAllString(s, "${1}"+redactedValue)
+}
+
+// bytes is text for command output.
+func (r redactor) bytes(b []byte) []byte {
+	if len(b) == 0 {
+		return b
+	}
+	return []byte(r.text(string(b)))
+}
+
+// error returns err with its message redacted. The original error can still
+// be inspected with errors.Is and errors.As.
+func (r redactor) error(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := r.text(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+// mask returns ctx with known secrets masked in all log output.
+func (r redactor) mask(ctx context.Context) context.Context {
+	// Log masks replace whole matches, so variables are masked as whole
+	// NAME=value pairs rather than by their values alone
+	masked := append(append([]string{}, r.secrets...), r.variables...)
+	if len(masked) == 0 {
+		return ctx
+	}
+	ctx = tflog.MaskAllFieldValuesStrings(ctx, masked...)
+	ctx = tflog.MaskMessageStrings(ctx, masked...)
+	for _, subsystem := range []string{logTransport, logWPCLI, logParser} {
+		ctx = tflog.SubsystemMaskAllFieldValuesStrings(ctx, subsystem, masked...)
+		ctx = tflog.SubsystemMaskMessageStrings(ctx, subsystem, masked...)
+	}
+	return ctx
+}
+
+// redactedError is an error whose message had secrets removed.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,234 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor_Args(t *testing.T) {
+	r := redactor{secrets: []string{"s3cr3t-license"}}
+
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"--user_pass=hunter2", "--user_pass=***"},
+		{"--password=hunter2", "--password=***"},
+		{"--dbpass=hunter2", "--dbpass=***"},
+		{"s3cr3t-license", "***"},
+		{"--value=s3cr3t-license", "--value=***"},
+		{"plugin", "plugin"},
+		{"--user_pass", "--user_pass"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			assert.Equal(t, []string{tt.want}, r.args([]string{tt.arg}))
+		})
+	}
+}
+
+func TestRedactor_Text(t *testing.T) {
+	r := newRedactor(withSecrets(context.Background(), "abc", "abcdef", ""), &WPConfig{})
+
+	assert.Equal(t, "key *** and ***", r.text("key abcdef and abc"))
+	assert.Equal(t, "usage: wp user create bob --user_pass=*** --role=editor",
+		r.text("usage: wp user create bob --user_pass=hunter2 --role=editor"))
+	assert.Equal(t, "nothing to hide", r.text("nothing to hide"))
+}
+
+func TestRedactor_Error(t *testing.T) {
+	r := redactor{secrets: []string{"hunter2"}}
+	exitErr := &dockerExitError{ExitCode: 1}
+
+	assert.Same(t, exitErr, r.error(exitErr))
+	assert.Nil(t, r.error(nil))
+
+	wrapped := errors.Join(errors.New("bad password hunter2"), exitErr)
+	err := r.error(wrapped)
+	assert.Equal(t, "bad password ***\nexit status 1", err.Error())
+	assert.ErrorIs(t, err, exitErr)
+	assert.Equal(t, 1, exitCode(err))
+}
+
+func TestWithSecrets_Accumulates(t *testing.T) {
+	ctx := withSecrets(context.Background(), "one")
+	ctx2 := withSecrets(ctx, "two")
+
+	assert.ElementsMatch(t, []string{"one"}, newRedactor(ctx, &WPConfig{}).secrets)
+	assert.ElementsMatch(t, []string{"one", "two", "three"}, newRedactor(ctx2, &WPConfig{secrets: []string{"three"}}).secrets)
+	assert.Equal(t, ctx, withSecrets(ctx, ""))
+}
+
+// leakyCommander fails echoing its arguments and a secret everywhere.
+type leakyCommander struct {
+	secret string
+}
+
+func (c leakyCommander) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	stdout, stderr, err := c.Output(ctx, name, args...)
+	return append(stdout, stderr...), err
+}
+
+func (c leakyCommander) Output(_ context.Context, name string, args ...string) ([]byte, []byte, error) {
+	stdout := []byte("token=" + c.secret)
+	stderr := []byte("Error: invalid arguments: " + name + " " + strings.Join(args, " "))
+	err := errors.Join(errors.New("remote said "+c.secret), &dockerExitError{ExitCode: 1})
+	return stdout, stderr, err
+}
+
+func TestRunCommand_NoSecretSurvives(t *testing.T) {
+	const password = "correct-horse-battery-staple"
+	const apiKey = "sk_live_1234567890"
+
+	var logs bytes.Buffer
+	audit := newTestAuditLog(t, true)
+	cfg := &WPConfig{Exec: leakyCommander{secret: apiKey}, audit: audit, secrets: []string{apiKey}}
+	ctx := withSecrets(tflogtest.RootLogger(context.Background(), &logs), password)
+
+	err := runWP(ctx, cfg, "user", "create", "bob", "bob@example.com", "--user_pass="+password)
+	require.Error(t, err)
+	err2 := runWP(ctx, cfg, "option", "update", "license_key", password)
+	require.Error(t, err2)
+
+	var diags diag.Diagnostics
+	addCommandError(&diags, "Failed to create user", err)
+	addCommandError(&diags, "Failed to set option", err2)
+
+	var cmdErr *commandError
+	require.ErrorAs(t, err, &cmdErr)
+	assert.Equal(t, 1, cmdErr.ExitCode)
+
+	auditLog, readErr := os.ReadFile(audit.path)
+	require.NoError(t, readErr)
+
+	reports := map[string]string{
+		"error":         err.Error() + err2.Error(),
+		"stdout":        string(cmdErr.Stdout),
+		"stderr":        string(cmdErr.Stderr),
+		"diagnostics":   diags[0].Detail() + diags[1].Detail(),
+		"logs":          logs.String(),
+		"audit":         string(auditLog),
+		"audit command": cmdErr.Command,
+	}
+	for name, report := range reports {
+		assert.NotContains(t, report, password, name)
+		assert.NotContains(t, report, apiKey, name)
+	}
+	assert.Contains(t, err.Error(), "--user_pass=***")
+}
+
+func TestRunCommand_TimeoutRedactsCommand(t *testing.T) {
+	cfg := &WPConfig{Exec: blockingCommander{}, CommandTimeout: 10 * time.Millisecond}
+	ctx := withSecrets(context.Background(), "s3cr3t")
+
+	err := runWP(ctx, cfg, "option", "update", "license_key", "s3cr3t")
+	var timeout *commandTimeoutError
+	require.ErrorAs(t, err, &timeout)
+	assert.NotContains(t, err.Error(), "s3cr3t")
+}
+
+func TestConfigSecrets(t *testing.T) {
+	ctx := context.Background()
+	schemaResp := &resource.SchemaResponse{}
+	NewUserResource().Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := map[string]tftypes.Value{}
+	for name, typ := range objType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["login"] = tftypes.NewValue(tftypes.String, "bob")
+	values["email"] = tftypes.NewValue(tftypes.String, "bob@example.com")
+	values["password_wo"] = tftypes.NewValue(tftypes.String, "hunter2")
+	config := tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, values)}
+
+	assert.Equal(t, []string{"hunter2"}, configSecrets(ctx, config))
+	assert.Empty(t, configSecrets(ctx, tfsdk.Config{}))
+}
+
+func TestUserResource_CreateRedactsPassword(t *testing.T) {
+	ctx := context.Background()
+	r := &wordpressUserResource{config: &WPConfig{Exec: leakyCommander{secret: "hunter2"}}}
+
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := map[string]tftypes.Value{}
+	for name, typ := range objType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["login"] = tftypes.NewValue(tftypes.String, "bob")
+	values["email"] = tftypes.NewValue(tftypes.String, "bob@example.com")
+	values["password_wo"] = tftypes.NewValue(tftypes.String, "hunter2")
+	raw := tftypes.NewValue(objType, values)
+
+	req := resource.CreateRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: raw},
+		Plan:   tfsdk.Plan{Schema: schemaResp.Schema, Raw: raw},
+	}
+	resp := &resource.CreateResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, nil)},
+	}
+	r.Create(ctx, req, resp)
+
+	require.True(t, resp.Diagnostics.HasError())
+	for _, d := range resp.Diagnostics {
+		assert.NotContains(t, d.Detail(), "hunter2")
+	}
+}
+
+func TestProviderSecrets(t *testing.T) {
+	data := WordpressProviderModel{
+		REST: &restBackendModel{ApplicationPassword: types.StringValue("abcd efgh")},
+		SSH: &sshTransportModel{
+			PrivateKey: types.StringValue("-----BEGIN KEY-----"),
+			JumpHost:   &sshJumpHostModel{PrivateKey: types.StringNull()},
+		},
+	}
+	assert.Equal(t, []string{"abcd efgh", "-----BEGIN KEY-----"}, providerSecrets(data))
+	assert.Empty(t, providerSecrets(WordpressProviderModel{}))
+
+	// env values are redacted as NAME=value pairs instead
+	data.Env = types.MapValueMust(types.StringType, map[string]attr.Value{
+		"WP_DB_PASSWORD": types.StringValue("hunter2"),
+	})
+	assert.Equal(t, []string{"abcd efgh", "-----BEGIN KEY-----"}, providerSecrets(data))
+}
+
+func TestRedactor_Variables(t *testing.T) {
+	cfg := &WPConfig{variables: []string{"WP_CLI_STRICT_ARGS_MODE=1", "WP_DB_PASSWORD=hunter2"}}
+	r := newRedactor(context.Background(), cfg)
+
+	// A short, non-secret value is left alone outside its own pair
+	assert.Equal(t, []string{"wp", "plugin", "install", "akismet", "--version=5.3.1"},
+		r.args([]string{"wp", "plugin", "install", "akismet", "--version=5.3.1"}))
+	assert.Equal(t, "wp user delete 15 --reassign=1", r.text("wp user delete 15 --reassign=1"))
+	assert.Equal(t, "password hunter2x", r.text("password hunter2x"))
+
+	assert.Equal(t, []string{"env", "WP_CLI_STRICT_ARGS_MODE=***", "WP_DB_PASSWORD=***", "wp"},
+		r.args([]string{"env", "WP_CLI_STRICT_ARGS_MODE=1", "WP_DB_PASSWORD=hunter2", "wp"}))
+	assert.Equal(t, "env WP_CLI_STRICT_ARGS_MODE=*** WP_DB_PASSWORD=*** wp: not found",
+		r.text("env WP_CLI_STRICT_ARGS_MODE=1 WP_DB_PASSWORD=hunter2 wp: not found"))
+	assert.Equal(t, "'WP_DB_PASSWORD=***' 'WP_DB_PASSWORD=***'",
+		r.text("'WP_DB_PASSWORD=hunter2' 'WP_DB_PASSWORD=hunter2'"))
+	assert.Equal(t, "MY_WP_DB_PASSWORD=hunter2", r.text("MY_WP_DB_PASSWORD=hunter2"))
+}
@@ -0,0 +1,473 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.ResourceWithValidateConfig = &wordpressOptionResource{}
+var _ resource.ResourceWithModifyPlan = &wordpressOptionResource{}
+
+func NewOptionResource() resource.Resource {
+	return &wordpressOptionResource{}
+}
+
+type wordpressOptionResource struct {
+	config *WPConfig
+}
+
+type wordpressOptionModel struct {
+	Name              types.String `tfsdk:"name"`
+	Value             types.String `tfsdk:"value"`
+	ValueJSON         types.String `tfsdk:"value_json"`
+	Autoload          types.Bool   `tfsdk:"autoload"`
+	Sensitive         types.Bool   `tfsdk:"sensitive"`
+	RestoreOnDestroy  types.Bool   `tfsdk:"restore_on_destroy"`
+	PreviousValueJSON types.String `tfsdk:"previous_value_json"`
+}
+
+// errOptionNotFound is wrapped by Backend.GetOption when the option does not exist.
+var errOptionNotFound = errors.New("option not found")
+
+// errOptionAutoloadUnsupported is returned by Backend.GetOptionAutoload when
+// the backend cannot read the autoload flag.
+var errOptionAutoloadUnsupported = errors.New("option autoload flag not supported")
+
+// optionListEntry is a row of `wp option list --format=json` output.
+type optionListEntry struct {
+	Name     string `json:"option_name"`
+	Autoload string `json:"autoload"`
+}
+
+func (r *wordpressOptionResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_option"
+}
+
+func (r *wordpressOptionResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "The option name (e.g., 'blogname').",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthAtMost(191),
+					stringvalidator.RegexMatches(optionNamePattern, "must be an option name without whitespace or control characters, not starting with '-'"),
+				},
+			},
+			"value": schema.StringAttribute{
+				Optional:    true,
+				Description: "The option value as a plain string. Conflicts with value_json.",
+			},
+			"value_json": schema.StringAttribute{
+				Optional: true,
+				Description: "The option value as a JSON document, for array, object and serialized values. " +
+					"Use jsonencode() to build it. Conflicts with value.",
+			},
+			"autoload": schema.BoolAttribute{
+				Optional: true,
+				Computed: true,
+				Description: "Whether WordPress should autoload the option on every request. " +
+					"Not supported by the rest backend, where it is always null.",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"sensitive": schema.BoolAttribute{
+				Optional: true,
+				Description: "When true, the option value is treated as a secret, such as a license key, and redacted from " +
+					"command lines in errors, logs and the audit log. Set value from a sensitive variable to also hide it in plans.",
+			},
+			"restore_on_destroy": schema.BoolAttribute{
+				Optional: true,
+				Description: "When true, destroying the resource restores the value the option had before it was " +
+					"managed by Terraform instead of deleting the option. Recommended for core options such as 'blogname'.",
+			},
+			"previous_value_json": schema.StringAttribute{
+				Computed:    true,
+				Description: "The JSON encoded value the option had before Terraform managed it, or null if it did not exist.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *wordpressOptionResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data wordpressOptionModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Value.IsUnknown() || data.ValueJSON.IsUnknown() {
+		return
+	}
+
+	if data.Value.IsNull() == data.ValueJSON.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("value"), "Invalid option value",
+			"Exactly one of value or value_json must be set.")
+		return
+	}
+
+	if !data.ValueJSON.IsNull() && !json.Valid([]byte(data.ValueJSON.ValueString())) {
+		resp.Diagnostics.AddAttributeError(path.Root("value_json"), "Invalid option value",
+			"value_json must be a valid JSON document.")
+	}
+}
+
+// ModifyPlan rejects an autoload setting the backend cannot apply, so that
+// it fails during plan rather than part way through an apply.
+func (r *wordpressOptionResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.config == nil {
+		return
+	}
+	if _, ok := r.config.backend().(*restBackend); !ok {
+		return
+	}
+
+	var autoload types.Bool
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("autoload"), &autoload)...)
+	if resp.Diagnostics.HasError() || autoload.IsNull() {
+		return
+	}
+	resp.Diagnostics.AddAttributeError(path.Root("autoload"), "Unsupported option setting",
+		fmt.Sprintf("autoload cannot be used when backend is %q: the WordPress REST API does not expose the autoload flag.", backendREST))
+}
+
+func (r *wordpressOptionResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	cfg, ok := req.ProviderData.(*WPConfig)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected Provider Data Type", "Expected *WPConfig")
+		return
+	}
+	r.config = cfg
+}
+
+func (r *wordpressOptionResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	cfg := r.config
+
+	var plan wordpressOptionModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "wordpress_option", plan.Name.ValueString())
+	ctx = withSecrets(ctx, configSecrets(ctx, req.Config)...)
+	ctx = withSecrets(ctx, optionSecrets(plan)...)
+
+	name := plan.Name.ValueString()
+
+	// Remember the current value so it can be restored on destroy. Only an
+	// option that does not exist yet has no previous value; guessing on any
+	// other error would make destroy delete an existing option
+	plan.PreviousValueJSON = types.StringNull()
+	previous, err := getOptionJSON(ctx, cfg, name)
+	switch {
+	case err == nil:
+		plan.PreviousValueJSON = types.StringValue(previous)
+	case !errors.Is(err, errOptionNotFound):
+		addCommandError(&resp.Diagnostics, "Failed to read option", err)
+		return
+	}
+
+	if err := cfg.backend().UpdateOption(ctx, cfg, plan); err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to set option", err)
+		return
+	}
+
+	if err := readOption(ctx, cfg, &plan); err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to read option after update", err)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *wordpressOptionResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	cfg := r.config
+
+	var state wordpressOptionModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "wordpress_option", state.Name.ValueString())
+	ctx = withSecrets(ctx, optionSecrets(state)...)
+
+	raw, err := getOptionJSON(ctx, cfg, state.Name.ValueString())
+	if errors.Is(err, errOptionNotFound) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to read option", err)
+		return
+	}
+	applyOptionValue(&state, raw)
+
+	autoload, err := getOptionAutoload(ctx, cfg, state.Name.ValueString())
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to read option", err)
+		return
+	}
+	state.Autoload = autoload
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *wordpressOptionResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	cfg := r.config
+
+	var plan wordpressOptionModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "wordpress_option", plan.Name.ValueString())
+	ctx = withSecrets(ctx, configSecrets(ctx, req.Config)...)
+	ctx = withSecrets(ctx, optionSecrets(plan)...)
+
+	if err := cfg.backend().UpdateOption(ctx, cfg, plan); err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to set option", err)
+		return
+	}
+
+	if err := readOption(ctx, cfg, &plan); err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to read option after update", err)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *wordpressOptionResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	cfg := r.config
+
+	var state wordpressOptionModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "wordpress_option", state.Name.ValueString())
+	ctx = withSecrets(ctx, optionSecrets(state)...)
+
+	name := state.Name.ValueString()
+
+	if defaultBoolIfUnset(state.RestoreOnDestroy, false) && !state.PreviousValueJSON.IsNull() {
+		previous := wordpressOptionModel{
+			Name:      state.Name,
+			Value:     types.StringNull(),
+			ValueJSON: state.PreviousValueJSON,
+			Autoload:  types.BoolNull(),
+		}
+		if err := cfg.backend().UpdateOption(ctx, cfg, previous); err != nil {
+			addCommandError(&resp.Diagnostics, "Failed to restore option", err)
+		}
+		return
+	}
+
+	if err := cfg.backend().DeleteOption(ctx, cfg, name); err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to delete option", err)
+		return
+	}
+}
+
+// optionSecrets returns the values of an option marked sensitive, as they
+// appear on WP-CLI command lines and in its output.
+func optionSecrets(m wordpressOptionModel) []string {
+	if !defaultBoolIfUnset(m.Sensitive, false) {
+		return nil
+	}
+	var secrets []string
+	for _, v := range []types.String{m.Value, m.ValueJSON, m.PreviousValueJSON} {
+		value := defaultStringIfUnset(v, "")
+		if value == "" {
+			continue
+		}
+		secrets = append(secrets, value, compactJSON(value))
+		// A JSON string also appears decoded, e.g. in WP-CLI errors
+		var decoded string
+		if json.Unmarshal([]byte(value), &decoded) == nil {
+			secrets = append(secrets, decoded)
+		}
+	}
+	return secrets
+}
+
+// optionValue returns the planned value as passed to `wp option update`.
+func optionValue(m wordpressOptionModel) string {
+	if !m.ValueJSON.IsNull() && !m.ValueJSON.IsUnknown() {
+		return m.ValueJSON.ValueString()
+	}
+	return m.Value.ValueString()
+}
+
+// optionUpdateArgs builds the `wp option update` command for the planned
+// value. The value is left out when it is sent on stdin instead.
+func optionUpdateArgs(m wordpressOptionModel, valueOnStdin bool) []string {
+	args := []string{"option", "update", m.Name.ValueString()}
+	if !valueOnStdin {
+		args = append(args, optionValue(m))
+	}
+	if !m.ValueJSON.IsNull() && !m.ValueJSON.IsUnknown() {
+		args = append(args, "--format=json")
+	}
+
+	if !m.Autoload.IsNull() && !m.Autoload.IsUnknown() {
+		if m.Autoload.ValueBool() {
+			args = append(args, "--autoload=yes")
+		} else {
+			args = append(args, "--autoload=no")
+		}
+	}
+	return args
+}
+
+// getOptionJSON returns the JSON encoded value of an option.
+func getOptionJSON(ctx context.Context, cfg *WPConfig, name string) (string, error) {
+	return cfg.backend().GetOption(ctx, cfg, name)
+}
+
+// readOption refreshes the value and autoload flag of the option in the model.
+func readOption(ctx context.Context, cfg *WPConfig, m *wordpressOptionModel) error {
+	name := m.Name.ValueString()
+
+	raw, err := getOptionJSON(ctx, cfg, name)
+	if err != nil {
+		return err
+	}
+	applyOptionValue(m, raw)
+
+	autoload, err := getOptionAutoload(ctx, cfg, name)
+	if err != nil {
+		return err
+	}
+	m.Autoload = autoload
+	return nil
+}
+
+// getOptionAutoload returns whether WordPress autoloads the option, or null
+// when the backend cannot tell.
+func getOptionAutoload(ctx context.Context, cfg *WPConfig, name string) (types.Bool, error) {
+	autoload, err := cfg.backend().GetOptionAutoload(ctx, cfg, name)
+	if errors.Is(err, errOptionAutoloadUnsupported) {
+		return types.BoolNull(), nil
+	}
+	if err != nil {
+		return types.BoolNull(), err
+	}
+	return types.BoolValue(autoload), nil
+}
+
+// applyOptionValue stores the observed JSON value in whichever attribute the
+// configuration uses, keeping the configured text when it is semantically equal.
+func applyOptionValue(m *wordpressOptionModel, raw string) {
+	if !m.ValueJSON.IsNull() {
+		if !optionJSONEqual(m.ValueJSON.ValueString(), raw) {
+			m.ValueJSON = types.StringValue(compactJSON(raw))
+		}
+		return
+	}
+
+	var s string
+	if err := json.Unmarshal([]byte(raw), &s); err == nil {
+		m.Value = types.StringValue(s)
+		return
+	}
+	m.Value = types.StringValue(compactJSON(raw))
+}
+
+// parseOptionAutoload finds the autoload flag for name in `wp option list` output.
+func parseOptionAutoload(output, name string) (bool, error) {
+	var entries []optionListEntry
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &entries); err != nil {
+		return false, fmt.Errorf("unable to parse option list: %v\nOutput: %s", err, output)
+	}
+	for _, e := range entries {
+		if e.Name == name {
+			// WordPress 6.6 introduced on/off/auto values next to the legacy yes/no.
+			switch e.Autoload {
+			case "yes", "on", "auto", "auto-on":
+				return true, nil
+			default:
+				return false, nil
+			}
+		}
+	}
+	return false, fmt.Errorf("option %s not found in option list", name)
+}
+
+// optionJSONEqual compares two JSON documents semantically. WordPress stores
+// top-level scalars as strings, so 10, "10" and true, "1" are treated as equal.
+func optionJSONEqual(a, b string) bool {
+	var va, vb interface{}
+	if err := json.Unmarshal([]byte(a), &va); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(b), &vb); err != nil {
+		return false
+	}
+	if sa, ok := phpScalarString(va); ok {
+		if sb, ok := phpScalarString(vb); ok {
+			return sa == sb
+		}
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// phpScalarString mirrors how PHP casts a scalar to string when it is stored.
+func phpScalarString(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	case bool:
+		if t {
+			return "1", true
+		}
+		return "", true
+	case nil:
+		return "", true
+	}
+	return "", false
+}
+
+// compactJSON strips insignificant whitespace, returning the input unchanged if it is not JSON.
+func compactJSON(raw string) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(raw)); err != nil {
+		return raw
+	}
+	return buf.String()
+}
@@ -0,0 +1,268 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordpressOptionResource_Metadata(t *testing.T) {
+	res := &wordpressOptionResource{}
+	resp := &resource.MetadataResponse{}
+	res.Metadata(context.Background(), resource.MetadataRequest{ProviderTypeName: "wordpress"}, resp)
+	assert.Equal(t, "wordpress_option", resp.TypeName)
+}
+
+func TestWordpressOptionResource_Schema(t *testing.T) {
+	res := &wordpressOptionResource{}
+	resp := &resource.SchemaResponse{}
+	res.Schema(context.Background(), resource.SchemaRequest{}, resp)
+
+	for _, attr := range []string{"name", "value", "value_json", "autoload", "restore_on_destroy", "previous_value_json"} {
+		assert.Contains(t, resp.Schema.Attributes, attr)
+	}
+	assert.True(t, resp.Schema.Attributes["name"].IsRequired())
+	assert.True(t, resp.Schema.Attributes["previous_value_json"].IsComputed())
+}
+
+func TestOptionUpdateArgs(t *testing.T) {
+	m := wordpressOptionModel{
+		Name:      types.StringValue("blogname"),
+		Value:     types.StringValue("My Site"),
+		ValueJSON: types.StringNull(),
+		Autoload:  types.BoolUnknown(),
+	}
+	assert.Equal(t, []string{"option", "update", "blogname", "My Site"}, optionUpdateArgs(m, false))
+	assert.Equal(t, []string{"option", "update", "blogname"}, optionUpdateArgs(m, true))
+	assert.Equal(t, "My Site", optionValue(m))
+
+	m = wordpressOptionModel{
+		Name:      types.StringValue("my_settings"),
+		Value:     types.StringNull(),
+		ValueJSON: types.StringValue(`{"enabled":true}`),
+		Autoload:  types.BoolValue(false),
+	}
+	assert.Equal(t, []string{"option", "update", "my_settings", `{"enabled":true}`, "--format=json", "--autoload=no"}, optionUpdateArgs(m, false))
+	assert.Equal(t, []string{"option", "update", "my_settings", "--format=json", "--autoload=no"}, optionUpdateArgs(m, true))
+	assert.Equal(t, `{"enabled":true}`, optionValue(m))
+}
+
+func TestApplyOptionValue_String(t *testing.T) {
+	m := wordpressOptionModel{Value: types.StringValue("old"), ValueJSON: types.StringNull()}
+	applyOptionValue(&m, `"WordPress Test Site"`)
+	assert.Equal(t, "WordPress Test Site", m.Value.ValueString())
+	assert.True(t, m.ValueJSON.IsNull())
+
+	// A structured value where a string was expected is surfaced as JSON text
+	applyOptionValue(&m, `{"a": 1}`)
+	assert.Equal(t, `{"a":1}`, m.Value.ValueString())
+}
+
+func TestApplyOptionValue_JSON(t *testing.T) {
+	configured := "{\n  \"b\": [1, 2],\n  \"a\": \"x\"\n}"
+	m := wordpressOptionModel{Value: types.StringNull(), ValueJSON: types.StringValue(configured)}
+
+	// Semantically equal values keep the configured formatting
+	applyOptionValue(&m, `{"a":"x","b":[1,2]}`)
+	assert.Equal(t, configured, m.ValueJSON.ValueString())
+
+	// Drift is reported using the remote value
+	applyOptionValue(&m, `{"a":"y","b":[1,2]}`)
+	assert.Equal(t, `{"a":"y","b":[1,2]}`, m.ValueJSON.ValueString())
+	assert.True(t, m.Value.IsNull())
+}
+
+func TestOptionJSONEqual(t *testing.T) {
+	assert.True(t, optionJSONEqual(`10`, `"10"`))
+	assert.True(t, optionJSONEqual(`true`, `"1"`))
+	assert.True(t, optionJSONEqual(`false`, `""`))
+	assert.True(t, optionJSONEqual(`[1, {"a": null}]`, `[1,{"a":null}]`))
+	assert.False(t, optionJSONEqual(`[1]`, `["1"]`))
+	assert.False(t, optionJSONEqual(`{"a":1}`, `{"a":2}`))
+	assert.False(t, optionJSONEqual(`not json`, `"x"`))
+}
+
+func TestParseOptionAutoload(t *testing.T) {
+	output := `[{"option_name":"blogname_extra","autoload":"no"},{"option_name":"blogname","autoload":"on"}]`
+	autoload, err := parseOptionAutoload(output, "blogname")
+	assert.NoError(t, err)
+	assert.True(t, autoload)
+
+	autoload, err = parseOptionAutoload(output, "blogname_extra")
+	assert.NoError(t, err)
+	assert.False(t, autoload)
+
+	_, err = parseOptionAutoload(output, "missing")
+	assert.Error(t, err)
+
+	_, err = parseOptionAutoload("garbage", "blogname")
+	assert.Error(t, err)
+}
+
+func TestGetOptionJSON(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+
+	cmdExec = mockCommander{output: []byte("\"Hello\"\n")}
+	value, err := getOptionJSON(context.Background(), &WPConfig{}, "blogname")
+	assert.NoError(t, err)
+	assert.Equal(t, `"Hello"`, value)
+
+	cmdExec = mockCommander{output: []byte("Error: Could not get 'nope' option. Does it exist?"), err: assert.AnError}
+	_, err = getOptionJSON(context.Background(), &WPConfig{}, "nope")
+	assert.Error(t, err)
+}
+
+// optionBackend serves GetOption from a fixed result and records updates.
+type optionBackend struct {
+	Backend
+	value   string
+	err     error
+	updates []string
+}
+
+func (b *optionBackend) GetOption(context.Context, *WPConfig, string) (string, error) {
+	return b.value, b.err
+}
+
+func (b *optionBackend) UpdateOption(_ context.Context, _ *WPConfig, m wordpressOptionModel) error {
+	b.updates = append(b.updates, m.Name.ValueString())
+	return nil
+}
+
+func TestOptionResource_CreateFailsWhenPreviousValueUnreadable(t *testing.T) {
+	ctx := context.Background()
+	backend := &optionBackend{err: errors.New("Error establishing a database connection")}
+	r := &wordpressOptionResource{config: &WPConfig{Backend: backend}}
+
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := map[string]tftypes.Value{}
+	for name, typ := range objType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["name"] = tftypes.NewValue(tftypes.String, "blogname")
+	values["value"] = tftypes.NewValue(tftypes.String, "My Site")
+	values["restore_on_destroy"] = tftypes.NewValue(tftypes.Bool, true)
+	raw := tftypes.NewValue(objType, values)
+
+	resp := &resource.CreateResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, nil)},
+	}
+	r.Create(ctx, resource.CreateRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: raw},
+		Plan:   tfsdk.Plan{Schema: schemaResp.Schema, Raw: raw},
+	}, resp)
+
+	require.True(t, resp.Diagnostics.HasError())
+	assert.Equal(t, "Failed to read option", resp.Diagnostics[0].Summary())
+	assert.Empty(t, backend.updates, "the option must not be changed without knowing its previous value")
+}
+
+func TestOptionResource_ModifyPlanREST(t *testing.T) {
+	ctx := context.Background()
+	r := &wordpressOptionResource{}
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	plan := func(cfg *WPConfig, autoload interface{}) *resource.ModifyPlanResponse {
+		values := map[string]tftypes.Value{}
+		for name, typ := range objType.AttributeTypes {
+			values[name] = tftypes.NewValue(typ, nil)
+		}
+		values["name"] = tftypes.NewValue(tftypes.String, "blogname")
+		values["value"] = tftypes.NewValue(tftypes.String, "My Site")
+		values["autoload"] = tftypes.NewValue(tftypes.Bool, autoload)
+		raw := tftypes.NewValue(objType, values)
+
+		r.config = cfg
+		resp := &resource.ModifyPlanResponse{Plan: tfsdk.Plan{Schema: schemaResp.Schema, Raw: raw}}
+		r.ModifyPlan(ctx, resource.ModifyPlanRequest{
+			Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: raw},
+			Plan:   tfsdk.Plan{Schema: schemaResp.Schema, Raw: raw},
+			State:  tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, nil)},
+		}, resp)
+		return resp
+	}
+
+	rest := &WPConfig{Backend: &restBackend{}}
+	assert.False(t, plan(rest, nil).Diagnostics.HasError())
+	assert.False(t, plan(&WPConfig{}, false).Diagnostics.HasError())
+
+	resp := plan(rest, false)
+	require.True(t, resp.Diagnostics.HasError())
+	assert.Equal(t, "Unsupported option setting", resp.Diagnostics.Errors()[0].Summary())
+	assert.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "does not expose the autoload flag")
+}
+
+func TestOptionSecrets(t *testing.T) {
+	m := wordpressOptionModel{
+		Value:             types.StringNull(),
+		ValueJSON:         types.StringValue(`{ "key": "ABC-123" }`),
+		PreviousValueJSON: types.StringValue(`"OLD-KEY"`),
+	}
+	assert.Empty(t, optionSecrets(m))
+
+	m.Sensitive = types.BoolValue(true)
+	secrets := optionSecrets(m)
+	assert.Contains(t, secrets, `{ "key": "ABC-123" }`)
+	assert.Contains(t, secrets, `{"key":"ABC-123"}`)
+	assert.Contains(t, secrets, `"OLD-KEY"`)
+	assert.Contains(t, secrets, "OLD-KEY")
+}
+
+func TestOptionResource_CreateRedactsSensitiveValue(t *testing.T) {
+	ctx := context.Background()
+	audit := newTestAuditLog(t, true)
+	commander := &sequenceCommander{outputs: []string{
+		`"OLD-KEY-456"`,
+		"",
+		`"NEW-KEY-123"`,
+		`[{"option_name":"license_key","autoload":"on"}]`,
+	}}
+	// WP-CLI's --ssh may drop stdin, so the value is on the command line
+	r := &wordpressOptionResource{config: &WPConfig{Exec: commander, SSHTarget: "docker:wordpress", audit: audit}}
+
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := map[string]tftypes.Value{}
+	for name, typ := range objType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["name"] = tftypes.NewValue(tftypes.String, "license_key")
+	values["value"] = tftypes.NewValue(tftypes.String, "NEW-KEY-123")
+	values["sensitive"] = tftypes.NewValue(tftypes.Bool, true)
+	raw := tftypes.NewValue(objType, values)
+
+	resp := &resource.CreateResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, nil)},
+	}
+	r.Create(ctx, resource.CreateRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: raw},
+		Plan:   tfsdk.Plan{Schema: schemaResp.Schema, Raw: raw},
+	}, resp)
+	require.False(t, resp.Diagnostics.HasError(), "%v", resp.Diagnostics)
+
+	records := readAuditLog(t, audit.path)
+	require.Len(t, records, 4)
+	assert.Equal(t, []string{"wp", "--ssh=docker:wordpress", "option", "update", "license_key", "***"}, records[1].Argv)
+	for _, rec := range records {
+		assert.NotContains(t, strings.Join(rec.Argv, " "), "NEW-KEY-123")
+	}
+}
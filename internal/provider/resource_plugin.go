@@ -5,13 +5,45 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/identityschema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+var _ resource.ResourceWithModifyPlan = &wordpressPluginResource{}
+var _ resource.ResourceWithValidateConfig = &wordpressPluginResource{}
+var _ resource.ResourceWithImportState = &wordpressPluginResource{}
+var _ resource.ResourceWithIdentity = &wordpressPluginResource{}
+
+// sha256Pattern matches a hex encoded SHA-256 digest.
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// defaultPluginTimeout bounds plugin create and update operations unless
+// overridden in the resource's timeouts block.
+const defaultPluginTimeout = 10 * time.Minute
+
+// Plugin update policies.
+const (
+	updatePolicyPinned = "pinned"
+	updatePolicyMinor  = "minor"
+	updatePolicyLatest = "latest"
 )
 
 func NewPluginResource() resource.Resource {
@@ -23,30 +55,192 @@ type wordpressPluginResource struct {
 }
 
 type wordpressPluginModel struct {
-	Name   types.String `tfsdk:"name"`
-	Active types.Bool   `tfsdk:"active"`
+	Name             types.String   `tfsdk:"name"`
+	Active           types.Bool     `tfsdk:"active"`
+	Version          types.String   `tfsdk:"version"`
+	InstalledVersion types.String   `tfsdk:"installed_version"`
+	UpdateVersion    types.String   `tfsdk:"update_version"`
+	UpdatePolicy     types.String   `tfsdk:"update_policy"`
+	Source           types.String   `tfsdk:"source"`
+	Checksum         types.String   `tfsdk:"checksum"`
+	URL              types.String   `tfsdk:"url"`
+	Timeouts         timeouts.Value `tfsdk:"timeouts"`
+}
+
+type wordpressPluginIdentityModel struct {
+	Name types.String `tfsdk:"name"`
+	URL  types.String `tfsdk:"url"`
 }
 
 func (r *wordpressPluginResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_plugin"
 }
 
-func (r *wordpressPluginResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+func (r *wordpressPluginResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Attributes: map[string]schema.Attribute{
 			"name": schema.StringAttribute{
-				Required:    true,
-				Description: "The WP‑CLI plugin slug (e.g., 'akismet').",
+				Optional:    true,
+				Computed:    true,
+				Description: "The WP‑CLI plugin slug (e.g., 'akismet'). Required unless source is set, in which case it is derived from the archive.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplaceIfConfigured(),
+					stringplanmodifier.UseStateForUnknown(),
+				},
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(slugPattern, "must be a plugin slug of letters, digits, '.', '_' and '-', not starting with '-' or '.'"),
+				},
 			},
 			"active": schema.BoolAttribute{
 				Optional:    true,
 				Computed:    true,
 				Description: "Whether the plugin should be activated.",
 			},
+			"version": schema.StringAttribute{
+				Optional: true,
+				Description: "The plugin version to install. Changing it updates (or downgrades) the plugin in place. " +
+					"When unset, the latest version is installed and update_policy controls later updates.",
+			},
+			"installed_version": schema.StringAttribute{
+				Computed:    true,
+				Description: "The plugin version currently installed.",
+			},
+			"update_version": schema.StringAttribute{
+				Computed:    true,
+				Description: "The newer version available from wordpress.org, or empty if the plugin is up to date.",
+			},
+			"update_policy": schema.StringAttribute{
+				Optional: true,
+				Description: "How the plugin is kept up to date when version is unset: 'pinned' (default) never updates, " +
+					"'minor' updates within the installed major version and 'latest' always updates to the newest version.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(updatePolicyPinned, updatePolicyMinor, updatePolicyLatest),
+				},
+			},
+			"source": schema.StringAttribute{
+				Optional: true,
+				Description: "A URL or local path of a plugin zip archive to install instead of the wordpress.org plugin directory. " +
+					"Local files are uploaded to the WordPress host. Changing it reinstalls the plugin.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("version")),
+					stringvalidator.RegexMatches(notFlagPattern, "must be a URL or path, not starting with '-'"),
+				},
+			},
+			"checksum": schema.StringAttribute{
+				Optional:    true,
+				Description: "The expected SHA-256 checksum (hex encoded) of the source archive. Changing it reinstalls the plugin.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("source")),
+					stringvalidator.RegexMatches(sha256Pattern, "must be a hex encoded SHA-256 checksum"),
+				},
+			},
+			"url": schema.StringAttribute{
+				Optional: true,
+				Description: "The URL of the site to manage the plugin on in a multisite network, passed to WP‑CLI as --url. " +
+					"Changing it replaces the resource.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+			}),
+		},
+	}
+}
+
+func (r *wordpressPluginResource) IdentitySchema(_ context.Context, _ resource.IdentitySchemaRequest, resp *resource.IdentitySchemaResponse) {
+	resp.IdentitySchema = identityschema.Schema{
+		Attributes: map[string]identityschema.Attribute{
+			"name": identityschema.StringAttribute{
+				RequiredForImport: true,
+				Description:       "The WP‑CLI plugin slug.",
+			},
+			"url": identityschema.StringAttribute{
+				OptionalForImport: true,
+				Description:       "The URL of the site in a multisite network, if any.",
+			},
 		},
 	}
 }
 
+func (r *wordpressPluginResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data wordpressPluginModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Name.IsNull() && data.Source.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("name"), "Missing plugin name or source",
+			"Either name or source must be set on a wordpress_plugin resource.")
+	}
+
+	if data.Version.IsNull() || data.UpdatePolicy.IsNull() || data.UpdatePolicy.IsUnknown() {
+		return
+	}
+	if data.UpdatePolicy.ValueString() != updatePolicyPinned {
+		resp.Diagnostics.AddAttributeError(path.Root("update_policy"), "Conflicting plugin version settings",
+			fmt.Sprintf("update_policy %q cannot be combined with an explicit version. "+
+				"Remove version or set update_policy to %q.", data.UpdatePolicy.ValueString(), updatePolicyPinned))
+	}
+}
+
+// ModifyPlan plans an in-place update when the configured version differs from
+// the installed one, or when the update policy allows a newer version.
+func (r *wordpressPluginResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to do on destroy
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan wordpressPluginModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	validatePluginBackend(r.config, plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var state wordpressPluginModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	needsUpdate, err := pluginNeedsUpdate(plan, state, func() (string, error) {
+		cfg := r.config.forSite(plan.URL)
+		if cfg == nil {
+			return "", nil
+		}
+		return cfg.backend().MinorPluginUpdate(ctx, cfg, plan.Name.ValueString())
+	})
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to check for plugin updates", err)
+		return
+	}
+	if needsUpdate {
+		plan.InstalledVersion = types.StringUnknown()
+		plan.UpdateVersion = types.StringUnknown()
+	} else {
+		plan.InstalledVersion = state.InstalledVersion
+		plan.UpdateVersion = state.UpdateVersion
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
 func (r *wordpressPluginResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
 	if req.ProviderData == nil {
 		return
@@ -60,116 +254,143 @@ func (r *wordpressPluginResource) Configure(ctx context.Context, req resource.Co
 }
 
 func (r *wordpressPluginResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	cfg := r.config
-
 	var plan wordpressPluginModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// Build install command
-	args := []string{"plugin", "install", plan.Name.ValueString()}
-	if plan.Active.ValueBool() {
-		args = append(args, "--activate")
+	ctx = withAuditResource(ctx, "wordpress_plugin", plan.Name.ValueString())
+	ctx = withSecrets(ctx, configSecrets(ctx, req.Config)...)
+
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultPluginTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
 
-	fmt.Printf("DEBUG: Installing plugin %s, active=%t\n",
-		plan.Name.ValueString(), plan.Active.ValueBool())
+	cfg := r.config.forSite(plan.URL)
 
-	// Install the plugin
-	if err := runWP(cfg, args...); err != nil {
-		resp.Diagnostics.AddError("Failed to install plugin", err.Error())
-		return
+	install := pluginInstall{
+		Slug:     plan.Name.ValueString(),
+		Version:  defaultStringIfUnset(plan.Version, ""),
+		Activate: plan.Active.ValueBool(),
+	}
+	if source := defaultStringIfUnset(plan.Source, ""); source != "" {
+		archive, cleanup, err := preparePluginSource(ctx, cfg, &plan)
+		defer cleanup()
+		if err != nil {
+			addCommandError(&resp.Diagnostics, "Failed to prepare plugin source", err)
+			return
+		}
+		// The name is taken from the archive when it was not configured
+		install.Slug = plan.Name.ValueString()
+		install.Archive = archive
 	}
 
-	// Add a delay after installation
-	time.Sleep(3 * time.Second)
+	tflog.Debug(ctx, "Installing plugin", map[string]interface{}{
+		logFieldPlugin: plan.Name.ValueString(),
+		"active":       plan.Active.ValueBool(),
+	})
 
-	// Verify that the plugin was installed
-	if err := runWP(cfg, "plugin", "is-installed", plan.Name.ValueString()); err != nil {
-		resp.Diagnostics.AddError("Plugin not installed after install attempt",
-			fmt.Sprintf("Failed to verify plugin %s is installed: %v", plan.Name.ValueString(), err))
+	// Install the plugin
+	if err := cfg.backend().InstallPlugin(ctx, cfg, install); err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to install plugin", err)
 		return
 	}
 
-	// Query plugin status
-	output, err := runWPWithOutput(cfg, "plugin", "status", plan.Name.ValueString())
+	// Wait until the plugin is listed, and active if activation was requested
+	info, err := waitForPlugin(ctx, cfg, plan.Name.ValueString(), "installed", func(info *pluginInfo) bool {
+		return !plan.Active.ValueBool() || info.Active()
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Failed to verify plugin status",
-			fmt.Sprintf("Command failed: %v\nOutput: %s", err, output))
+		addCommandError(&resp.Diagnostics, "Failed to verify plugin status", err)
 		return
 	}
 
-	active := isPluginActive(output)
-	fmt.Printf("DEBUG: After installation, plugin %s active=%t\n",
-		plan.Name.ValueString(), active)
+	tflog.Debug(ctx, "Installed plugin", map[string]interface{}{
+		logFieldPlugin: plan.Name.ValueString(),
+		"active":       info.Active(),
+		"version":      info.Version,
+	})
 
 	// If we wanted it inactive but it's active, explicitly deactivate it
-	if !plan.Active.ValueBool() && active {
-		fmt.Printf("DEBUG: Plugin was activated by default, deactivating...\n")
-		if err := runWP(cfg, "plugin", "deactivate", plan.Name.ValueString()); err != nil {
-			resp.Diagnostics.AddError("Failed to deactivate plugin", err.Error())
+	if !plan.Active.ValueBool() && info.Active() {
+		tflog.Debug(ctx, "Plugin was activated on install, deactivating it", map[string]interface{}{
+			logFieldPlugin: plan.Name.ValueString(),
+		})
+		if err := cfg.backend().SetPluginActive(ctx, cfg, plan.Name.ValueString(), false); err != nil {
+			addCommandError(&resp.Diagnostics, "Failed to deactivate plugin", err)
 			return
 		}
 
-		// Wait for deactivation to take effect
-		time.Sleep(3 * time.Second)
-
-		output, err = runWPWithOutput(cfg, "plugin", "status", plan.Name.ValueString())
+		info, err = waitForPluginActive(ctx, cfg, plan.Name.ValueString(), false)
 		if err != nil {
-			resp.Diagnostics.AddError("Failed to verify plugin status after deactivation",
-				fmt.Sprintf("Command failed: %v\nOutput: %s", err, output))
+			addCommandError(&resp.Diagnostics, "Failed to verify plugin status after deactivation", err)
 			return
 		}
-		active = isPluginActive(output)
-		fmt.Printf("DEBUG: After explicit deactivation, plugin active=%t\n", active)
 	}
 
-	plan.Active = types.BoolValue(active)
+	applyPluginInfo(&plan, info)
 	resp.State.Set(ctx, &plan)
+	setPluginIdentity(ctx, plan, resp.Identity, &resp.Diagnostics)
 }
 
 func (r *wordpressPluginResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
-	cfg := r.config
-
 	var state wordpressPluginModel
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// Check if plugin is installed
-	if err := runWP(cfg, "plugin", "is-installed", state.Name.ValueString()); err != nil {
+	ctx = withAuditResource(ctx, "wordpress_plugin", state.Name.ValueString())
+
+	cfg := r.config.forSite(state.URL)
+
+	// Get plugin status, dropping the resource if it is no longer installed
+	info, err := getPlugin(ctx, cfg, state.Name.ValueString())
+	if errors.Is(err, errPluginNotFound) {
 		resp.State.RemoveResource(ctx)
 		return
 	}
-
-	// Get plugin status
-	output, err := runWPWithOutput(cfg, "plugin", "status", state.Name.ValueString())
 	if err != nil {
-		resp.Diagnostics.AddError("Failed to get plugin status",
-			fmt.Sprintf("Command failed: %v\nOutput: %s", err, output))
+		addCommandError(&resp.Diagnostics, "Failed to get plugin status", err)
 		return
 	}
 
-	active := isPluginActive(output)
-	fmt.Printf("DEBUG: Read operation - plugin %s active=%t\n",
-		state.Name.ValueString(), active)
+	tflog.Debug(ctx, "Read plugin", map[string]interface{}{
+		logFieldPlugin: state.Name.ValueString(),
+		"active":       info.Active(),
+		"version":      info.Version,
+	})
 
-	state.Active = types.BoolValue(active)
+	applyPluginInfo(&state, info)
 	resp.State.Set(ctx, &state)
+	setPluginIdentity(ctx, state, resp.Identity, &resp.Diagnostics)
 }
 
 func (r *wordpressPluginResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	cfg := r.config
-
 	var plan wordpressPluginModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	ctx = withAuditResource(ctx, "wordpress_plugin", plan.Name.ValueString())
+	ctx = withSecrets(ctx, configSecrets(ctx, req.Config)...)
+
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultPluginTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	cfg := r.config.forSite(plan.URL)
+
 	// Get current state for comparison
 	var state wordpressPluginModel
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
@@ -177,103 +398,281 @@ func (r *wordpressPluginResource) Update(ctx context.Context, req resource.Updat
 		return
 	}
 
+	// Move to the pinned version, or apply updates allowed by the policy
+	needsUpdate, err := pluginNeedsUpdate(plan, state, func() (string, error) {
+		return cfg.backend().MinorPluginUpdate(ctx, cfg, plan.Name.ValueString())
+	})
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to check for plugin updates", err)
+		return
+	}
+	if needsUpdate {
+		version := defaultStringIfUnset(plan.Version, "")
+		minor := defaultStringIfUnset(plan.UpdatePolicy, updatePolicyPinned) == updatePolicyMinor
+
+		tflog.Debug(ctx, "Updating plugin", map[string]interface{}{
+			logFieldPlugin:      plan.Name.ValueString(),
+			"installed_version": state.InstalledVersion.ValueString(),
+			"version":           version,
+			"minor":             minor,
+		})
+
+		if err := cfg.backend().UpdatePlugin(ctx, cfg, plan.Name.ValueString(), version, minor); err != nil {
+			addCommandError(&resp.Diagnostics, "Failed to update plugin version", err)
+			return
+		}
+	}
+
 	// Only take action if active state is changing
 	if !plan.Active.IsUnknown() && !plan.Active.IsNull() &&
 		plan.Active.ValueBool() != state.Active.ValueBool() {
 
-		fmt.Printf("DEBUG: Changing plugin %s active state from %t to %t\n",
-			plan.Name.ValueString(), state.Active.ValueBool(), plan.Active.ValueBool())
-
-		var err error
-		if plan.Active.ValueBool() {
-			err = runWP(cfg, "plugin", "activate", plan.Name.ValueString())
-			fmt.Printf("DEBUG: Activated plugin %s\n", plan.Name.ValueString())
-		} else {
-			err = runWP(cfg, "plugin", "deactivate", plan.Name.ValueString())
-			fmt.Printf("DEBUG: Deactivated plugin %s\n", plan.Name.ValueString())
-		}
+		tflog.Debug(ctx, "Changing plugin activation", map[string]interface{}{
+			logFieldPlugin: plan.Name.ValueString(),
+			"active":       plan.Active.ValueBool(),
+		})
 
+		err := cfg.backend().SetPluginActive(ctx, cfg, plan.Name.ValueString(), plan.Active.ValueBool())
 		if err != nil {
-			resp.Diagnostics.AddError("Failed to update plugin activation", err.Error())
+			addCommandError(&resp.Diagnostics, "Failed to update plugin activation", err)
 			return
 		}
-
-		// Add a delay to ensure WordPress has processed the change
-		fmt.Printf("DEBUG: Waiting 3 seconds for WordPress to process the change...\n")
-		time.Sleep(3 * time.Second)
 	}
 
-	// Allow time for the change to take effect
-	time.Sleep(3 * time.Second)
-
-	// Re-read status to reflect actual state
-	output, err := runWPWithOutput(cfg, "plugin", "status", plan.Name.ValueString())
+	// Re-read status to reflect actual state, waiting for a requested activation change
+	info, err := waitForPlugin(ctx, cfg, plan.Name.ValueString(), "updated", func(info *pluginInfo) bool {
+		return plan.Active.IsUnknown() || plan.Active.IsNull() || info.Active() == plan.Active.ValueBool()
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Failed to verify plugin status",
-			fmt.Sprintf("Command failed: %v\nOutput: %s", err, output))
+		addCommandError(&resp.Diagnostics, "Failed to verify plugin status", err)
 		return
 	}
 
-	active := isPluginActive(output)
-	fmt.Printf("DEBUG: After operation, plugin %s is active=%t\n",
-		plan.Name.ValueString(), active)
+	tflog.Debug(ctx, "Updated plugin", map[string]interface{}{
+		logFieldPlugin: plan.Name.ValueString(),
+		"active":       info.Active(),
+		"version":      info.Version,
+	})
 
-	plan.Active = types.BoolValue(active)
+	applyPluginInfo(&plan, info)
 	resp.State.Set(ctx, &plan)
+	setPluginIdentity(ctx, plan, resp.Identity, &resp.Diagnostics)
 }
 
 func (r *wordpressPluginResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
-	cfg := r.config
-
 	var state wordpressPluginModel
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	if err := runWP(cfg, "plugin", "delete", state.Name.ValueString()); err != nil {
-		resp.Diagnostics.AddError("Failed to delete plugin", err.Error())
+	ctx = withAuditResource(ctx, "wordpress_plugin", state.Name.ValueString())
+
+	cfg := r.config.forSite(state.URL)
+
+	if err := cfg.backend().DeletePlugin(ctx, cfg, state.Name.ValueString()); err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to delete plugin", err)
 		return
 	}
 }
 
-func isPluginActive(output string) bool {
-	fmt.Printf("DEBUG: Raw plugin status output:\n%s\n", output)
+// ImportState adopts an installed plugin. The import ID is the plugin slug, or
+// the site URL and slug separated by a slash for multisite installs, e.g.
+// "https://example.com/blog/akismet".
+func (r *wordpressPluginResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	var identity wordpressPluginIdentityModel
+	if req.ID != "" {
+		siteURL, slug, err := parsePluginImportID(req.ID)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid import ID",
+				fmt.Sprintf("Expected a plugin slug such as \"akismet\" or a site URL and slug such as "+
+					"\"https://example.com/blog/akismet\", got %q: %s.", req.ID, err))
+			return
+		}
+		identity.Name = types.StringValue(slug)
+		identity.URL = types.StringNull()
+		if siteURL != "" {
+			identity.URL = types.StringValue(siteURL)
+		}
+	} else {
+		resp.Diagnostics.Append(req.Identity.Get(ctx, &identity)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if !slugPattern.MatchString(identity.Name.ValueString()) {
+			resp.Diagnostics.AddAttributeError(path.Root("name"), "Invalid import identity",
+				fmt.Sprintf("Expected a plugin slug such as \"akismet\", got %q.", identity.Name.ValueString()))
+			return
+		}
+		if !identity.URL.IsNull() {
+			if err := checkSiteURL(identity.URL.ValueString()); err != nil {
+				resp.Diagnostics.AddAttributeError(path.Root("url"), "Invalid import identity", err.Error())
+				return
+			}
+		}
+	}
+
+	slug := identity.Name.ValueString()
 
-	// First check for explicit status line
-	for _, line := range strings.Split(output, "\n") {
-		trimLine := strings.TrimSpace(strings.ToLower(line))
-		if strings.HasPrefix(trimLine, "status:") {
-			status := strings.TrimSpace(strings.TrimPrefix(trimLine, "status:"))
-			fmt.Printf("DEBUG: Found status line: '%s', extracted status: '%s'\n", trimLine, status)
-			return status == "active"
+	if _, err := getPlugin(ctx, r.config.forSite(identity.URL), slug); err != nil {
+		if errors.Is(err, errPluginNotFound) {
+			resp.Diagnostics.AddError("Cannot import non-existent plugin",
+				fmt.Sprintf("Plugin %s is not installed on the site.", slug))
+		} else {
+			addCommandError(&resp.Diagnostics, "Failed to get plugin status", err)
 		}
+		return
 	}
 
-	// Next check for plugin name with status
-	for _, line := range strings.Split(output, "\n") {
-		line = strings.ToLower(strings.TrimSpace(line))
-		// Look for lines that might contain plugin info
-		if strings.Contains(line, "plugin") || strings.Contains(line, "akismet") ||
-			strings.Contains(line, "hello-dolly") {
-			fmt.Printf("DEBUG: Found potential plugin line: '%s'\n", line)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), identity.Name)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("url"), identity.URL)...)
+	if resp.Identity != nil {
+		resp.Diagnostics.Append(resp.Identity.Set(ctx, identity)...)
+	}
+}
 
-			// Check for inactive status
-			if strings.Contains(line, "inactive") {
-				return false
-			}
-			// Check for active status, but make sure it's not just part of another word
-			if (strings.Contains(line, " active") ||
-				strings.Contains(line, "(active)") ||
-				strings.Contains(line, "[active]") ||
-				strings.Contains(line, "active,") ||
-				strings.Contains(line, "active.")) &&
-				!strings.Contains(line, "inactive") {
-				return true
-			}
+// parsePluginImportID splits an import ID of the form <slug> or <url>/<slug>
+// into the site URL, empty for the former, and the plugin slug.
+func parsePluginImportID(id string) (siteURL, slug string, err error) {
+	id = strings.TrimSuffix(strings.TrimSpace(id), "/")
+	slug = id
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		siteURL, slug = id[:i], id[i+1:]
+		if err := checkSiteURL(siteURL); err != nil {
+			return "", "", err
+		}
+	}
+	if !slugPattern.MatchString(slug) {
+		return "", "", fmt.Errorf("%q is not a plugin slug", slug)
+	}
+	return siteURL, slug, nil
+}
+
+// checkSiteURL returns an error unless siteURL is an http:// or https://
+// URL with a host.
+func checkSiteURL(siteURL string) error {
+	u, err := url.Parse(siteURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("site URL %q must be an http:// or https:// URL", siteURL)
+	}
+	return nil
+}
+
+// setPluginIdentity stores the resource identity when the client supports it.
+func setPluginIdentity(ctx context.Context, m wordpressPluginModel, identity *tfsdk.ResourceIdentity, diags *diag.Diagnostics) {
+	if identity == nil {
+		return
+	}
+	diags.Append(identity.Set(ctx, wordpressPluginIdentityModel{Name: m.Name, URL: m.URL})...)
+}
+
+// validatePluginBackend reports the settings in m the backend of cfg cannot
+// apply, so that they fail during plan rather than part way through an apply.
+func validatePluginBackend(cfg *WPConfig, m wordpressPluginModel, diags *diag.Diagnostics) {
+	if cfg == nil {
+		return
+	}
+	if _, ok := cfg.backend().(*restBackend); !ok {
+		return
+	}
+
+	if !m.Source.IsNull() {
+		diags.AddAttributeError(path.Root("source"), "Unsupported plugin setting",
+			fmt.Sprintf("source cannot be used when backend is %q: the WordPress REST API cannot install plugins from an archive.", backendREST))
+	}
+	if !m.Version.IsNull() {
+		diags.AddAttributeError(path.Root("version"), "Unsupported plugin setting",
+			fmt.Sprintf("version cannot be used when backend is %q: the WordPress REST API always installs the latest version "+
+				"and cannot update plugins.", backendREST))
+	}
+	if policy := defaultStringIfUnset(m.UpdatePolicy, updatePolicyPinned); policy != updatePolicyPinned {
+		diags.AddAttributeError(path.Root("update_policy"), "Unsupported plugin setting",
+			fmt.Sprintf("update_policy %q cannot be used when backend is %q: the WordPress REST API cannot update plugins. "+
+				"Remove update_policy or set it to %q.", policy, backendREST, updatePolicyPinned))
+	}
+}
+
+// pluginNeedsUpdate reports whether the installed version must change to
+// satisfy the planned version or update policy. minorUpdate returns the
+// newest version within the installed major version; it is only called when
+// the newest version overall is a new major version.
+func pluginNeedsUpdate(plan, state wordpressPluginModel, minorUpdate func() (string, error)) (bool, error) {
+	installed := state.InstalledVersion.ValueString()
+
+	if !plan.Version.IsNull() {
+		return !plan.Version.IsUnknown() && plan.Version.ValueString() != installed, nil
+	}
+
+	available := state.UpdateVersion.ValueString()
+	if available == "" {
+		return false, nil
+	}
+
+	switch defaultStringIfUnset(plan.UpdatePolicy, updatePolicyPinned) {
+	case updatePolicyLatest:
+		return true, nil
+	case updatePolicyMinor:
+		if majorVersion(available) == majorVersion(installed) {
+			return true, nil
 		}
+		// A new major version hides any pending update within the installed one
+		version, err := minorUpdate()
+		return version != "", err
+	}
+	return false, nil
+}
+
+// majorVersion returns the leading component of a dotted version string.
+func majorVersion(version string) string {
+	major, _, _ := strings.Cut(version, ".")
+	return major
+}
+
+// applyPluginInfo copies the observed plugin details into the model.
+func applyPluginInfo(m *wordpressPluginModel, info *pluginInfo) {
+	m.Active = types.BoolValue(info.Active())
+	m.InstalledVersion = types.StringValue(info.Version)
+	if info.UpdateAvailable() {
+		m.UpdateVersion = types.StringValue(info.UpdateVersion)
+	} else {
+		m.UpdateVersion = types.StringValue("")
+	}
+}
+
+// preparePluginSource fetches and verifies the plugin archive referenced by
+// the plan, sets the plan's name to the slug found in the archive and uploads
+// the archive to the WordPress host. It returns the path WP-CLI should install
+// from and a cleanup function that is always safe to call.
+func preparePluginSource(ctx context.Context, cfg *WPConfig, plan *wordpressPluginModel) (string, func(), error) {
+	source := plan.Source.ValueString()
+
+	archive, cleanupLocal, err := fetchPluginArchive(ctx, source)
+	if err != nil {
+		return "", cleanupLocal, err
 	}
 
-	fmt.Printf("DEBUG: Could not determine plugin status from output, defaulting to inactive\n")
-	return false
+	if checksum := defaultStringIfUnset(plan.Checksum, ""); checksum != "" {
+		if err := verifyChecksum(archive, checksum); err != nil {
+			return "", cleanupLocal, fmt.Errorf("plugin archive %s: %w", source, err)
+		}
+	}
+
+	slug, err := pluginSlugFromArchive(archive)
+	if err != nil {
+		return "", cleanupLocal, err
+	}
+	if name := defaultStringIfUnset(plan.Name, ""); name != "" && name != slug {
+		return "", cleanupLocal, fmt.Errorf("plugin archive %s contains plugin %q, but name is set to %q", source, slug, name)
+	}
+	plan.Name = types.StringValue(slug)
+
+	remotePath, cleanupRemote, err := uploadFile(ctx, cfg, archive)
+	cleanup := func() {
+		cleanupRemote()
+		cleanupLocal()
+	}
+	if err != nil {
+		return "", cleanup, err
+	}
+	return remotePath, cleanup, nil
 }
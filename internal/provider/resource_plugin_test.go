@@ -29,6 +29,10 @@ func TestWordpressPluginResource_Schema(t *testing.T) {
 
 	assert.Contains(t, resp.Schema.Attributes, "name")
 	assert.Contains(t, resp.Schema.Attributes, "active")
+	assert.Contains(t, resp.Schema.Attributes, "version")
+	assert.Contains(t, resp.Schema.Attributes, "installed_version")
+	assert.Contains(t, resp.Schema.Attributes, "update_version")
+	assert.Contains(t, resp.Schema.Attributes, "update_policy")
 }
 
 func TestWordpressPluginResource_Configure(t *testing.T) {
@@ -64,22 +68,20 @@ func TestWordpressPluginResource_Configure(t *testing.T) {
 	assert.Contains(t, resp3.Diagnostics.Errors()[0].Summary(), "Unexpected Provider Data Type")
 }
 
-func TestIsPluginActive_StatusLine(t *testing.T) {
-	assert.True(t, isPluginActive("Status: active\n"))
-	assert.False(t, isPluginActive("Status: inactive\n"))
-	assert.True(t, isPluginActive("status:    active  \nother stuff"))
-	assert.False(t, isPluginActive("status:   inactive\nother stuff"))
+func TestPluginInfo_ActiveFromStatus(t *testing.T) {
+	// Any slug is classified from the structured status, not from its name
+	for _, slug := range []string{"akismet", "hello-dolly", "classic-editor", "woocommerce"} {
+		assert.True(t, pluginInfo{Name: slug, Status: "active"}.Active(), slug)
+		assert.False(t, pluginInfo{Name: slug, Status: "inactive"}.Active(), slug)
+	}
 }
 
-func TestIsPluginActive_NameWithStatus(t *testing.T) {
-	assert.True(t, isPluginActive("hello-dolly (active)\n"))
-	assert.True(t, isPluginActive("akismet [active]\n"))
-	assert.False(t, isPluginActive("hello-dolly (inactive)\n"))
-	assert.False(t, isPluginActive("akismet inactive\n"))
-	assert.True(t, isPluginActive("akismet active.\n"))
-	assert.True(t, isPluginActive("plugin foobar active,\n"))
-}
+func TestGetPlugin_UnparseableOutput(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
 
-func TestIsPluginActive_Unrecognized(t *testing.T) {
-	assert.False(t, isPluginActive("plugin foobar is something\n"))
+	cmdExec = mockCommander{output: []byte("Plugin classic-editor details:\n    Status: Active\n")}
+	_, err := getPlugin(context.Background(), &WPConfig{}, "classic-editor")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, errPluginNotFound)
 }
@@ -0,0 +1,259 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Unit tests for wordpress_plugin that need no WordPress environment.
+// resource_plugin_test.go is only built with the acceptance tag.
+
+func TestPluginNeedsUpdate(t *testing.T) {
+	state := wordpressPluginModel{
+		InstalledVersion: types.StringValue("5.3.1"),
+		UpdateVersion:    types.StringValue("5.4"),
+	}
+	majorState := wordpressPluginModel{
+		InstalledVersion: types.StringValue("5.3.1"),
+		UpdateVersion:    types.StringValue("6.0"),
+	}
+	upToDate := wordpressPluginModel{
+		InstalledVersion: types.StringValue("5.3.1"),
+		UpdateVersion:    types.StringValue(""),
+	}
+	minor := wordpressPluginModel{UpdatePolicy: types.StringValue("minor")}
+
+	cases := []struct {
+		name        string
+		plan        wordpressPluginModel
+		state       wordpressPluginModel
+		minorUpdate string
+		updates     bool
+	}{
+		{"pinned by default", wordpressPluginModel{}, state, "", false},
+		{"explicit pinned", wordpressPluginModel{UpdatePolicy: types.StringValue("pinned")}, state, "", false},
+		{"latest", wordpressPluginModel{UpdatePolicy: types.StringValue("latest")}, majorState, "", true},
+		{"latest up to date", wordpressPluginModel{UpdatePolicy: types.StringValue("latest")}, upToDate, "", false},
+		{"minor within major", minor, state, "", true},
+		{"minor across major with pending minor", minor, majorState, "5.4.2", true},
+		{"minor across major only", minor, majorState, "", false},
+		{"minor up to date", minor, upToDate, "", false},
+		{"version matches", wordpressPluginModel{Version: types.StringValue("5.3.1")}, state, "", false},
+		{"version upgrade", wordpressPluginModel{Version: types.StringValue("5.4")}, upToDate, "", true},
+		{"version downgrade", wordpressPluginModel{Version: types.StringValue("4.0")}, upToDate, "", true},
+		{"version unknown", wordpressPluginModel{Version: types.StringUnknown()}, state, "", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			updates, err := pluginNeedsUpdate(tc.plan, tc.state, func() (string, error) {
+				calls++
+				return tc.minorUpdate, nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.updates, updates)
+			// The minor version is only looked up when a new major version hides it
+			if tc.state.UpdateVersion.ValueString() != "6.0" || tc.plan.UpdatePolicy.ValueString() != "minor" {
+				assert.Zero(t, calls)
+			}
+		})
+	}
+
+	_, err := pluginNeedsUpdate(minor, majorState, func() (string, error) { return "", assert.AnError })
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestMajorVersion(t *testing.T) {
+	assert.Equal(t, "5", majorVersion("5.3.1"))
+	assert.Equal(t, "10", majorVersion("10"))
+	assert.Equal(t, "", majorVersion(""))
+}
+
+func TestApplyPluginInfo(t *testing.T) {
+	var m wordpressPluginModel
+	applyPluginInfo(&m, &pluginInfo{Status: "active", Version: "1.0", Update: "available", UpdateVersion: "1.1"})
+	assert.True(t, m.Active.ValueBool())
+	assert.Equal(t, "1.0", m.InstalledVersion.ValueString())
+	assert.Equal(t, "1.1", m.UpdateVersion.ValueString())
+
+	applyPluginInfo(&m, &pluginInfo{Status: "inactive", Version: "1.1", Update: "none", UpdateVersion: "1.1"})
+	assert.False(t, m.Active.ValueBool())
+	assert.Equal(t, "", m.UpdateVersion.ValueString())
+}
+
+func TestParsePluginImportID(t *testing.T) {
+	cases := []struct {
+		id   string
+		url  string
+		slug string
+	}{
+		{"akismet", "", "akismet"},
+		{"https://example.com/blog/akismet", "https://example.com/blog", "akismet"},
+		{"https://example.com/sub/akismet/", "https://example.com/sub", "akismet"},
+		{"http://localhost:8080/akismet", "http://localhost:8080", "akismet"},
+	}
+	for _, tc := range cases {
+		url, slug, err := parsePluginImportID(tc.id)
+		require.NoError(t, err, tc.id)
+		assert.Equal(t, tc.url, url, tc.id)
+		assert.Equal(t, tc.slug, slug, tc.id)
+	}
+
+	invalid := []struct {
+		id  string
+		err string
+	}{
+		{"", `"" is not a plugin slug`},
+		{"https://example.com/", `site URL "https:/" must be an http:// or https:// URL`},
+		{"https://example.com", `site URL "https:/" must be an http:// or https:// URL`},
+		{"example.com/akismet", `site URL "example.com" must be an http:// or https:// URL`},
+		{"https://example.com//", `"" is not a plugin slug`},
+		{"https://example.com/--activate", `"--activate" is not a plugin slug`},
+	}
+	for _, tc := range invalid {
+		_, _, err := parsePluginImportID(tc.id)
+		assert.EqualError(t, err, tc.err, tc.id)
+	}
+}
+
+func TestWordpressPluginResource_IdentitySchema(t *testing.T) {
+	res := &wordpressPluginResource{}
+	resp := &resource.IdentitySchemaResponse{}
+	res.IdentitySchema(context.Background(), resource.IdentitySchemaRequest{}, resp)
+	assert.Contains(t, resp.IdentitySchema.Attributes, "name")
+	assert.Contains(t, resp.IdentitySchema.Attributes, "url")
+}
+
+// importPlugin runs ImportState with the given import ID.
+func importPlugin(t *testing.T, cfg *WPConfig, id string) *resource.ImportStateResponse {
+	t.Helper()
+	ctx := context.Background()
+	res := &wordpressPluginResource{config: cfg}
+
+	schemaResp := &resource.SchemaResponse{}
+	res.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	identityResp := &resource.IdentitySchemaResponse{}
+	res.IdentitySchema(ctx, resource.IdentitySchemaRequest{}, identityResp)
+
+	resp := &resource.ImportStateResponse{
+		State: tfsdk.State{
+			Schema: schemaResp.Schema,
+			Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+		},
+		Identity: &tfsdk.ResourceIdentity{
+			Schema: identityResp.IdentitySchema,
+			Raw:    tftypes.NewValue(identityResp.IdentitySchema.Type().TerraformType(ctx), nil),
+		},
+	}
+	res.ImportState(ctx, resource.ImportStateRequest{ID: id}, resp)
+	return resp
+}
+
+func TestWordpressPluginResource_ImportState(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+	cmdExec = mockCommander{output: []byte(testPluginListOutput)}
+
+	resp := importPlugin(t, &WPConfig{}, "https://example.com/shop/woocommerce")
+	assert.False(t, resp.Diagnostics.HasError(), "%v", resp.Diagnostics)
+
+	var name, url types.String
+	resp.State.GetAttribute(context.Background(), path.Root("name"), &name)
+	resp.State.GetAttribute(context.Background(), path.Root("url"), &url)
+	assert.Equal(t, "woocommerce", name.ValueString())
+	assert.Equal(t, "https://example.com/shop", url.ValueString())
+
+	var identity wordpressPluginIdentityModel
+	resp.Identity.Get(context.Background(), &identity)
+	assert.Equal(t, "woocommerce", identity.Name.ValueString())
+	assert.Equal(t, "https://example.com/shop", identity.URL.ValueString())
+}
+
+func TestWordpressPluginResource_ImportStateMissing(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+	cmdExec = mockCommander{output: []byte("[]")}
+
+	resp := importPlugin(t, &WPConfig{}, "not-installed")
+	assert.True(t, resp.Diagnostics.HasError())
+	assert.Equal(t, "Cannot import non-existent plugin", resp.Diagnostics.Errors()[0].Summary())
+
+	recorder := &recordingCommander{}
+	resp = importPlugin(t, &WPConfig{Exec: recorder}, "https://example.com/")
+	require.True(t, resp.Diagnostics.HasError())
+	assert.Equal(t, "Invalid import ID", resp.Diagnostics.Errors()[0].Summary())
+	assert.Contains(t, resp.Diagnostics.Errors()[0].Detail(), `site URL "https:/" must be an http:// or https:// URL`)
+	assert.Empty(t, recorder.calls, "an invalid import ID must not reach WP-CLI")
+}
+
+// planNewPlugin runs ModifyPlan for a plugin being created with the given
+// attributes set.
+func planNewPlugin(t *testing.T, cfg *WPConfig, set map[string]tftypes.Value) *resource.ModifyPlanResponse {
+	t.Helper()
+	ctx := context.Background()
+	res := &wordpressPluginResource{config: cfg}
+
+	schemaResp := &resource.SchemaResponse{}
+	res.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := map[string]tftypes.Value{}
+	for name, typ := range objType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	for name, value := range set {
+		values[name] = value
+	}
+	plan := tfsdk.Plan{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, values)}
+
+	resp := &resource.ModifyPlanResponse{Plan: plan}
+	res.ModifyPlan(ctx, resource.ModifyPlanRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: plan.Raw},
+		Plan:   plan,
+		State:  tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, nil)},
+	}, resp)
+	return resp
+}
+
+func TestWordpressPluginResource_ModifyPlanREST(t *testing.T) {
+	rest := &WPConfig{Backend: &restBackend{}}
+	name := tftypes.NewValue(tftypes.String, "akismet")
+
+	resp := planNewPlugin(t, rest, map[string]tftypes.Value{"name": name})
+	assert.False(t, resp.Diagnostics.HasError(), "%v", resp.Diagnostics)
+	resp = planNewPlugin(t, rest, map[string]tftypes.Value{
+		"name":          name,
+		"update_policy": tftypes.NewValue(tftypes.String, updatePolicyPinned),
+	})
+	assert.False(t, resp.Diagnostics.HasError(), "%v", resp.Diagnostics)
+
+	unsupported := map[string]tftypes.Value{
+		"version":       tftypes.NewValue(tftypes.String, "5.3"),
+		"update_policy": tftypes.NewValue(tftypes.String, updatePolicyMinor),
+		"source":        tftypes.NewValue(tftypes.String, "https://example.com/akismet.zip"),
+	}
+	for attr, value := range unsupported {
+		resp = planNewPlugin(t, rest, map[string]tftypes.Value{"name": name, attr: value})
+		require.True(t, resp.Diagnostics.HasError(), attr)
+		assert.Equal(t, "Unsupported plugin setting", resp.Diagnostics.Errors()[0].Summary(), attr)
+		assert.True(t, strings.HasPrefix(resp.Diagnostics.Errors()[0].Detail(), attr+" "), attr)
+		assert.Contains(t, resp.Diagnostics.Errors()[0].Detail(), `cannot be used when backend is "rest"`, attr)
+
+		// The WP-CLI backend supports them
+		resp = planNewPlugin(t, &WPConfig{}, map[string]tftypes.Value{"name": name, attr: value})
+		assert.False(t, resp.Diagnostics.HasError(), "%v", resp.Diagnostics)
+	}
+}
@@ -0,0 +1,367 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.ResourceWithModifyPlan = &wordpressThemeResource{}
+
+func NewThemeResource() resource.Resource {
+	return &wordpressThemeResource{}
+}
+
+type wordpressThemeResource struct {
+	config *WPConfig
+}
+
+type wordpressThemeModel struct {
+	Name        types.String `tfsdk:"name"`
+	Version     types.String `tfsdk:"version"`
+	Active      types.Bool   `tfsdk:"active"`
+	Title       types.String `tfsdk:"title"`
+	ParentTheme types.String `tfsdk:"parent_theme"`
+	Status      types.String `tfsdk:"status"`
+}
+
+// themeInfo is the subset of `wp theme get --format=json` output used by the provider.
+type themeInfo struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Status      string `json:"status"`
+	ParentTheme string `json:"parent_theme"`
+}
+
+func (r *wordpressThemeResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_theme"
+}
+
+func (r *wordpressThemeResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "The WP‑CLI theme slug (e.g., 'twentytwentyfour').",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(slugPattern, "must be a theme slug of letters, digits, '.', '_' and '-', not starting with '-' or '.'"),
+				},
+			},
+			"version": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The theme version to install. Defaults to the latest version available.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"active": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Whether the theme should be the active theme. Only one theme can be active at a time; activating this theme replaces the currently active one.",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"title": schema.StringAttribute{
+				Computed:    true,
+				Description: "The human readable theme name.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"parent_theme": schema.StringAttribute{
+				Computed:    true,
+				Description: "The parent theme name, if this is a child theme.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"status": schema.StringAttribute{
+				Computed:    true,
+				Description: "The theme status as reported by WP‑CLI ('active', 'parent' or 'inactive').",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+// ModifyPlan leaves status unknown when active changes, since activating or
+// deactivating the theme changes the status kept from state.
+func (r *wordpressThemeResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to do on create or destroy
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var planActive, stateActive types.Bool
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("active"), &planActive)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("active"), &stateActive)...)
+	if resp.Diagnostics.HasError() || planActive.Equal(stateActive) {
+		return
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("status"), types.StringUnknown())...)
+}
+
+func (r *wordpressThemeResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	cfg, ok := req.ProviderData.(*WPConfig)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected Provider Data Type", "Expected *WPConfig")
+		return
+	}
+	requireWPCLIBackend(cfg, "wordpress_theme", &resp.Diagnostics)
+	r.config = cfg
+}
+
+func (r *wordpressThemeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	cfg := r.config
+
+	var plan wordpressThemeModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "wordpress_theme", plan.Name.ValueString())
+	ctx = withSecrets(ctx, configSecrets(ctx, req.Config)...)
+
+	if err := checkPositional(plan.Name.ValueString()); err != nil {
+		addCommandError(&resp.Diagnostics, "Invalid theme name", err)
+		return
+	}
+
+	args := []string{"theme", "install", plan.Name.ValueString()}
+	if version := defaultStringIfUnset(plan.Version, ""); version != "" {
+		args = append(args, "--version="+version)
+	}
+	if defaultBoolIfUnset(plan.Active, false) {
+		args = append(args, "--activate")
+	}
+
+	if err := runWP(ctx, cfg, args...); err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to install theme", err)
+		return
+	}
+
+	info, err := getTheme(ctx, cfg, plan.Name.ValueString())
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to verify theme status", err)
+		return
+	}
+
+	applyThemeInfo(&plan, info)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *wordpressThemeResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	cfg := r.config
+
+	var state wordpressThemeModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "wordpress_theme", state.Name.ValueString())
+
+	if err := checkPositional(state.Name.ValueString()); err != nil {
+		addCommandError(&resp.Diagnostics, "Invalid theme name", err)
+		return
+	}
+
+	err := runWP(ctx, cfg, "theme", "is-installed", state.Name.ValueString())
+	if themeMissing(err) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to check theme", err)
+		return
+	}
+
+	info, err := getTheme(ctx, cfg, state.Name.ValueString())
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to get theme status", err)
+		return
+	}
+
+	applyThemeInfo(&state, info)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *wordpressThemeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	cfg := r.config
+
+	var plan wordpressThemeModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "wordpress_theme", plan.Name.ValueString())
+	ctx = withSecrets(ctx, configSecrets(ctx, req.Config)...)
+
+	if err := checkPositional(plan.Name.ValueString()); err != nil {
+		addCommandError(&resp.Diagnostics, "Invalid theme name", err)
+		return
+	}
+
+	var state wordpressThemeModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := plan.Name.ValueString()
+
+	// Move to the requested version, if it changed
+	if !plan.Version.IsUnknown() && !plan.Version.IsNull() &&
+		plan.Version.ValueString() != state.Version.ValueString() {
+		if err := runWP(ctx, cfg, "theme", "update", name, "--version="+plan.Version.ValueString()); err != nil {
+			addCommandError(&resp.Diagnostics, "Failed to update theme version", err)
+			return
+		}
+	}
+
+	if !plan.Active.IsUnknown() && !plan.Active.IsNull() &&
+		plan.Active.ValueBool() != state.Active.ValueBool() {
+		if plan.Active.ValueBool() {
+			// Activating a theme implicitly deactivates the previously active one.
+			if err := runWP(ctx, cfg, "theme", "activate", name); err != nil {
+				addCommandError(&resp.Diagnostics, "Failed to activate theme", err)
+				return
+			}
+		} else {
+			// WordPress has no "deactivate" for themes; another theme must be
+			// activated instead. That may already have happened if another
+			// wordpress_theme resource was updated first.
+			info, err := getTheme(ctx, cfg, name)
+			if err != nil {
+				addCommandError(&resp.Diagnostics, "Failed to verify theme status", err)
+				return
+			}
+			if info.Status == "active" {
+				resp.Diagnostics.AddError("Cannot deactivate the active theme",
+					fmt.Sprintf("Theme %s is the active theme. WordPress always needs one active theme, "+
+						"so set active = true on another wordpress_theme resource (and make this resource depend on it) "+
+						"to switch themes.", name))
+				return
+			}
+		}
+	}
+
+	info, err := getTheme(ctx, cfg, name)
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to verify theme status", err)
+		return
+	}
+
+	applyThemeInfo(&plan, info)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *wordpressThemeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	cfg := r.config
+
+	var state wordpressThemeModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "wordpress_theme", state.Name.ValueString())
+
+	if err := checkPositional(state.Name.ValueString()); err != nil {
+		addCommandError(&resp.Diagnostics, "Invalid theme name", err)
+		return
+	}
+
+	name := state.Name.ValueString()
+
+	info, err := getTheme(ctx, cfg, name)
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to verify theme status", err)
+		return
+	}
+
+	switch info.Status {
+	case "active":
+		resp.Diagnostics.AddError("Cannot delete the active theme",
+			fmt.Sprintf("Theme %s is the active theme and deleting it would leave the site without a theme. "+
+				"Activate another theme before destroying this resource.", name))
+		return
+	case "parent":
+		resp.Diagnostics.AddError("Cannot delete a parent theme in use",
+			fmt.Sprintf("Theme %s is the parent of the active theme and deleting it would break the site. "+
+				"Activate another theme before destroying this resource.", name))
+		return
+	}
+
+	if err := runWP(ctx, cfg, "theme", "delete", name); err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to delete theme", err)
+		return
+	}
+}
+
+// getTheme fetches a theme's details via `wp theme get`.
+func getTheme(ctx context.Context, cfg *WPConfig, name string) (*themeInfo, error) {
+	output, err := runWPWithOutput(ctx, cfg, "theme", "get", name, "--format=json")
+	if err != nil {
+		return nil, err
+	}
+	return parseOutput(ctx, "theme get", output, parseThemeInfo)
+}
+
+// parseThemeInfo decodes the JSON output of `wp theme get --format=json`.
+func parseThemeInfo(output string) (*themeInfo, error) {
+	var info themeInfo
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &info); err != nil {
+		return nil, fmt.Errorf("unable to parse theme details: %v\nOutput: %s", err, output)
+	}
+	return &info, nil
+}
+
+// applyThemeInfo copies the observed theme details into the model.
+func applyThemeInfo(m *wordpressThemeModel, info *themeInfo) {
+	m.Version = types.StringValue(info.Version)
+	m.Active = types.BoolValue(info.Status == "active")
+	m.Title = types.StringValue(info.Title)
+	m.ParentTheme = types.StringValue(info.ParentTheme)
+	m.Status = types.StringValue(info.Status)
+}
+
+// themeMissing reports whether err is `wp theme is-installed` exiting with
+// status 1 because the theme is not installed. WP-CLI errors such as a
+// database connection error also exit with status 1 but print an error, and
+// PHP fatal errors exit with status 255.
+func themeMissing(err error) bool {
+	var cmdErr *commandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != 1 {
+		return false
+	}
+	return !strings.Contains(cmdErr.Output(), "Error")
+}
@@ -0,0 +1,178 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordpressThemeResource_Metadata(t *testing.T) {
+	res := &wordpressThemeResource{}
+	resp := &resource.MetadataResponse{}
+	req := resource.MetadataRequest{ProviderTypeName: "wordpress"}
+	res.Metadata(context.Background(), req, resp)
+	assert.Equal(t, "wordpress_theme", resp.TypeName)
+}
+
+func TestWordpressThemeResource_Schema(t *testing.T) {
+	res := &wordpressThemeResource{}
+	resp := &resource.SchemaResponse{}
+	res.Schema(context.Background(), resource.SchemaRequest{}, resp)
+
+	for _, attr := range []string{"name", "version", "active", "title", "parent_theme", "status"} {
+		assert.Contains(t, resp.Schema.Attributes, attr)
+	}
+	assert.True(t, resp.Schema.Attributes["name"].IsRequired())
+	assert.True(t, resp.Schema.Attributes["title"].IsComputed())
+}
+
+func TestWordpressThemeResource_ModifyPlanStatus(t *testing.T) {
+	ctx := context.Background()
+	res := &wordpressThemeResource{}
+	schemaResp := &resource.SchemaResponse{}
+	res.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	theme := func(active bool, status interface{}) tftypes.Value {
+		return tftypes.NewValue(objType, map[string]tftypes.Value{
+			"name":         tftypes.NewValue(tftypes.String, "twentytwentyfour"),
+			"version":      tftypes.NewValue(tftypes.String, "1.2"),
+			"active":       tftypes.NewValue(tftypes.Bool, active),
+			"title":        tftypes.NewValue(tftypes.String, "Twenty Twenty-Four"),
+			"parent_theme": tftypes.NewValue(tftypes.String, ""),
+			"status":       tftypes.NewValue(tftypes.String, status),
+		})
+	}
+	plan := func(active bool) types.String {
+		// The attribute plan modifier has already copied status from state
+		resp := &resource.ModifyPlanResponse{Plan: tfsdk.Plan{Schema: schemaResp.Schema, Raw: theme(active, "inactive")}}
+		res.ModifyPlan(ctx, resource.ModifyPlanRequest{
+			Plan:  resp.Plan,
+			State: tfsdk.State{Schema: schemaResp.Schema, Raw: theme(false, "inactive")},
+		}, resp)
+		require.False(t, resp.Diagnostics.HasError(), "%v", resp.Diagnostics)
+
+		var status types.String
+		resp.Plan.GetAttribute(ctx, path.Root("status"), &status)
+		return status
+	}
+
+	assert.Equal(t, types.StringValue("inactive"), plan(false))
+	assert.True(t, plan(true).IsUnknown())
+
+	status := schemaResp.Schema.Attributes["status"].(schema.StringAttribute)
+	assert.Len(t, status.PlanModifiers, 1)
+}
+
+func TestWordpressThemeResource_Configure(t *testing.T) {
+	res := &wordpressThemeResource{}
+	wpCfg := &WPConfig{SSHTarget: "foo", RemotePath: "bar"}
+	resp := &resource.ConfigureResponse{}
+	res.Configure(context.Background(), resource.ConfigureRequest{ProviderData: wpCfg}, resp)
+	assert.Equal(t, wpCfg, res.config)
+	assert.False(t, resp.Diagnostics.HasError())
+
+	res2 := &wordpressThemeResource{}
+	resp2 := &resource.ConfigureResponse{}
+	res2.Configure(context.Background(), resource.ConfigureRequest{ProviderData: "nope"}, resp2)
+	assert.Nil(t, res2.config)
+	assert.True(t, resp2.Diagnostics.HasError())
+}
+
+func TestParseThemeInfo(t *testing.T) {
+	info, err := parseThemeInfo(`{"name":"twentytwentyfour-child","title":"Twenty Twenty-Four Child","version":"1.0","status":"active","parent_theme":"Twenty Twenty-Four","tags":["blog"]}` + "\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "twentytwentyfour-child", info.Name)
+	assert.Equal(t, "Twenty Twenty-Four Child", info.Title)
+	assert.Equal(t, "1.0", info.Version)
+	assert.Equal(t, "active", info.Status)
+	assert.Equal(t, "Twenty Twenty-Four", info.ParentTheme)
+}
+
+func TestParseThemeInfo_Invalid(t *testing.T) {
+	_, err := parseThemeInfo("Error: The 'foo' theme could not be found.")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to parse theme details")
+}
+
+func TestGetTheme_CommandFailure(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+
+	cmdExec = mockCommander{output: []byte("Error: not found"), err: assert.AnError}
+
+	_, err := getTheme(context.Background(), &WPConfig{}, "missing")
+	var cmdErr *commandError
+	assert.ErrorAs(t, err, &cmdErr)
+	assert.Contains(t, err.Error(), "wp theme get missing --format=json failed")
+	assert.Contains(t, err.Error(), "Error: not found")
+}
+
+func TestThemeResource_ReadRemovesOnlyMissingThemes(t *testing.T) {
+	ctx := context.Background()
+	r := &wordpressThemeResource{}
+
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := map[string]tftypes.Value{}
+	for name, typ := range objType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["name"] = tftypes.NewValue(tftypes.String, "twentytwentyfour")
+	state := tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, values)}
+
+	tests := []struct {
+		name    string
+		stderr  string
+		code    int
+		removed bool
+	}{
+		{name: "not installed", code: 1, removed: true},
+		{name: "not installed with warning", stderr: "PHP Warning:  Undefined array key \"HTTP_HOST\"", code: 1, removed: true},
+		{name: "database", stderr: "Error: Error establishing a database connection.", code: 1},
+		{name: "php fatal", stderr: "PHP Fatal error:  Uncaught Error: Call to undefined function foo()", code: 255},
+		{name: "bad path", stderr: "Error: This does not seem to be a WordPress installation.", code: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.config = &WPConfig{Exec: splitCommander{stderr: []byte(tt.stderr), err: &dockerExitError{ExitCode: tt.code}}}
+
+			resp := &resource.ReadResponse{State: state}
+			r.Read(ctx, resource.ReadRequest{State: state}, resp)
+
+			if tt.removed {
+				assert.False(t, resp.Diagnostics.HasError())
+				assert.True(t, resp.State.Raw.IsNull())
+				return
+			}
+			require.True(t, resp.Diagnostics.HasError())
+			assert.Equal(t, "Failed to check theme", resp.Diagnostics[0].Summary())
+			assert.False(t, resp.State.Raw.IsNull(), "the theme must stay in state")
+		})
+	}
+}
+
+func TestApplyThemeInfo(t *testing.T) {
+	m := wordpressThemeModel{Name: types.StringValue("twentytwentythree")}
+	applyThemeInfo(&m, &themeInfo{Title: "Twenty Twenty-Three", Version: "1.5", Status: "inactive"})
+	assert.Equal(t, "1.5", m.Version.ValueString())
+	assert.False(t, m.Active.ValueBool())
+	assert.Equal(t, "", m.ParentTheme.ValueString())
+	assert.Equal(t, "inactive", m.Status.ValueString())
+
+	applyThemeInfo(&m, &themeInfo{Status: "active"})
+	assert.True(t, m.Active.ValueBool())
+}
@@ -0,0 +1,555 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func NewUserResource() resource.Resource {
+	return &wordpressUserResource{}
+}
+
+type wordpressUserResource struct {
+	config *WPConfig
+}
+
+type wordpressUserModel struct {
+	ID                types.Int64  `tfsdk:"id"`
+	Login             types.String `tfsdk:"login"`
+	Email             types.String `tfsdk:"email"`
+	DisplayName       types.String `tfsdk:"display_name"`
+	FirstName         types.String `tfsdk:"first_name"`
+	LastName          types.String `tfsdk:"last_name"`
+	Roles             types.Set    `tfsdk:"roles"`
+	PasswordWO        types.String `tfsdk:"password_wo"`
+	PasswordWOVersion types.Int64  `tfsdk:"password_wo_version"`
+	ReassignTo        types.Int64  `tfsdk:"reassign_to"`
+}
+
+// userInfo is the subset of `wp user get --format=json` output used by the provider.
+type userInfo struct {
+	ID          int64  `json:"ID"`
+	Login       string `json:"user_login"`
+	Email       string `json:"user_email"`
+	DisplayName string `json:"display_name"`
+	Roles       string `json:"roles"`
+	FirstName   string `json:"-"`
+	LastName    string `json:"-"`
+}
+
+// userMetaEntry is a row of `wp user meta list --format=json` output.
+type userMetaEntry struct {
+	Key   string `json:"meta_key"`
+	Value string `json:"meta_value"`
+}
+
+func (r *wordpressUserResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_user"
+}
+
+func (r *wordpressUserResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The WordPress user ID.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"login": schema.StringAttribute{
+				Required:    true,
+				Description: "The user login name. WordPress does not allow changing it, so a new value replaces the user.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthAtMost(60),
+					stringvalidator.RegexMatches(loginPattern, "must only contain letters, digits, spaces and the characters _ . - @, not starting with '-'"),
+				},
+			},
+			"email": schema.StringAttribute{
+				Required:    true,
+				Description: "The user email address.",
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(emailPattern, "must be an email address"),
+				},
+			},
+			"display_name": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The name displayed publicly for the user. Defaults to the login name.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"first_name": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The user's first name.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"last_name": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The user's last name.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"roles": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Description: "The user's roles (e.g., ['editor']). Defaults to the site's default role.",
+				PlanModifiers: []planmodifier.Set{
+					setplanmodifier.UseStateForUnknown(),
+				},
+				Validators: []validator.Set{
+					setvalidator.ValueStringsAre(stringvalidator.RegexMatches(rolePattern, "must be a role name of letters, digits, '_' and '-'")),
+				},
+			},
+			"password_wo": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,
+				WriteOnly: true,
+				Description: "The user's password. This value is write-only and never stored in state; " +
+					"change password_wo_version to update it. When unset, WordPress generates a random password.",
+			},
+			"password_wo_version": schema.Int64Attribute{
+				Optional:    true,
+				Description: "An arbitrary number that triggers a password update from password_wo when changed.",
+			},
+			"reassign_to": schema.Int64Attribute{
+				Optional: true,
+				Description: "The ID of the user that receives this user's posts and links when the user is destroyed. " +
+					"When unset, the user's content is deleted with the user.",
+			},
+		},
+	}
+}
+
+func (r *wordpressUserResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	cfg, ok := req.ProviderData.(*WPConfig)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected Provider Data Type", "Expected *WPConfig")
+		return
+	}
+	requireWPCLIBackend(cfg, "wordpress_user", &resp.Diagnostics)
+	r.config = cfg
+}
+
+func (r *wordpressUserResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	cfg := r.config
+
+	var plan wordpressUserModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "wordpress_user", plan.Login.ValueString())
+	ctx = withSecrets(ctx, configSecrets(ctx, req.Config)...)
+
+	password, diags := userPassword(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	roles, diags := userRoles(ctx, plan.Roles)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := checkPositional(plan.Login.ValueString(), plan.Email.ValueString()); err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to create user", err)
+		return
+	}
+
+	args := []string{"user", "create", plan.Login.ValueString(), plan.Email.ValueString(), "--porcelain"}
+	args = append(args, userFieldArgs(plan, wordpressUserModel{})...)
+	if len(roles) > 0 {
+		args = append(args, "--role="+roles[0])
+	}
+
+	output, err := runWPWithOutput(ctx, cfg, args...)
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to create user", err)
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create user",
+			fmt.Sprintf("Unable to parse user ID from WP-CLI output: %v\nOutput: %s", err, output))
+		return
+	}
+	plan.ID = types.Int64Value(id)
+
+	// Save the ID right away so a failure below does not orphan the user
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), plan.ID)...)
+
+	if password != "" {
+		if err := setUserPassword(ctx, cfg, id, password); err != nil {
+			addCommandError(&resp.Diagnostics, "Failed to set user password", err)
+			return
+		}
+	}
+
+	if roles != nil {
+		info, err := getUser(ctx, cfg, id)
+		if err != nil {
+			addCommandError(&resp.Diagnostics, "Failed to read user", err)
+			return
+		}
+		if err := syncUserRoles(ctx, cfg, id, splitRoles(info.Roles), roles); err != nil {
+			addCommandError(&resp.Diagnostics, "Failed to set user roles", err)
+			return
+		}
+	}
+
+	info, err := getUser(ctx, cfg, id)
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to read user", err)
+		return
+	}
+
+	resp.Diagnostics.Append(applyUserInfo(ctx, &plan, info)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *wordpressUserResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	cfg := r.config
+
+	var state wordpressUserModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "wordpress_user", strconv.FormatInt(state.ID.ValueInt64(), 10))
+
+	info, err := getUser(ctx, cfg, state.ID.ValueInt64())
+	if userMissing(err) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to read user", err)
+		return
+	}
+
+	resp.Diagnostics.Append(applyUserInfo(ctx, &state, info)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *wordpressUserResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	cfg := r.config
+
+	var plan wordpressUserModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "wordpress_user", strconv.FormatInt(plan.ID.ValueInt64(), 10))
+	ctx = withSecrets(ctx, configSecrets(ctx, req.Config)...)
+
+	var state wordpressUserModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	id := state.ID.ValueInt64()
+	plan.ID = state.ID
+
+	args := userFieldArgs(plan, state)
+	if !plan.Email.Equal(state.Email) {
+		args = append(args, "--user_email="+plan.Email.ValueString())
+	}
+
+	if len(args) > 0 {
+		args = append([]string{"user", "update", strconv.FormatInt(id, 10)}, args...)
+		if err := runWP(ctx, cfg, args...); err != nil {
+			addCommandError(&resp.Diagnostics, "Failed to update user", err)
+			return
+		}
+	}
+
+	if !plan.PasswordWOVersion.Equal(state.PasswordWOVersion) {
+		password, diags := userPassword(ctx, req.Config)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if password != "" {
+			if err := setUserPassword(ctx, cfg, id, password); err != nil {
+				addCommandError(&resp.Diagnostics, "Failed to set user password", err)
+				return
+			}
+		}
+	}
+
+	roles, diags := userRoles(ctx, plan.Roles)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if roles != nil {
+		info, err := getUser(ctx, cfg, id)
+		if err != nil {
+			addCommandError(&resp.Diagnostics, "Failed to read user", err)
+			return
+		}
+		if err := syncUserRoles(ctx, cfg, id, splitRoles(info.Roles), roles); err != nil {
+			addCommandError(&resp.Diagnostics, "Failed to set user roles", err)
+			return
+		}
+	}
+
+	info, err := getUser(ctx, cfg, id)
+	if err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to read user", err)
+		return
+	}
+
+	resp.Diagnostics.Append(applyUserInfo(ctx, &plan, info)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *wordpressUserResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	cfg := r.config
+
+	var state wordpressUserModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAuditResource(ctx, "wordpress_user", strconv.FormatInt(state.ID.ValueInt64(), 10))
+
+	args := []string{"user", "delete", strconv.FormatInt(state.ID.ValueInt64(), 10), "--yes"}
+	if !state.ReassignTo.IsNull() && !state.ReassignTo.IsUnknown() {
+		args = append(args, "--reassign="+strconv.FormatInt(state.ReassignTo.ValueInt64(), 10))
+	}
+
+	if err := runWP(ctx, cfg, args...); err != nil {
+		addCommandError(&resp.Diagnostics, "Failed to delete user", err)
+		return
+	}
+}
+
+// userPassword reads the write-only password from the configuration.
+func userPassword(ctx context.Context, config tfsdk.Config) (string, diag.Diagnostics) {
+	var password types.String
+	diags := config.GetAttribute(ctx, path.Root("password_wo"), &password)
+	return defaultStringIfUnset(password, ""), diags
+}
+
+// userRoles returns the sorted planned roles, or nil if they are not configured.
+func userRoles(ctx context.Context, set types.Set) ([]string, diag.Diagnostics) {
+	if set.IsNull() || set.IsUnknown() {
+		return nil, nil
+	}
+	roles := []string{}
+	diags := set.ElementsAs(ctx, &roles, false)
+	sort.Strings(roles)
+	return roles, diags
+}
+
+// userFieldArgs returns the `wp user create/update` flags for profile fields
+// that are configured in plan and differ from state.
+func userFieldArgs(plan, state wordpressUserModel) []string {
+	var args []string
+	fields := []struct {
+		flag    string
+		planned types.String
+		current types.String
+	}{
+		{"display_name", plan.DisplayName, state.DisplayName},
+		{"first_name", plan.FirstName, state.FirstName},
+		{"last_name", plan.LastName, state.LastName},
+	}
+	for _, f := range fields {
+		if f.planned.IsNull() || f.planned.IsUnknown() || f.planned.Equal(f.current) {
+			continue
+		}
+		args = append(args, "--"+f.flag+"="+f.planned.ValueString())
+	}
+	return args
+}
+
+// syncUserRoles adds and removes roles so the user ends up with exactly desired.
+func syncUserRoles(ctx context.Context, cfg *WPConfig, id int64, current, desired []string) error {
+	userID := strconv.FormatInt(id, 10)
+	add, remove := diffRoles(current, desired)
+	if err := checkPositional(append(append([]string{}, add...), remove...)...); err != nil {
+		return err
+	}
+	for _, role := range add {
+		if err := runWP(ctx, cfg, "user", "add-role", userID, role); err != nil {
+			return err
+		}
+	}
+	for _, role := range remove {
+		if err := runWP(ctx, cfg, "user", "remove-role", userID, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffRoles returns the roles to add and remove to go from current to desired.
+func diffRoles(current, desired []string) (add, remove []string) {
+	have := map[string]bool{}
+	for _, role := range current {
+		have[role] = true
+	}
+	want := map[string]bool{}
+	for _, role := range desired {
+		want[role] = true
+		if !have[role] {
+			add = append(add, role)
+		}
+	}
+	for _, role := range current {
+		if !want[role] {
+			remove = append(remove, role)
+		}
+	}
+	return add, remove
+}
+
+// setUserPasswordPHP is run by `wp eval` to set the password of the user
+// with the given ID to the base64-encoded first line of stdin. A transport
+// or wrapper that drops stdin leaves nothing to read, which must fail rather
+// than set an empty password.
+const setUserPasswordPHP = `$user = get_userdata(%d); if (!$user) { WP_CLI::error('Invalid user ID.'); } ` +
+	`$line = fgets(STDIN); $password = $line === false ? false : base64_decode(trim($line), true); ` +
+	`if ($password === false || $password === '') { WP_CLI::error('No password received on stdin.'); } ` +
+	`wp_set_password($password, $user->ID);`
+
+// setUserPassword sets a user's password. The password is written to the
+// command's stdin rather than passed as --user_pass, where it would be
+// visible in the process list of the host running WP-CLI.
+func setUserPassword(ctx context.Context, cfg *WPConfig, id int64, password string) error {
+	input := base64.StdEncoding.EncodeToString([]byte(password)) + "\n"
+	return runWP(withStdin(ctx, []byte(input)), cfg, "eval", fmt.Sprintf(setUserPasswordPHP, id))
+}
+
+// getUser fetches a user's profile and name fields via WP-CLI.
+func getUser(ctx context.Context, cfg *WPConfig, id int64) (*userInfo, error) {
+	userID := strconv.FormatInt(id, 10)
+
+	output, err := runWPWithOutput(ctx, cfg, "user", "get", userID, "--format=json")
+	if err != nil {
+		return nil, err
+	}
+	info, err := parseOutput(ctx, "user get", output, parseUserInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err = runWPWithOutput(ctx, cfg, "user", "meta", "list", userID, "--keys=first_name,last_name", "--format=json")
+	if err != nil {
+		return nil, err
+	}
+	meta, err := parseOutput(ctx, "user meta list", output, parseUserMeta)
+	if err != nil {
+		return nil, err
+	}
+	info.FirstName = meta["first_name"]
+	info.LastName = meta["last_name"]
+
+	return info, nil
+}
+
+// userMissing reports whether err is WP-CLI rejecting the user ID because the
+// user does not exist, as opposed to any other failure to read it.
+func userMissing(err error) bool {
+	var cmdErr *commandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != 1 {
+		return false
+	}
+	return strings.Contains(cmdErr.Output(), "Invalid user ID")
+}
+
+// parseUserInfo decodes the JSON output of `wp user get --format=json`.
+func parseUserInfo(output string) (*userInfo, error) {
+	var info userInfo
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &info); err != nil {
+		return nil, fmt.Errorf("unable to parse user details: %v\nOutput: %s", err, output)
+	}
+	return &info, nil
+}
+
+// parseUserMeta decodes the JSON output of `wp user meta list --format=json` into a map.
+func parseUserMeta(output string) (map[string]string, error) {
+	var entries []userMetaEntry
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &entries); err != nil {
+		return nil, fmt.Errorf("unable to parse user meta: %v\nOutput: %s", err, output)
+	}
+	meta := map[string]string{}
+	for _, e := range entries {
+		meta[e.Key] = e.Value
+	}
+	return meta, nil
+}
+
+// splitRoles turns the comma separated roles reported by WP-CLI into a sorted slice.
+func splitRoles(roles string) []string {
+	result := []string{}
+	for _, role := range strings.Split(roles, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			result = append(result, role)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// applyUserInfo copies the observed user details into the model.
+func applyUserInfo(ctx context.Context, m *wordpressUserModel, info *userInfo) diag.Diagnostics {
+	roles, diags := types.SetValueFrom(ctx, types.StringType, splitRoles(info.Roles))
+
+	m.ID = types.Int64Value(info.ID)
+	m.Login = types.StringValue(info.Login)
+	m.Email = types.StringValue(info.Email)
+	m.DisplayName = types.StringValue(info.DisplayName)
+	m.FirstName = types.StringValue(info.FirstName)
+	m.LastName = types.StringValue(info.LastName)
+	m.Roles = roles
+	m.PasswordWO = types.StringNull()
+	return diags
+}
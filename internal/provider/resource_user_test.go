@@ -0,0 +1,220 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordpressUserResource_Metadata(t *testing.T) {
+	res := &wordpressUserResource{}
+	resp := &resource.MetadataResponse{}
+	res.Metadata(context.Background(), resource.MetadataRequest{ProviderTypeName: "wordpress"}, resp)
+	assert.Equal(t, "wordpress_user", resp.TypeName)
+}
+
+func TestWordpressUserResource_Schema(t *testing.T) {
+	res := &wordpressUserResource{}
+	resp := &resource.SchemaResponse{}
+	res.Schema(context.Background(), resource.SchemaRequest{}, resp)
+
+	for _, attr := range []string{"id", "login", "email", "display_name", "first_name", "last_name", "roles", "password_wo", "password_wo_version", "reassign_to"} {
+		assert.Contains(t, resp.Schema.Attributes, attr)
+	}
+
+	password, ok := resp.Schema.Attributes["password_wo"].(schema.StringAttribute)
+	assert.True(t, ok)
+	assert.True(t, password.IsWriteOnly())
+	assert.True(t, password.IsSensitive())
+	assert.False(t, resp.Diagnostics.HasError())
+}
+
+func TestParseUserInfo(t *testing.T) {
+	info, err := parseUserInfo(`{"ID":7,"user_login":"editor1","display_name":"Ed","user_email":"ed@example.com","user_registered":"2025-01-01 00:00:00","roles":"editor, author"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), info.ID)
+	assert.Equal(t, "editor1", info.Login)
+	assert.Equal(t, "ed@example.com", info.Email)
+	assert.Equal(t, "Ed", info.DisplayName)
+	assert.Equal(t, []string{"author", "editor"}, splitRoles(info.Roles))
+
+	_, err = parseUserInfo("Error: Invalid user ID, email or login: '99'")
+	assert.Error(t, err)
+}
+
+func TestParseUserMeta(t *testing.T) {
+	meta, err := parseUserMeta(`[{"user_id":7,"meta_key":"first_name","meta_value":"Ed"},{"user_id":7,"meta_key":"last_name","meta_value":"Itor"}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ed", meta["first_name"])
+	assert.Equal(t, "Itor", meta["last_name"])
+
+	_, err = parseUserMeta("nope")
+	assert.Error(t, err)
+}
+
+func TestSplitRoles(t *testing.T) {
+	assert.Equal(t, []string{}, splitRoles(""))
+	assert.Equal(t, []string{"administrator"}, splitRoles("administrator"))
+	assert.Equal(t, []string{"editor", "shop_manager"}, splitRoles("shop_manager,editor"))
+}
+
+func TestDiffRoles(t *testing.T) {
+	add, remove := diffRoles([]string{"subscriber"}, []string{"editor", "author"})
+	assert.Equal(t, []string{"editor", "author"}, add)
+	assert.Equal(t, []string{"subscriber"}, remove)
+
+	add, remove = diffRoles([]string{"editor"}, []string{"editor"})
+	assert.Empty(t, add)
+	assert.Empty(t, remove)
+}
+
+func TestSyncUserRoles_RejectsFlagLikeRoles(t *testing.T) {
+	recorder := &recordingCommander{}
+	cfg := &WPConfig{Exec: recorder}
+
+	err := syncUserRoles(context.Background(), cfg, 7, []string{"subscriber"}, []string{"editor", "--network"})
+	require.Error(t, err)
+	assert.Empty(t, recorder.calls)
+
+	require.NoError(t, syncUserRoles(context.Background(), cfg, 7, []string{"subscriber"}, []string{"editor"}))
+	assert.Equal(t, [][]string{
+		{"wp", "user", "add-role", "7", "editor"},
+		{"wp", "user", "remove-role", "7", "subscriber"},
+	}, recorder.calls)
+}
+
+func TestUserResource_ReadRemovesOnlyMissingUsers(t *testing.T) {
+	ctx := context.Background()
+	r := &wordpressUserResource{}
+
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := map[string]tftypes.Value{}
+	for name, typ := range objType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["id"] = tftypes.NewValue(tftypes.Number, 7)
+	values["login"] = tftypes.NewValue(tftypes.String, "jdoe")
+	state := tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, values)}
+
+	tests := []struct {
+		name    string
+		stdout  string
+		stderr  string
+		code    int
+		removed bool
+	}{
+		{name: "missing", stderr: "Error: Invalid user ID, email or login: '7'", code: 1, removed: true},
+		{name: "database", stderr: "Error: Error establishing a database connection.", code: 1},
+		{name: "php fatal", stderr: "PHP Fatal error:  Allowed memory size exhausted", code: 255},
+		{name: "bad json", stdout: "Warning: something\n{"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.code != 0 {
+				err = &dockerExitError{ExitCode: tt.code}
+			}
+			r.config = &WPConfig{Exec: splitCommander{stdout: []byte(tt.stdout), stderr: []byte(tt.stderr), err: err}}
+
+			resp := &resource.ReadResponse{State: state}
+			r.Read(ctx, resource.ReadRequest{State: state}, resp)
+
+			if tt.removed {
+				assert.False(t, resp.Diagnostics.HasError())
+				assert.True(t, resp.State.Raw.IsNull())
+				return
+			}
+			require.True(t, resp.Diagnostics.HasError())
+			assert.False(t, resp.State.Raw.IsNull(), "the user must stay in state")
+		})
+	}
+}
+
+// stdinCommander records the commands it runs and the stdin they were given.
+type stdinCommander struct {
+	calls [][]string
+	stdin []string
+}
+
+func (c *stdinCommander) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	c.calls = append(c.calls, append([]string{name}, args...))
+	var input []byte
+	if stdin := commandStdin(ctx); stdin != nil {
+		input, _ = io.ReadAll(stdin)
+	}
+	c.stdin = append(c.stdin, string(input))
+	return nil, nil
+}
+
+func TestSetUserPassword(t *testing.T) {
+	commander := &stdinCommander{}
+	password := " p@ss'word\n$(id) "
+
+	err := setUserPassword(context.Background(), &WPConfig{Exec: commander}, 7, password)
+	require.NoError(t, err)
+	require.Len(t, commander.calls, 1)
+	assert.Equal(t, []string{"wp", "eval"}, commander.calls[0][:2])
+	assert.Contains(t, commander.calls[0][2], "get_userdata(7)")
+	for _, arg := range commander.calls[0] {
+		assert.NotContains(t, arg, "p@ss")
+	}
+
+	line := strings.TrimSuffix(commander.stdin[0], "\n")
+	assert.NotContains(t, line, "\n", "the password must be sent as a single line")
+	decoded, err := base64.StdEncoding.DecodeString(line)
+	require.NoError(t, err)
+	assert.Equal(t, password, string(decoded))
+}
+
+func TestSetUserPasswordPHP_RejectsMissingPassword(t *testing.T) {
+	// Without stdin fgets returns false, and base64_decode('') returns '';
+	// neither may reach wp_set_password
+	script := fmt.Sprintf(setUserPasswordPHP, 7)
+	guard := "if ($password === false || $password === '') { WP_CLI::error('No password received on stdin.'); }"
+	assert.Contains(t, script, "$line === false ? false :")
+	assert.Contains(t, script, "base64_decode(trim($line), true)")
+	assert.Contains(t, script, guard)
+	assert.Less(t, strings.Index(script, guard), strings.Index(script, "wp_set_password("))
+}
+
+func TestUserFieldArgs(t *testing.T) {
+	plan := wordpressUserModel{
+		DisplayName: types.StringValue("Ed"),
+		FirstName:   types.StringValue("Edward"),
+		LastName:    types.StringUnknown(),
+	}
+	state := wordpressUserModel{
+		DisplayName: types.StringValue("Ed"),
+		FirstName:   types.StringValue("Eddie"),
+		LastName:    types.StringValue("Itor"),
+	}
+	assert.Equal(t, []string{"--first_name=Edward"}, userFieldArgs(plan, state))
+	assert.Equal(t, []string{"--display_name=Ed", "--first_name=Edward"}, userFieldArgs(plan, wordpressUserModel{}))
+}
+
+func TestApplyUserInfo(t *testing.T) {
+	m := wordpressUserModel{PasswordWO: types.StringValue("secret")}
+	diags := applyUserInfo(context.Background(), &m, &userInfo{ID: 3, Login: "svc", Email: "svc@example.com", Roles: "editor", FirstName: "Service"})
+	assert.False(t, diags.HasError())
+	assert.Equal(t, int64(3), m.ID.ValueInt64())
+	assert.Equal(t, "Service", m.FirstName.ValueString())
+	assert.Len(t, m.Roles.Elements(), 1)
+	assert.True(t, m.PasswordWO.IsNull(), "write-only password must never be kept in state")
+}
@@ -0,0 +1,84 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultRetryAttempts is how many times a command failing with a transient
+// error is tried unless the provider configures retry_attempts.
+const defaultRetryAttempts = 3
+
+// defaultRetryDelay is the wait before the first retry unless the provider
+// configures retry_delay. The delay doubles with each further retry.
+const defaultRetryDelay = time.Second
+
+// maxRetryDelay caps the wait between two attempts.
+const maxRetryDelay = 30 * time.Second
+
+// transientFailures are printed by commands that failed for a reason that
+// is likely to go away on its own, such as a dropped connection.
+var transientFailures = []string{
+	"Connection reset by peer",
+	"connection reset by peer",
+	"Connection closed by",
+	"kex_exchange_identification",
+	"ssh_exchange_identification",
+	"Error establishing a database connection",
+}
+
+// transientError reports whether err is a command failure that is worth
+// retrying: a reset connection, a failed SSH handshake, or WordPress failing
+// to reach its database. Authentication and host key failures are not.
+func transientError(err error) bool {
+	var cmdErr *commandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	msg := cmdErr.Err.Error()
+	if strings.Contains(msg, "SSH handshake") {
+		return !strings.Contains(msg, "unable to authenticate") && !strings.Contains(msg, "knownhosts")
+	}
+
+	output := cmdErr.Output()
+	for _, failure := range transientFailures {
+		if strings.Contains(output, failure) || strings.Contains(msg, failure) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryPolicy returns how many attempts a command gets and the delay before
+// the first retry. Mutating commands get a single attempt unless cfg opts in
+// to retrying them, since a transient failure may hit after the change was
+// already made.
+func retryPolicy(cfg *WPConfig, name string, args []string) (int, time.Duration) {
+	if !cfg.RetryMutatingCommands && classifyCommand(name, args) != commandReadOnly {
+		return 1, 0
+	}
+
+	attempts := cfg.RetryAttempts
+	if attempts <= 0 {
+		attempts = defaultRetryAttempts
+	}
+	delay := cfg.RetryDelay
+	if delay <= 0 {
+		delay = defaultRetryDelay
+	}
+	return attempts, delay
+}
+
+// nextRetryDelay doubles delay, up to maxRetryDelay.
+func nextRetryDelay(delay time.Duration) time.Duration {
+	return min(delay*2, maxRetryDelay)
+}
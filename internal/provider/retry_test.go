@@ -0,0 +1,153 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyCommander fails its first failures calls with stderr and exit status
+// 255, then succeeds.
+type flakyCommander struct {
+	failures int
+	stderr   string
+	calls    int
+}
+
+func (f *flakyCommander) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	stdout, stderr, err := f.Output(ctx, name, args...)
+	return append(stdout, stderr...), err
+}
+
+func (f *flakyCommander) Output(_ context.Context, name string, args ...string) ([]byte, []byte, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, []byte(f.stderr), &dockerExitError{ExitCode: 255}
+	}
+	return []byte("[]"), nil, nil
+}
+
+const testConnectionReset = "kex_exchange_identification: read: Connection reset by peer\nConnection reset by 10.0.0.5 port 22"
+
+func TestRunCommand_RetriesTransientReadOnlyCommands(t *testing.T) {
+	flaky := &flakyCommander{failures: 2, stderr: testConnectionReset}
+	cfg := &WPConfig{Exec: flaky, RetryDelay: time.Millisecond}
+
+	output, err := runWPWithOutput(context.Background(), cfg, "plugin", "list", "--format=json")
+	require.NoError(t, err)
+	assert.Equal(t, "[]", output)
+	assert.Equal(t, 3, flaky.calls)
+}
+
+func TestRunCommand_GivesUpAfterAttempts(t *testing.T) {
+	flaky := &flakyCommander{failures: 10, stderr: "Error establishing a database connection"}
+	cfg := &WPConfig{Exec: flaky, RetryAttempts: 2, RetryDelay: time.Millisecond}
+
+	_, err := runWPWithOutput(context.Background(), cfg, "option", "get", "blogname")
+	var cmdErr *commandError
+	require.ErrorAs(t, err, &cmdErr)
+	assert.Equal(t, 2, cmdErr.Attempts)
+	assert.Equal(t, 2, flaky.calls)
+	assert.Contains(t, err.Error(), "wp option get blogname failed after 2 attempts with exit code 255")
+}
+
+func TestRunCommand_DoesNotRetryMutatingCommands(t *testing.T) {
+	flaky := &flakyCommander{failures: 1, stderr: testConnectionReset}
+	cfg := &WPConfig{Exec: flaky, RetryDelay: time.Millisecond}
+
+	err := runWP(context.Background(), cfg, "plugin", "install", "akismet")
+	assert.True(t, transientError(err))
+	assert.Equal(t, 1, flaky.calls)
+
+	// Unless the provider opts in
+	flaky = &flakyCommander{failures: 1, stderr: testConnectionReset}
+	cfg = &WPConfig{Exec: flaky, RetryDelay: time.Millisecond, RetryMutatingCommands: true}
+	require.NoError(t, runWP(context.Background(), cfg, "plugin", "install", "akismet"))
+	assert.Equal(t, 2, flaky.calls)
+}
+
+func TestRunCommand_DoesNotRetryPermanentErrors(t *testing.T) {
+	flaky := &flakyCommander{failures: 1, stderr: "Error: The 'missing' plugin could not be found."}
+	cfg := &WPConfig{Exec: flaky, RetryDelay: time.Millisecond}
+
+	_, err := runWPWithOutput(context.Background(), cfg, "plugin", "get", "missing")
+	assert.Error(t, err)
+	assert.Equal(t, 1, flaky.calls)
+}
+
+func TestRunCommand_StopsRetryingWhenCanceled(t *testing.T) {
+	flaky := &flakyCommander{failures: 10, stderr: testConnectionReset}
+	cfg := &WPConfig{Exec: flaky, RetryDelay: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := runWPWithOutput(ctx, cfg, "plugin", "list")
+	assert.True(t, transientError(err))
+	assert.Equal(t, 1, flaky.calls)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestTransientError(t *testing.T) {
+	cases := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"not a command error": {err: errors.New("Connection reset by peer")},
+		"connection reset": {
+			err:      &commandError{ExitCode: 255, Stderr: []byte(testConnectionReset), Err: errors.New("exit status 255")},
+			expected: true,
+		},
+		"connection reset in transport": {
+			err:      &commandError{ExitCode: -1, Err: fmt.Errorf("reading output from container wp: %w", syscall.ECONNRESET)},
+			expected: true,
+		},
+		"database unavailable": {
+			err:      &commandError{ExitCode: 1, Stdout: []byte("<h1>Error establishing a database connection</h1>"), Err: errors.New("exit status 1")},
+			expected: true,
+		},
+		"ssh handshake": {
+			err:      &commandError{ExitCode: -1, Err: errors.New("SSH handshake with wp:22 failed: EOF")},
+			expected: true,
+		},
+		"ssh authentication": {
+			err: &commandError{ExitCode: -1, Err: errors.New("SSH handshake with wp:22 failed: ssh: unable to authenticate, attempted methods [none publickey]")},
+		},
+		"ssh host key": {
+			err: &commandError{ExitCode: -1, Err: errors.New("SSH handshake with wp:22 failed: knownhosts: key mismatch")},
+		},
+		"wp-cli error": {
+			err: &commandError{ExitCode: 1, Stderr: []byte("Error: Plugin not found."), Err: errors.New("exit status 1")},
+		},
+		"timeout": {err: &commandTimeoutError{Command: "wp plugin list"}},
+	}
+	for name, tc := range cases {
+		assert.Equal(t, tc.expected, transientError(tc.err), name)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	attempts, delay := retryPolicy(&WPConfig{}, "wp", []string{"plugin", "list"})
+	assert.Equal(t, defaultRetryAttempts, attempts)
+	assert.Equal(t, defaultRetryDelay, delay)
+
+	attempts, _ = retryPolicy(&WPConfig{RetryAttempts: 5}, "wp", []string{"plugin", "delete", "akismet"})
+	assert.Equal(t, 1, attempts)
+
+	attempts, delay = retryPolicy(&WPConfig{RetryAttempts: 5, RetryDelay: time.Minute, RetryMutatingCommands: true}, "wp", []string{"plugin", "delete", "akismet"})
+	assert.Equal(t, 5, attempts)
+	assert.Equal(t, time.Minute, delay)
+
+	assert.Equal(t, 2*time.Second, nextRetryDelay(time.Second))
+	assert.Equal(t, maxRetryDelay, nextRetryDelay(20*time.Second))
+}
@@ -0,0 +1,245 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Transports select how WP-CLI commands reach the WordPress host.
+const (
+	// transportWPCLI runs the local wp binary, which reaches ssh_target through its --ssh option.
+	transportWPCLI = "wp-cli"
+
+	// transportSSH runs wp on the host over a native SSH connection.
+	transportSSH = "ssh"
+
+	// transportDocker runs wp inside a container through the Docker Engine API.
+	transportDocker = "docker"
+
+	// transportKubernetes runs wp inside a pod through the Kubernetes pod exec API.
+	transportKubernetes = "kubernetes"
+
+	// transportLocal runs wp on the machine Terraform runs on.
+	transportLocal = "local"
+)
+
+// pidWrapper makes the shell print its PID before replacing itself with the
+// command, so exec based transports can kill the command if the operation is
+// canceled.
+const pidWrapper = `echo $$; exec "$@"`
+
+// wrapWithPID returns the argv that runs name and args under pidWrapper.
+func wrapWithPID(name string, args []string) []string {
+	return append([]string{"/bin/sh", "-c", pidWrapper, "sh", name}, args...)
+}
+
+// pidCapture strips the PID line printed by pidWrapper from a stdout stream
+// and passes the rest through to w.
+type pidCapture struct {
+	w    io.Writer
+	mu   sync.Mutex
+	line []byte
+	done bool
+}
+
+func (p *pidCapture) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(b)
+	if !p.done {
+		line, rest, found := bytes.Cut(b, []byte("\n"))
+		p.line = append(p.line, line...)
+		if !found {
+			return n, nil
+		}
+		p.done = true
+		b = rest
+	}
+	if _, err := p.w.Write(b); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// PID returns the captured PID, or "" if it has not been printed.
+func (p *pidCapture) PID() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.done {
+		return ""
+	}
+	if _, err := strconv.Atoi(string(p.line)); err != nil {
+		return ""
+	}
+	return string(p.line)
+}
+
+// fileUploader is implemented by transports that can copy files to the WordPress host themselves.
+type fileUploader interface {
+	Upload(ctx context.Context, localPath, remotePath string) error
+}
+
+// transportBlockNames lists the transports configured by a block of the same name.
+var transportBlockNames = []string{transportSSH, transportDocker, transportKubernetes, transportLocal}
+
+// transportBlocks reports which transport blocks are set, keyed by transport name.
+func transportBlocks(data WordpressProviderModel) map[string]bool {
+	return map[string]bool{
+		transportSSH:        data.SSH != nil,
+		transportDocker:     data.Docker != nil,
+		transportKubernetes: data.Kubernetes != nil,
+		transportLocal:      data.Local != nil,
+	}
+}
+
+// validateTransport checks that the settings required by the selected transport are present.
+func validateTransport(data WordpressProviderModel, diags *diag.Diagnostics) {
+	if data.Transport.IsUnknown() {
+		return
+	}
+	transport := defaultStringIfUnset(data.Transport, transportWPCLI)
+
+	blocks := transportBlocks(data)
+	for _, name := range transportBlockNames {
+		if blocks[name] && name != transport {
+			diags.AddAttributeError(path.Root(name), fmt.Sprintf("Unexpected %s block", name),
+				fmt.Sprintf("The %s block is only used when transport is %q.", name, name))
+		}
+	}
+
+	if transport == transportWPCLI {
+		if data.SSHTarget.IsNull() {
+			diags.AddAttributeError(path.Root("ssh_target"), "Missing ssh_target",
+				fmt.Sprintf("ssh_target is required when transport is %q.", transportWPCLI))
+		}
+		return
+	}
+
+	// The local block is optional since every setting in it has a default
+	if transport == transportLocal {
+		if !data.SSHTarget.IsNull() {
+			diags.AddAttributeError(path.Root("ssh_target"), "Unexpected ssh_target",
+				fmt.Sprintf("ssh_target cannot be used with transport %q, which runs wp on the machine Terraform runs on.", transportLocal))
+		}
+		if data.RemotePath.IsNull() {
+			diags.AddAttributeError(path.Root("remote_path"), "Missing remote_path",
+				fmt.Sprintf("remote_path is required when transport is %q.", transportLocal))
+		}
+		return
+	}
+
+	if !data.SSHTarget.IsNull() {
+		diags.AddAttributeError(path.Root("ssh_target"), "Unexpected ssh_target",
+			fmt.Sprintf("ssh_target cannot be used with transport %q. Configure the connection in the %s block instead.", transport, transport))
+	}
+	if set, hasBlock := blocks[transport]; hasBlock && !set {
+		diags.AddAttributeError(path.Root(transport), fmt.Sprintf("Missing %s block", transport),
+			fmt.Sprintf("The %s block is required when transport is %q.", transport, transport))
+	}
+}
+
+// newTransport returns the Commander for the selected transport, or nil to
+// run commands through cmdExec.
+func newTransport(data WordpressProviderModel, diags *diag.Diagnostics) Commander {
+	env := wpEnv(data.Env)
+
+	switch defaultStringIfUnset(data.Transport, transportWPCLI) {
+	case transportSSH:
+		t, err := newSSHTransport(data.SSH)
+		if err != nil {
+			diags.AddAttributeError(path.Root("ssh"), "Invalid SSH configuration", err.Error())
+			return nil
+		}
+		t.env = env
+		return t
+
+	case transportDocker:
+		t, err := newDockerTransport(data.Docker, defaultStringIfUnset(data.RemotePath, ""))
+		if err != nil {
+			diags.AddAttributeError(path.Root("docker"), "Invalid Docker configuration", err.Error())
+			return nil
+		}
+		t.env = env
+		return t
+
+	case transportKubernetes:
+		t, err := newKubernetesTransport(data.Kubernetes)
+		if err != nil {
+			diags.AddAttributeError(path.Root("kubernetes"), "Invalid Kubernetes configuration", err.Error())
+			return nil
+		}
+		t.env = env
+		return t
+
+	case transportLocal:
+		t, err := newLocalTransport(data.Local, defaultStringIfUnset(data.RemotePath, ""))
+		if err != nil {
+			diags.AddAttributeError(path.Root("local"), "Invalid local configuration", err.Error())
+			return nil
+		}
+		t.env = env
+		return t
+	}
+
+	if len(env) > 0 {
+		return defaultCommander{env: env}
+	}
+	return nil
+}
+
+// wpEnv returns the variables of the provider's env setting as NAME=value
+// pairs, sorted by name.
+func wpEnv(m types.Map) []string {
+	var env []string
+	for name, value := range m.Elements() {
+		if s, ok := value.(types.String); ok && !s.IsNull() && !s.IsUnknown() {
+			env = append(env, name+"="+s.ValueString())
+		}
+	}
+	sort.Strings(env)
+	return env
+}
+
+// envPrelude exports the NAME=value lines it reads from stdin up to an
+// empty line, then runs its arguments. sh reads a pipe a byte at a time, so
+// the rest of stdin is left to the command.
+const envPrelude = `while IFS= read -r v && [ -n "$v" ]; do export "$v"; done; exec "$@"`
+
+// envCommand returns argv run under envPrelude with the variables in env
+// set, and the stdin that carries them ahead of stdin, which may be nil.
+func envCommand(env []string, argv []string, stdin io.Reader) ([]string, io.Reader) {
+	variables := strings.NewReader(strings.Join(env, "\n") + "\n\n")
+	if stdin != nil {
+		stdin = io.MultiReader(variables, stdin)
+	} else {
+		stdin = variables
+	}
+	return append([]string{"sh", "-c", envPrelude, "sh"}, argv...), stdin
+}
+
+// withEnv returns the command that runs wp with the variables in env set,
+// and the stdin to run it with, for transports that start commands through
+// a shell or exec API that cannot set the environment itself. The values go
+// to the command's stdin rather than its command line, which other users of
+// the host can list. Other commands are unchanged.
+func withEnv(env []string, name string, args []string, stdin io.Reader) (string, []string, io.Reader) {
+	if name != "wp" || len(env) == 0 {
+		return name, args, stdin
+	}
+	argv, stdin := envCommand(env, append([]string{name}, args...), stdin)
+	return argv[0], argv[1:], stdin
+}
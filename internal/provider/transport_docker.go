@@ -0,0 +1,457 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// defaultDockerHost is the Engine API socket used when neither the docker
+// block nor DOCKER_HOST names one.
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+var (
+	_ Commander    = &dockerTransport{}
+	_ fileUploader = &dockerTransport{}
+)
+
+// dockerTransportModel is the provider's docker block.
+type dockerTransportModel struct {
+	Container types.String `tfsdk:"container"`
+	User      types.String `tfsdk:"user"`
+	Workdir   types.String `tfsdk:"workdir"`
+	Host      types.String `tfsdk:"host"`
+}
+
+// dockerTransportAttribute returns the schema of the provider's docker block.
+func dockerTransportAttribute() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Optional:            true,
+		MarkdownDescription: "Connection settings for the `docker` transport.",
+		Attributes: map[string]schema.Attribute{
+			"container": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The name or ID of the container WordPress runs in.",
+			},
+			"user": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The user to run `wp` as inside the container, e.g. `www-data`. Defaults to the container's user.",
+			},
+			"workdir": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The working directory for `wp` inside the container. Defaults to `remote_path`.",
+			},
+			"host": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The Docker Engine API address, e.g. `unix:///var/run/docker.sock` or `tcp://127.0.0.1:2375`. Defaults to `DOCKER_HOST`, then to the local socket. As with the docker CLI, `DOCKER_TLS_VERIFY` and `DOCKER_CERT_PATH` connect to `tcp://` and `https://` addresses over TLS with the `ca.pem`, `cert.pem` and `key.pem` in `DOCKER_CERT_PATH` (default `~/.docker`).",
+			},
+		},
+	}
+}
+
+// dockerTransport runs commands inside a container through the Docker Engine API.
+type dockerTransport struct {
+	client    *http.Client
+	endpoint  string
+	container string
+	user      string
+	workdir   string
+
+	// env holds NAME=value pairs set for wp commands.
+	env []string
+}
+
+// newDockerTransport prepares an Engine API client for the docker block.
+func newDockerTransport(m *dockerTransportModel, remotePath string) (*dockerTransport, error) {
+	container := defaultStringIfUnset(m.Container, "")
+	if container == "" {
+		return nil, fmt.Errorf("docker container must not be empty")
+	}
+
+	host := defaultStringIfUnset(m.Host, os.Getenv("DOCKER_HOST"))
+	if host == "" {
+		host = defaultDockerHost
+	}
+	tlsConfig, err := dockerTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, endpoint, err := newDockerClient(host, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dockerTransport{
+		client:    client,
+		endpoint:  endpoint,
+		container: container,
+		user:      defaultStringIfUnset(m.User, ""),
+		workdir:   defaultStringIfUnset(m.Workdir, remotePath),
+	}, nil
+}
+
+// dockerIdleConns is how many idle Engine API connections are kept for reuse,
+// enough for Terraform's default parallelism.
+const dockerIdleConns = 10
+
+// dockerHTTPTransport returns a TCP transport that keeps dockerIdleConns
+// connections for reuse.
+func dockerHTTPTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = dockerIdleConns
+	return transport
+}
+
+// dockerTLSConfig returns the client TLS settings named by DOCKER_TLS_VERIFY
+// and DOCKER_CERT_PATH, or nil when neither is set. Like the docker CLI it
+// reads ca.pem, cert.pem and key.pem from DOCKER_CERT_PATH, defaulting to
+// ~/.docker; ca.pem is only required when DOCKER_TLS_VERIFY is set.
+func dockerTLSConfig() (*tls.Config, error) {
+	verify := os.Getenv("DOCKER_TLS_VERIFY") != ""
+	certPath := os.Getenv("DOCKER_CERT_PATH")
+	if !verify && certPath == "" {
+		return nil, nil
+	}
+	if certPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to find the docker certificates: DOCKER_CERT_PATH is not set: %w", err)
+		}
+		certPath = filepath.Join(home, ".docker")
+	}
+
+	cert, err := tls.LoadX509KeyPair(filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the docker client certificate from %s: %w", certPath, err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	ca, err := os.ReadFile(filepath.Join(certPath, "ca.pem"))
+	if errors.Is(err, os.ErrNotExist) && !verify {
+		// Verify the daemon against the system roots instead
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the docker CA certificate from %s: %w", certPath, err)
+	}
+	config.RootCAs = x509.NewCertPool()
+	if !config.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("unable to load the docker CA certificate from %s: no certificates found in ca.pem", certPath)
+	}
+	return config, nil
+}
+
+// newDockerClient returns an HTTP client and base URL for a DOCKER_HOST style
+// address. When tlsConfig is set, tcp:// addresses are reached over TLS.
+func newDockerClient(host string, tlsConfig *tls.Config) (*http.Client, string, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+			// Keep a connection per concurrent Terraform operation
+			MaxIdleConnsPerHost: dockerIdleConns,
+		}
+		return &http.Client{Transport: transport}, "http://docker", nil
+	case "tcp", "http", "https":
+		transport := dockerHTTPTransport()
+		if tlsConfig == nil && u.Scheme != "https" {
+			return &http.Client{Transport: transport}, "http://" + u.Host, nil
+		}
+		transport.TLSClientConfig = tlsConfig
+		return &http.Client{Transport: transport}, "https://" + u.Host, nil
+	}
+	return nil, "", fmt.Errorf("unsupported docker host %q: expected a unix://, tcp:// or https:// address", host)
+}
+
+// dockerExitError reports a command that exited with a non-zero status inside the container.
+type dockerExitError struct {
+	ExitCode int
+}
+
+func (e *dockerExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.ExitCode)
+}
+
+// ExitStatus returns the exit code of the command.
+func (e *dockerExitError) ExitStatus() int {
+	return e.ExitCode
+}
+
+// CombinedOutput runs the command in the container and returns combined
+// stdout and stderr. The process is killed when the context is done.
+func (t *dockerTransport) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var output bytes.Buffer
+	err := t.exec(ctx, name, args, &output, &output)
+	return output.Bytes(), err
+}
+
+// Output runs the command in the container and returns stdout and stderr
+// separately.
+func (t *dockerTransport) Output(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	var stdout, stderr bytes.Buffer
+	err := t.exec(ctx, name, args, &stdout, &stderr)
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// exec runs the command in the container, copying its output to stdout and
+// stderr.
+func (t *dockerTransport) exec(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error {
+	var env []string
+	if name == "wp" {
+		env = t.env
+	}
+	stdin := commandStdin(ctx)
+	execID, err := t.createExec(ctx, wrapWithPID(name, args), env, stdin != nil)
+	if err != nil {
+		return err
+	}
+	tflog.SubsystemTrace(ctx, logTransport, "Created Docker exec", map[string]interface{}{
+		logFieldTarget: t.target(),
+		"exec_id":      execID,
+	})
+
+	resp, err := t.startExec(ctx, execID, stdin != nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if stdin != nil {
+		// The upgraded connection carries stdin; commands read it up to the
+		// newline they expect rather than waiting for it to be closed
+		conn, ok := resp.Body.(io.Writer)
+		if !ok {
+			return fmt.Errorf("docker API did not upgrade the exec connection for stdin")
+		}
+		go func() { _, _ = io.Copy(conn, stdin) }()
+	}
+
+	capture := &pidCapture{w: stdout}
+	err = demuxDockerStream(resp.Body, capture, stderr)
+	if ctx.Err() != nil {
+		if pid := capture.PID(); pid != "" {
+			t.kill(pid)
+		}
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("reading output from container %s: %w", t.container, err)
+	}
+
+	exitCode, err := t.exitCode(ctx, execID)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return &dockerExitError{ExitCode: exitCode}
+	}
+	return nil
+}
+
+// target names the container commands run in.
+func (t *dockerTransport) target() string {
+	return "docker:" + t.container
+}
+
+// kill stops a process started by CombinedOutput after its context ended.
+func (t *dockerTransport) kill(pid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandWaitDelay)
+	defer cancel()
+
+	execID, err := t.createExec(ctx, []string{"kill", "-KILL", pid}, nil, false)
+	if err != nil {
+		return
+	}
+	body, _ := json.Marshal(map[string]bool{"Detach": true})
+	if resp, err := t.request(ctx, http.MethodPost, "/exec/"+execID+"/start", "application/json", bytes.NewReader(body)); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// Upload copies a local file into the container with the archive API.
+func (t *dockerTransport) Upload(ctx context.Context, localPath, remotePath string) error {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	if err := tw.WriteHeader(&tar.Header{Name: path.Base(remotePath), Mode: 0o644, Size: int64(len(data))}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	query := url.Values{"path": {path.Dir(remotePath)}}
+	resp, err := t.request(ctx, http.MethodPut, "/containers/"+url.PathEscape(t.container)+"/archive?"+query.Encode(), "application/x-tar", &archive)
+	if err != nil {
+		return fmt.Errorf("uploading %s to container %s failed: %w", localPath, t.container, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// createExec creates an exec instance for cmd and returns its ID.
+func (t *dockerTransport) createExec(ctx context.Context, cmd, env []string, attachStdin bool) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"AttachStdin":  attachStdin,
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Tty":          false,
+		"Cmd":          cmd,
+		"Env":          env,
+		"User":         t.user,
+		"WorkingDir":   t.workdir,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := t.request(ctx, http.MethodPost, "/containers/"+url.PathEscape(t.container)+"/exec", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("unable to exec in container %s: %w", t.container, err)
+	}
+	defer resp.Body.Close()
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("unable to parse exec response: %w", err)
+	}
+	return created.ID, nil
+}
+
+// startExec starts an exec instance and returns the response streaming its
+// output. With stdin the connection is upgraded so that the response body
+// can also be written to.
+func (t *dockerTransport) startExec(ctx context.Context, execID string, stdin bool) (*http.Response, error) {
+	apiPath := "/exec/" + execID + "/start"
+	body, _ := json.Marshal(map[string]bool{"Detach": false, "Tty": false})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+apiPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if stdin {
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "tcp")
+	}
+	return t.do(req, apiPath)
+}
+
+// exitCode returns the exit code of a finished exec instance.
+func (t *dockerTransport) exitCode(ctx context.Context, execID string) (int, error) {
+	resp, err := t.request(ctx, http.MethodGet, "/exec/"+execID+"/json", "", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var inspect struct {
+		ExitCode int
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return 0, fmt.Errorf("unable to parse exec status: %w", err)
+	}
+	return inspect.ExitCode, nil
+}
+
+// request sends an Engine API request and converts error responses into errors.
+func (t *dockerTransport) request(ctx context.Context, method, apiPath, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.endpoint+apiPath, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return t.do(req, apiPath)
+}
+
+// do sends req to the Engine API path apiPath and converts error responses
+// into errors.
+func (t *dockerTransport) do(req *http.Request, apiPath string) (*http.Response, error) {
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, fmt.Errorf("docker API %s %s: %s: %s", req.Method, strings.SplitN(apiPath, "?", 2)[0], resp.Status, apiErr.Message)
+	}
+	return resp, nil
+}
+
+// demuxDockerStream copies a multiplexed exec stream to stdout and stderr.
+func demuxDockerStream(stream io.Reader, stdout, stderr io.Writer) error {
+	reader := bufio.NewReader(stream)
+	header := make([]byte, 8)
+
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		frame := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return err
+		}
+
+		// Stream type 1 is stdout, 2 is stderr
+		w := stderr
+		if header[0] == 1 {
+			w = stdout
+		}
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,529 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDockerExec is an exec instance created on the fake Engine API.
+type fakeDockerExec struct {
+	Cmd         []string
+	Env         []string
+	User        string
+	WorkingDir  string
+	AttachStdin bool
+	exitCode    int
+	// stdin is the first line written to the exec's stdin
+	stdin string
+}
+
+// fakeDockerAPI is a minimal Docker Engine API serving exec and archive requests.
+type fakeDockerAPI struct {
+	container string
+	// run returns stdout, stderr and the exit code for a command; block
+	// makes it wait until the client goes away.
+	run func(cmd []string) (stdout, stderr string, exitCode int, block bool)
+
+	mu      sync.Mutex
+	execs   map[string]*fakeDockerExec
+	order   []string
+	files   map[string]string
+	started []string
+}
+
+func newFakeDockerAPI(container string) *fakeDockerAPI {
+	return &fakeDockerAPI{
+		container: container,
+		execs:     map[string]*fakeDockerExec{},
+		files:     map[string]string{},
+		run: func(cmd []string) (string, string, int, bool) {
+			return "ran: " + strings.Join(cmd, " "), "", 0, false
+		},
+	}
+}
+
+func writeDockerFrame(w io.Writer, stream byte, data string) {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	_, _ = w.Write(header)
+	_, _ = io.WriteString(w, data)
+}
+
+func (f *fakeDockerAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(parts) == 3 && parts[0] == "containers" && parts[2] == "exec" && r.Method == http.MethodPost:
+		if parts[1] != f.container {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"message":"No such container: %s"}`, parts[1])
+			return
+		}
+		var exec fakeDockerExec
+		_ = json.NewDecoder(r.Body).Decode(&exec)
+		f.mu.Lock()
+		id := fmt.Sprintf("exec-%d", len(f.order)+1)
+		f.execs[id] = &exec
+		f.order = append(f.order, id)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Id":%q}`, id)
+
+	case len(parts) == 3 && parts[0] == "exec" && parts[2] == "start" && r.Method == http.MethodPost:
+		f.mu.Lock()
+		exec := f.execs[parts[1]]
+		f.started = append(f.started, parts[1])
+		f.mu.Unlock()
+
+		var start struct{ Detach bool }
+		_ = json.NewDecoder(r.Body).Decode(&start)
+		if start.Detach {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		// Strip the PID wrapper: /bin/sh -c <script> sh <cmd...>
+		cmd := exec.Cmd[4:]
+		if r.Header.Get("Upgrade") == "tcp" {
+			f.startAttached(w, exec, cmd)
+			return
+		}
+		stdout, stderr, exitCode, block := f.run(cmd)
+		exec.exitCode = exitCode
+
+		w.Header().Set("Content-Type", "application/vnd.docker.multiplexed-stream")
+		w.WriteHeader(http.StatusOK)
+		writeDockerFrame(w, 1, "4242\n")
+		w.(http.Flusher).Flush()
+		if block {
+			<-r.Context().Done()
+			return
+		}
+		if stdout != "" {
+			writeDockerFrame(w, 1, stdout)
+		}
+		if stderr != "" {
+			writeDockerFrame(w, 2, stderr)
+		}
+
+	case len(parts) == 3 && parts[0] == "exec" && parts[2] == "json":
+		f.mu.Lock()
+		exec := f.execs[parts[1]]
+		f.mu.Unlock()
+		fmt.Fprintf(w, `{"ExitCode":%d,"Running":false}`, exec.exitCode)
+
+	case len(parts) == 3 && parts[0] == "containers" && parts[2] == "archive" && r.Method == http.MethodPut:
+		dir := r.URL.Query().Get("path")
+		tr := tar.NewReader(r.Body)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				break
+			}
+			data, _ := io.ReadAll(tr)
+			f.mu.Lock()
+			f.files[dir+"/"+hdr.Name] = string(data)
+			f.mu.Unlock()
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"page not found"}`)
+	}
+}
+
+// startAttached serves an exec start that upgraded the connection to carry
+// stdin, reading one line of it before running the command.
+func (f *fakeDockerAPI) startAttached(w http.ResponseWriter, exec *fakeDockerExec, cmd []string) {
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprint(rw, "HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.multiplexed-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	writeDockerFrame(rw, 1, "4242\n")
+	_ = rw.Flush()
+
+	line, _ := rw.ReadString('\n')
+	stdout, stderr, exitCode, _ := f.run(cmd)
+	f.mu.Lock()
+	exec.stdin = line
+	exec.exitCode = exitCode
+	f.mu.Unlock()
+	if stdout != "" {
+		writeDockerFrame(rw, 1, stdout)
+	}
+	if stderr != "" {
+		writeDockerFrame(rw, 2, stderr)
+	}
+	_ = rw.Flush()
+}
+
+func (f *fakeDockerAPI) exec(i int) fakeDockerExec {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.execs[f.order[i]]
+}
+
+// newTestDockerTransport starts the fake API and returns a transport connected to it over TCP.
+func newTestDockerTransport(t *testing.T, api *fakeDockerAPI, m *dockerTransportModel) *dockerTransport {
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	// The fake API speaks plain HTTP, whatever the developer's environment
+	t.Setenv("DOCKER_TLS_VERIFY", "")
+	t.Setenv("DOCKER_CERT_PATH", "")
+
+	m.Host = types.StringValue("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	transport, err := newDockerTransport(m, "/var/www/html")
+	require.NoError(t, err)
+	return transport
+}
+
+func TestDockerTransport_CombinedOutput(t *testing.T) {
+	api := newFakeDockerAPI("wordpress")
+	transport := newTestDockerTransport(t, api, &dockerTransportModel{
+		Container: types.StringValue("wordpress"),
+		User:      types.StringValue("www-data"),
+	})
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "--path=/var/www/html", "plugin", "list")
+	require.NoError(t, err)
+	assert.Equal(t, "ran: wp --path=/var/www/html plugin list", string(output))
+
+	exec := api.exec(0)
+	assert.Equal(t, []string{"/bin/sh", "-c", pidWrapper, "sh", "wp", "--path=/var/www/html", "plugin", "list"}, exec.Cmd)
+	assert.Equal(t, "www-data", exec.User)
+	assert.Equal(t, "/var/www/html", exec.WorkingDir)
+	assert.Empty(t, exec.Env)
+}
+
+func TestDockerTransport_Env(t *testing.T) {
+	api := newFakeDockerAPI("wordpress")
+	transport := newTestDockerTransport(t, api, &dockerTransportModel{Container: types.StringValue("wordpress")})
+	transport.env = []string{"WP_CLI_CACHE_DIR=/tmp/wp-cli"}
+
+	_, err := transport.CombinedOutput(context.Background(), "wp", "plugin", "list")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"WP_CLI_CACHE_DIR=/tmp/wp-cli"}, api.exec(0).Env)
+	assert.Equal(t, []string{"/bin/sh", "-c", pidWrapper, "sh", "wp", "plugin", "list"}, api.exec(0).Cmd)
+
+	_, err = transport.CombinedOutput(context.Background(), "rm", "-f", "/tmp/plugin.zip")
+	require.NoError(t, err)
+	assert.Empty(t, api.exec(1).Env)
+}
+
+func TestDockerTransport_Stdin(t *testing.T) {
+	api := newFakeDockerAPI("wordpress")
+	transport := newTestDockerTransport(t, api, &dockerTransportModel{Container: types.StringValue("wordpress")})
+
+	ctx := withStdin(context.Background(), []byte("s3cret\n"))
+	stdout, _, err := transport.Output(ctx, "wp", "eval", "echo 1;")
+	require.NoError(t, err)
+	assert.Equal(t, "ran: wp eval echo 1;", string(stdout))
+	assert.True(t, api.exec(0).AttachStdin)
+	assert.Equal(t, "s3cret\n", api.exec(0).stdin)
+
+	// Commands without input do not attach stdin
+	_, _, err = transport.Output(context.Background(), "wp", "option", "get", "home")
+	require.NoError(t, err)
+	assert.False(t, api.exec(1).AttachStdin)
+}
+
+func TestDockerTransport_ExitCode(t *testing.T) {
+	api := newFakeDockerAPI("wordpress")
+	api.run = func(cmd []string) (string, string, int, bool) {
+		return "partial output\n", "Error: The 'missing' plugin could not be found.", 1, false
+	}
+	transport := newTestDockerTransport(t, api, &dockerTransportModel{
+		Container: types.StringValue("wordpress"),
+		Workdir:   types.StringValue("/srv/wordpress"),
+	})
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "plugin", "activate", "missing")
+	var exitErr *dockerExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 1, exitErr.ExitCode)
+	assert.Equal(t, "partial output\nError: The 'missing' plugin could not be found.", string(output))
+	assert.Equal(t, "/srv/wordpress", api.exec(0).WorkingDir)
+}
+
+func TestDockerTransport_Output(t *testing.T) {
+	api := newFakeDockerAPI("wordpress")
+	api.run = func(cmd []string) (string, string, int, bool) {
+		return "partial output\n", "Error: The 'missing' plugin could not be found.", 1, false
+	}
+	transport := newTestDockerTransport(t, api, &dockerTransportModel{Container: types.StringValue("wordpress")})
+
+	stdout, stderr, err := transport.Output(context.Background(), "wp", "plugin", "activate", "missing")
+	assert.Equal(t, 1, exitCode(err))
+	assert.Equal(t, "partial output\n", string(stdout))
+	assert.Equal(t, "Error: The 'missing' plugin could not be found.", string(stderr))
+}
+
+func TestDockerTransport_MissingContainer(t *testing.T) {
+	api := newFakeDockerAPI("wordpress")
+	transport := newTestDockerTransport(t, api, &dockerTransportModel{Container: types.StringValue("other")})
+
+	_, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	assert.ErrorContains(t, err, "unable to exec in container other")
+	assert.ErrorContains(t, err, "No such container: other")
+}
+
+func TestDockerTransport_CancelKillsCommand(t *testing.T) {
+	api := newFakeDockerAPI("wordpress")
+	api.run = func(cmd []string) (string, string, int, bool) {
+		return "", "", 0, cmd[0] == "wp"
+	}
+	transport := newTestDockerTransport(t, api, &dockerTransportModel{Container: types.StringValue("wordpress")})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := transport.CombinedOutput(ctx, "wp", "plugin", "install", "akismet")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.Len(t, api.order, 2)
+	assert.Equal(t, []string{"kill", "-KILL", "4242"}, api.exec(1).Cmd)
+	assert.Equal(t, []string{"exec-1", "exec-2"}, api.started)
+}
+
+func TestDockerTransport_Upload(t *testing.T) {
+	api := newFakeDockerAPI("wordpress")
+	transport := newTestDockerTransport(t, api, &dockerTransportModel{Container: types.StringValue("wordpress")})
+
+	local := filepath.Join(t.TempDir(), "plugin.zip")
+	require.NoError(t, os.WriteFile(local, []byte("zip data"), 0o600))
+
+	require.NoError(t, transport.Upload(context.Background(), local, "/tmp/terraform-wordpress-1.zip"))
+	assert.Equal(t, map[string]string{"/tmp/terraform-wordpress-1.zip": "zip data"}, api.files)
+}
+
+func TestDockerTransport_UnixSocket(t *testing.T) {
+	api := newFakeDockerAPI("wordpress")
+
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(api)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	t.Setenv("DOCKER_HOST", "unix://"+socket)
+	t.Setenv("DOCKER_TLS_VERIFY", "")
+	t.Setenv("DOCKER_CERT_PATH", "")
+	transport, err := newDockerTransport(&dockerTransportModel{Container: types.StringValue("wordpress")}, "/var/www/html")
+	require.NoError(t, err)
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "ran: wp cli version", string(output))
+}
+
+// writeDockerCerts writes a CA and a client certificate signed by it to dir
+// as ca.pem, cert.pem and key.pem, and returns the TLS settings of a daemon
+// with a server certificate from the same CA that requires the client
+// certificate.
+func writeDockerCerts(t *testing.T, dir string) *tls.Config {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "docker test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	// issue returns a PEM certificate and key signed by the CA
+	issue := func(serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "docker test"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	clientCert, clientKey := issue(2, x509.ExtKeyUsageClientAuth)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cert.pem"), clientCert, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.pem"), clientKey, 0o600))
+
+	serverCert, err := tls.X509KeyPair(issue(3, x509.ExtKeyUsageServerAuth))
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+}
+
+func TestDockerTransport_TLS(t *testing.T) {
+	certPath := t.TempDir()
+	api := newFakeDockerAPI("wordpress")
+	server := httptest.NewUnstartedServer(api)
+	server.TLS = writeDockerCerts(t, certPath)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	host := "tcp://" + strings.TrimPrefix(server.URL, "https://")
+
+	t.Setenv("DOCKER_HOST", host)
+	t.Setenv("DOCKER_TLS_VERIFY", "1")
+	t.Setenv("DOCKER_CERT_PATH", certPath)
+	transport, err := newDockerTransport(&dockerTransportModel{Container: types.StringValue("wordpress")}, "/var/www/html")
+	require.NoError(t, err)
+	assert.Equal(t, "https://"+strings.TrimPrefix(host, "tcp://"), transport.endpoint)
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "ran: wp cli version", string(output))
+
+	ctx := withStdin(context.Background(), []byte("s3cret\n"))
+	_, _, err = transport.Output(ctx, "wp", "eval", "echo 1;")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret\n", api.exec(1).stdin)
+}
+
+func TestDockerTLSConfig(t *testing.T) {
+	t.Setenv("DOCKER_TLS_VERIFY", "")
+	t.Setenv("DOCKER_CERT_PATH", "")
+	config, err := dockerTLSConfig()
+	require.NoError(t, err)
+	assert.Nil(t, config)
+
+	certPath := t.TempDir()
+	writeDockerCerts(t, certPath)
+	t.Setenv("DOCKER_CERT_PATH", certPath)
+	config, err = dockerTLSConfig()
+	require.NoError(t, err)
+	assert.Len(t, config.Certificates, 1)
+	assert.NotNil(t, config.RootCAs)
+
+	// Without DOCKER_TLS_VERIFY, a missing ca.pem falls back to the system roots
+	require.NoError(t, os.Remove(filepath.Join(certPath, "ca.pem")))
+	config, err = dockerTLSConfig()
+	require.NoError(t, err)
+	assert.Len(t, config.Certificates, 1)
+	assert.Nil(t, config.RootCAs)
+
+	t.Setenv("DOCKER_TLS_VERIFY", "1")
+	_, err = dockerTLSConfig()
+	assert.ErrorContains(t, err, "unable to load the docker CA certificate from "+certPath)
+
+	t.Setenv("DOCKER_CERT_PATH", t.TempDir())
+	_, err = dockerTLSConfig()
+	assert.ErrorContains(t, err, "unable to load the docker client certificate")
+
+	// DOCKER_CERT_PATH defaults to ~/.docker
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("DOCKER_CERT_PATH", "")
+	_, err = dockerTLSConfig()
+	assert.ErrorContains(t, err, "unable to load the docker client certificate from "+filepath.Join(home, ".docker"))
+
+	_, err = newDockerTransport(&dockerTransportModel{Container: types.StringValue("wordpress"), Host: types.StringValue("tcp://127.0.0.1:2376")}, "")
+	assert.ErrorContains(t, err, "unable to load the docker client certificate")
+}
+
+func TestNewDockerClient(t *testing.T) {
+	cases := map[string]string{
+		"unix:///var/run/docker.sock": "http://docker",
+		"tcp://127.0.0.1:2375":        "http://127.0.0.1:2375",
+		"https://docker.example.com":  "https://docker.example.com",
+	}
+	for host, endpoint := range cases {
+		_, actual, err := newDockerClient(host, nil)
+		assert.NoError(t, err, host)
+		assert.Equal(t, endpoint, actual, host)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	tlsCases := map[string]string{
+		"unix:///var/run/docker.sock": "http://docker",
+		"tcp://127.0.0.1:2376":        "https://127.0.0.1:2376",
+		"https://docker.example.com":  "https://docker.example.com",
+	}
+	for host, endpoint := range tlsCases {
+		client, actual, err := newDockerClient(host, tlsConfig)
+		assert.NoError(t, err, host)
+		assert.Equal(t, endpoint, actual, host)
+		if transport, ok := client.Transport.(*http.Transport); ok && actual != "http://docker" {
+			assert.Same(t, tlsConfig, transport.TLSClientConfig, host)
+		}
+	}
+
+	_, _, err := newDockerClient("ssh://docker.example.com", nil)
+	assert.ErrorContains(t, err, "unsupported docker host")
+
+	_, err = newDockerTransport(&dockerTransportModel{Container: types.StringValue("")}, "")
+	assert.ErrorContains(t, err, "docker container must not be empty")
+}
+
+func TestDemuxDockerStream(t *testing.T) {
+	var stream bytes.Buffer
+	writeDockerFrame(&stream, 1, "first ")
+	writeDockerFrame(&stream, 2, "warning ")
+	writeDockerFrame(&stream, 1, "second")
+
+	var stdout, stderr bytes.Buffer
+	assert.NoError(t, demuxDockerStream(&stream, &stdout, &stderr))
+	assert.Equal(t, "first second", stdout.String())
+	assert.Equal(t, "warning ", stderr.String())
+
+	// A truncated frame is an error
+	stream.Reset()
+	stream.Write([]byte{1, 0, 0, 0, 0, 0, 0, 9, 'a'})
+	assert.Error(t, demuxDockerStream(&stream, &stdout, &stderr))
+}
@@ -0,0 +1,327 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+var (
+	_ Commander    = &kubernetesTransport{}
+	_ fileUploader = &kubernetesTransport{}
+)
+
+// kubernetesTransportModel is the provider's kubernetes block.
+type kubernetesTransportModel struct {
+	Kubeconfig    types.String `tfsdk:"kubeconfig"`
+	Context       types.String `tfsdk:"context"`
+	Namespace     types.String `tfsdk:"namespace"`
+	Pod           types.String `tfsdk:"pod"`
+	LabelSelector types.String `tfsdk:"label_selector"`
+	Container     types.String `tfsdk:"container"`
+}
+
+// kubernetesTransportAttribute returns the schema of the provider's kubernetes block.
+func kubernetesTransportAttribute() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Optional:            true,
+		MarkdownDescription: "Connection settings for the `kubernetes` transport.",
+		Attributes: map[string]schema.Attribute{
+			"kubeconfig": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The path to the kubeconfig file. Defaults to `KUBECONFIG`, then `~/.kube/config`, then the in-cluster service account.",
+			},
+			"context": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The kubeconfig context to use. Defaults to the current context.",
+			},
+			"namespace": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The namespace of the WordPress pods. Defaults to the context's namespace.",
+			},
+			"pod": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The name of the pod to run `wp` in. Exactly one of `pod` and `label_selector` must be set.",
+				Validators: []validator.String{
+					stringvalidator.ExactlyOneOf(path.MatchRelative().AtParent().AtName("label_selector")),
+				},
+			},
+			"label_selector": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "A label selector matching the WordPress pods, e.g. `app.kubernetes.io/name=wordpress`. Commands run in one ready pod.",
+			},
+			"container": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The container to run `wp` in. Defaults to the pod's only or default container.",
+			},
+		},
+	}
+}
+
+// kubernetesTransport runs commands in a WordPress pod through the pod exec API.
+type kubernetesTransport struct {
+	config        *rest.Config
+	client        corev1client.CoreV1Interface
+	namespace     string
+	pod           string
+	labelSelector string
+	container     string
+
+	// env holds NAME=value pairs set for wp commands. The exec API cannot
+	// set them, so commands run under env.
+	env []string
+
+	// mu guards selected, the pod chosen by label selector. It is kept
+	// until a command fails to reach it.
+	mu       sync.Mutex
+	selected string
+}
+
+// newKubernetesTransport loads the kubeconfig and prepares an API client.
+func newKubernetesTransport(m *kubernetesTransportModel) (*kubernetesTransport, error) {
+	pod := defaultStringIfUnset(m.Pod, "")
+	selector := defaultStringIfUnset(m.LabelSelector, "")
+	if (pod == "") == (selector == "") {
+		return nil, fmt.Errorf("exactly one of pod and label_selector must be set")
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig := defaultStringIfUnset(m.Kubeconfig, ""); kubeconfig != "" {
+		rules.ExplicitPath = kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: defaultStringIfUnset(m.Context, ""),
+	}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load Kubernetes configuration: %w", err)
+	}
+	namespace := defaultStringIfUnset(m.Namespace, "")
+	if namespace == "" {
+		if namespace, _, err = clientConfig.Namespace(); err != nil {
+			return nil, fmt.Errorf("unable to determine Kubernetes namespace: %w", err)
+		}
+	}
+	return newKubernetesTransportForConfig(config, namespace, pod, selector, defaultStringIfUnset(m.Container, ""))
+}
+
+// newKubernetesTransportForConfig builds the transport for an already loaded REST config.
+func newKubernetesTransportForConfig(config *rest.Config, namespace, pod, selector, container string) (*kubernetesTransport, error) {
+	client, err := corev1client.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Kubernetes client: %w", err)
+	}
+	return &kubernetesTransport{
+		config:        config,
+		client:        client,
+		namespace:     namespace,
+		pod:           pod,
+		labelSelector: selector,
+		container:     container,
+	}, nil
+}
+
+// targetPod returns the pod to run commands in: the configured pod, or the
+// first ready pod matching the label selector. The selected pod is reused by
+// later commands.
+func (t *kubernetesTransport) targetPod(ctx context.Context) (string, error) {
+	if t.pod != "" {
+		return t.pod, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.selected != "" {
+		return t.selected, nil
+	}
+
+	pods, err := t.client.Pods(t.namespace).List(ctx, metav1.ListOptions{LabelSelector: t.labelSelector})
+	if err != nil {
+		return "", fmt.Errorf("unable to list pods matching %q in namespace %s: %w", t.labelSelector, t.namespace, err)
+	}
+
+	var ready []string
+	for _, pod := range pods.Items {
+		if podReady(&pod) {
+			ready = append(ready, pod.Name)
+		}
+	}
+	if len(ready) == 0 {
+		return "", fmt.Errorf("no ready pod matches %q in namespace %s", t.labelSelector, t.namespace)
+	}
+	sort.Strings(ready)
+	t.selected = ready[0]
+	tflog.SubsystemDebug(ctx, logTransport, "Selected pod", map[string]interface{}{
+		logFieldTarget: t.target(),
+		"pod":          t.selected,
+	})
+	return t.selected, nil
+}
+
+// target names the pod, or the label selector choosing it, commands run in.
+func (t *kubernetesTransport) target() string {
+	if t.pod != "" {
+		return t.namespace + "/" + t.pod
+	}
+	return t.namespace + "/" + t.labelSelector
+}
+
+// forgetPod drops the selected pod so that the next command selects a pod
+// again, e.g. after the pod was replaced by a rollout.
+func (t *kubernetesTransport) forgetPod(pod string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.selected == pod {
+		t.selected = ""
+	}
+}
+
+// podReady reports whether a pod is running, not terminating and passes its readiness checks.
+func podReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// CombinedOutput runs the command in the pod and returns combined stdout and
+// stderr. The process is killed when the context is done.
+func (t *kubernetesTransport) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var output syncBuffer
+	err := t.exec(ctx, name, args, &output, &output)
+	return output.Bytes(), err
+}
+
+// Output runs the command in the pod and returns stdout and stderr
+// separately.
+func (t *kubernetesTransport) Output(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	var stdout, stderr syncBuffer
+	err := t.exec(ctx, name, args, &stdout, &stderr)
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// exec runs the command in the target pod, copying its output to stdout and
+// stderr.
+func (t *kubernetesTransport) exec(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error {
+	pod, err := t.targetPod(ctx)
+	if err != nil {
+		return err
+	}
+
+	name, args, stdin := withEnv(t.env, name, args, commandStdin(ctx))
+	capture := &pidCapture{w: stdout}
+	err = t.stream(ctx, pod, wrapWithPID(name, args), stdin, capture, stderr)
+	if ctx.Err() != nil {
+		if pid := capture.PID(); pid != "" {
+			t.kill(pod, pid)
+		}
+		return ctx.Err()
+	}
+	// Anything but the command's own exit status may mean the pod is gone
+	var exitErr utilexec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		tflog.SubsystemDebug(ctx, logTransport, "Unable to run command in pod, selecting a pod again", map[string]interface{}{
+			logFieldTarget: t.target(),
+			"pod":          pod,
+			logFieldError:  err.Error(),
+		})
+		t.forgetPod(pod)
+	}
+	return err
+}
+
+// kill stops a process started by CombinedOutput after its context ended.
+func (t *kubernetesTransport) kill(pod, pid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandWaitDelay)
+	defer cancel()
+	_ = t.stream(ctx, pod, []string{"kill", "-KILL", pid}, nil, io.Discard, io.Discard)
+}
+
+// Upload streams a local file to remotePath in the pod.
+func (t *kubernetesTransport) Upload(ctx context.Context, localPath, remotePath string) error {
+	pod, err := t.targetPod(ctx)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var output syncBuffer
+	cmd := []string{"/bin/sh", "-c", `cat > "$0"`, remotePath}
+	if err := t.stream(ctx, pod, cmd, file, &output, &output); err != nil {
+		return fmt.Errorf("uploading %s to pod %s failed: %v: %s", localPath, pod, err, output.Bytes())
+	}
+	return nil
+}
+
+// stream runs cmd in the pod over the exec subresource.
+func (t *kubernetesTransport) stream(ctx context.Context, pod string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	req := t.client.RESTClient().Post().
+		Resource("pods").
+		Namespace(t.namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: t.container,
+			Command:   cmd,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	// API servers before 1.29, or with the WebSocket exec feature gate off,
+	// only speak SPDY, so fall back to it as kubectl does
+	websocketExecutor, err := remotecommand.NewWebSocketExecutor(t.config, "GET", req.URL().String())
+	if err != nil {
+		return fmt.Errorf("unable to exec in pod %s: %w", pod, err)
+	}
+	spdyExecutor, err := remotecommand.NewSPDYExecutor(t.config, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("unable to exec in pod %s: %w", pod, err)
+	}
+	executor, err := remotecommand.NewFallbackExecutor(websocketExecutor, spdyExecutor, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to exec in pod %s: %w", pod, err)
+	}
+	return executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+}
@@ -0,0 +1,517 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/apimachinery/pkg/util/httpstream/wsstream"
+	remotecommandconsts "k8s.io/apimachinery/pkg/util/remotecommand"
+	"k8s.io/client-go/rest"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// fakeKubernetesExec is an exec request received by the fake API server.
+type fakeKubernetesExec struct {
+	Namespace string
+	Pod       string
+	Container string
+	Command   []string
+	Stdin     string
+	// Protocol is the negotiated streaming protocol.
+	Protocol string
+}
+
+// fakeKubernetesAPI is a minimal Kubernetes API serving pod lists and the
+// exec subresource over the v5 WebSocket protocol, or over SPDY only when
+// spdyOnly is set, like API servers before 1.29.
+type fakeKubernetesAPI struct {
+	pods     []corev1.Pod
+	spdyOnly bool
+	// run returns stdout, stderr and the exit code for a command; block
+	// makes it wait until a kill command arrives.
+	run func(cmd []string) (stdout, stderr string, exitCode int, block bool)
+
+	mu      sync.Mutex
+	execs   []fakeKubernetesExec
+	killed  chan struct{}
+	selects []string
+	// deleted pods are listed no more and reject exec requests.
+	deleted map[string]bool
+}
+
+func newFakeKubernetesAPI(pods ...corev1.Pod) *fakeKubernetesAPI {
+	return &fakeKubernetesAPI{
+		pods:   pods,
+		killed: make(chan struct{}),
+		run: func(cmd []string) (string, string, int, bool) {
+			return "ran: " + strings.Join(cmd, " "), "", 0, false
+		},
+	}
+}
+
+// testPod returns a pod in the given phase and readiness.
+func testPod(name string, phase corev1.PodPhase, ready bool) corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "wordpress", Labels: map[string]string{"app": "wordpress"}},
+		Status: corev1.PodStatus{
+			Phase:      phase,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func (f *fakeKubernetesAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(parts) == 5 && parts[4] == "pods":
+		f.mu.Lock()
+		f.selects = append(f.selects, r.URL.Query().Get("labelSelector"))
+		list := corev1.PodList{TypeMeta: metav1.TypeMeta{Kind: "PodList", APIVersion: "v1"}}
+		for _, pod := range f.pods {
+			if !f.deleted[pod.Name] {
+				list.Items = append(list.Items, pod)
+			}
+		}
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+
+	case len(parts) == 7 && parts[6] == "exec":
+		f.mu.Lock()
+		deleted := f.deleted[parts[5]]
+		f.mu.Unlock()
+		if deleted {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.serveExec(w, r, parts[3], parts[5])
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeKubernetesAPI) serveExec(w http.ResponseWriter, r *http.Request, namespace, pod string) {
+	query := r.URL.Query()
+	stdin := query.Get("stdin") == "true"
+
+	var streams fakeExecStreams
+	if f.spdyOnly {
+		if wsstream.IsWebSocketRequest(r) {
+			// API servers before 1.29 reject WebSocket exec requests
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, s, err := openSPDYStreams(w, r, stdin)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		streams = s
+	} else {
+		conn, s, err := openWebSocketStreams(w, r, stdin)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		streams = s
+	}
+
+	exec := fakeKubernetesExec{Namespace: namespace, Pod: pod, Container: query.Get("container"), Command: query["command"], Protocol: streams.protocol}
+	if stdin {
+		data, _ := io.ReadAll(streams.stdin)
+		exec.Stdin = string(data)
+	}
+	f.mu.Lock()
+	f.execs = append(f.execs, exec)
+	f.mu.Unlock()
+
+	cmd := exec.Command
+	if len(cmd) > 4 && cmd[2] == pidWrapper {
+		// Strip the PID wrapper: /bin/sh -c <script> sh <cmd...>
+		cmd = cmd[4:]
+		_, _ = streams.stdout.Write([]byte("4242\n"))
+	}
+	if cmd[0] == "kill" {
+		close(f.killed)
+	}
+
+	stdout, stderr, exitCode, block := f.run(cmd)
+	if block {
+		select {
+		case <-f.killed:
+		case <-time.After(10 * time.Second):
+		}
+		return
+	}
+	if stdout != "" {
+		_, _ = streams.stdout.Write([]byte(stdout))
+	}
+	if stderr != "" {
+		_, _ = streams.stderr.Write([]byte(stderr))
+	}
+
+	status := metav1.Status{Status: metav1.StatusSuccess}
+	if exitCode != 0 {
+		status = metav1.Status{
+			Status: metav1.StatusFailure,
+			Reason: remotecommandconsts.NonZeroExitCodeReason,
+			Details: &metav1.StatusDetails{
+				Causes: []metav1.StatusCause{{Type: remotecommandconsts.ExitCodeCauseType, Message: fmt.Sprint(exitCode)}},
+			},
+		}
+	}
+	data, _ := json.Marshal(status)
+	_, _ = streams.status.Write(data)
+}
+
+// fakeExecStreams are the server side of an exec request's streams.
+type fakeExecStreams struct {
+	protocol string
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	status   io.Writer
+}
+
+// openWebSocketStreams upgrades an exec request to the v5 WebSocket protocol.
+func openWebSocketStreams(w http.ResponseWriter, r *http.Request, stdin bool) (io.Closer, fakeExecStreams, error) {
+	stdinChannel := wsstream.IgnoreChannel
+	if stdin {
+		stdinChannel = wsstream.ReadChannel
+	}
+	conn := wsstream.NewConn(map[string]wsstream.ChannelProtocolConfig{
+		remotecommandconsts.StreamProtocolV5Name: {
+			Binary: true,
+			Channels: []wsstream.ChannelType{
+				stdinChannel,
+				wsstream.WriteChannel,
+				wsstream.WriteChannel,
+				wsstream.WriteChannel,
+				wsstream.ReadChannel,
+			},
+		},
+	})
+	protocol, streams, err := conn.Open(w, r)
+	if err != nil {
+		return nil, fakeExecStreams{}, err
+	}
+	// The client waits for a first message before it starts streaming
+	_, _ = streams[1].Write([]byte{})
+	return conn, fakeExecStreams{protocol: protocol, stdin: streams[0], stdout: streams[1], stderr: streams[2], status: streams[3]}, nil
+}
+
+// openSPDYStreams upgrades an exec request to the v4 SPDY protocol and
+// waits for the client to open its streams.
+func openSPDYStreams(w http.ResponseWriter, r *http.Request, stdin bool) (io.Closer, fakeExecStreams, error) {
+	protocol, err := httpstream.Handshake(r, w, []string{remotecommandconsts.StreamProtocolV4Name})
+	if err != nil {
+		return nil, fakeExecStreams{}, err
+	}
+	created := make(chan httpstream.Stream, 4)
+	conn := spdy.NewResponseUpgrader().UpgradeResponse(w, r, func(stream httpstream.Stream, _ <-chan struct{}) error {
+		created <- stream
+		return nil
+	})
+	if conn == nil {
+		return nil, fakeExecStreams{}, fmt.Errorf("upgrade failed")
+	}
+
+	streams := fakeExecStreams{protocol: protocol}
+	expected := 3
+	if stdin {
+		expected++
+	}
+	for i := 0; i < expected; i++ {
+		select {
+		case stream := <-created:
+			switch stream.Headers().Get(corev1.StreamType) {
+			case corev1.StreamTypeStdin:
+				streams.stdin = stream
+			case corev1.StreamTypeStdout:
+				streams.stdout = stream
+			case corev1.StreamTypeStderr:
+				streams.stderr = stream
+			case corev1.StreamTypeError:
+				streams.status = stream
+			}
+		case <-time.After(5 * time.Second):
+			conn.Close()
+			return nil, fakeExecStreams{}, fmt.Errorf("timed out waiting for streams")
+		}
+	}
+	return conn, streams, nil
+}
+
+func (f *fakeKubernetesAPI) exec(i int) fakeKubernetesExec {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.execs[i]
+}
+
+// newTestKubernetesTransport starts the fake API and returns a transport connected to it.
+func newTestKubernetesTransport(t *testing.T, api *fakeKubernetesAPI, pod, selector, container string) *kubernetesTransport {
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	transport, err := newKubernetesTransportForConfig(&rest.Config{Host: server.URL}, "wordpress", pod, selector, container)
+	require.NoError(t, err)
+	return transport
+}
+
+func TestKubernetesTransport_CombinedOutput(t *testing.T) {
+	api := newFakeKubernetesAPI()
+	transport := newTestKubernetesTransport(t, api, "wordpress-0", "", "php")
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "--path=/var/www/html", "plugin", "list")
+	require.NoError(t, err)
+	assert.Equal(t, "ran: wp --path=/var/www/html plugin list", string(output))
+
+	exec := api.exec(0)
+	assert.Equal(t, "wordpress", exec.Namespace)
+	assert.Equal(t, "wordpress-0", exec.Pod)
+	assert.Equal(t, "php", exec.Container)
+	assert.Equal(t, []string{"/bin/sh", "-c", pidWrapper, "sh", "wp", "--path=/var/www/html", "plugin", "list"}, exec.Command)
+	assert.Empty(t, api.selects, "a named pod is used without listing pods")
+
+	transport.env = []string{"WP_DEBUG=1"}
+	_, err = transport.CombinedOutput(context.Background(), "wp", "plugin", "list")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/bin/sh", "-c", pidWrapper, "sh", "sh", "-c", envPrelude, "sh", "wp", "plugin", "list"}, api.exec(1).Command)
+	assert.Equal(t, "WP_DEBUG=1\n\n", api.exec(1).Stdin)
+}
+
+func TestKubernetesTransport_PicksReadyPod(t *testing.T) {
+	terminating := testPod("wordpress-a", corev1.PodRunning, true)
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+	api := newFakeKubernetesAPI(
+		testPod("wordpress-d", corev1.PodRunning, true),
+		testPod("wordpress-b", corev1.PodRunning, false),
+		testPod("wordpress-c", corev1.PodRunning, true),
+		testPod("wordpress-0", corev1.PodPending, false),
+		terminating,
+	)
+	transport := newTestKubernetesTransport(t, api, "", "app=wordpress", "")
+
+	_, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "wordpress-c", api.exec(0).Pod)
+	assert.Equal(t, []string{"app=wordpress"}, api.selects)
+}
+
+func TestKubernetesTransport_ReusesSelectedPod(t *testing.T) {
+	api := newFakeKubernetesAPI(
+		testPod("wordpress-a", corev1.PodRunning, true),
+		testPod("wordpress-b", corev1.PodRunning, true),
+	)
+	api.run = func(cmd []string) (string, string, int, bool) {
+		if cmd[len(cmd)-1] == "missing" {
+			return "", "Error: The 'missing' plugin could not be found.", 1, false
+		}
+		return "", "", 0, false
+	}
+	transport := newTestKubernetesTransport(t, api, "", "app=wordpress", "")
+
+	_, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	_, err = transport.CombinedOutput(context.Background(), "wp", "plugin", "get", "missing")
+	assert.Equal(t, 1, exitCode(err))
+	_, err = transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app=wordpress"}, api.selects)
+
+	// A rollout replaced the pod: the failed command drops it and the next
+	// command selects a pod again
+	api.mu.Lock()
+	api.deleted = map[string]bool{"wordpress-a": true}
+	api.mu.Unlock()
+	_, err = transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	assert.Error(t, err)
+	_, err = transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app=wordpress", "app=wordpress"}, api.selects)
+	assert.Equal(t, "wordpress-b", api.exec(3).Pod)
+}
+
+func TestKubernetesTransport_NoReadyPod(t *testing.T) {
+	api := newFakeKubernetesAPI(testPod("wordpress-0", corev1.PodRunning, false))
+	transport := newTestKubernetesTransport(t, api, "", "app=wordpress", "")
+
+	_, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	assert.ErrorContains(t, err, `no ready pod matches "app=wordpress" in namespace wordpress`)
+}
+
+func TestKubernetesTransport_ExitCode(t *testing.T) {
+	api := newFakeKubernetesAPI()
+	api.run = func(cmd []string) (string, string, int, bool) {
+		return "partial output\n", "Error: The 'missing' plugin could not be found.", 1, false
+	}
+	transport := newTestKubernetesTransport(t, api, "wordpress-0", "", "")
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "plugin", "activate", "missing")
+	var exitErr utilexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 1, exitErr.ExitStatus())
+	// stdout and stderr arrive on separate channels, so their order is not fixed
+	assert.Contains(t, string(output), "partial output\n")
+	assert.Contains(t, string(output), "Error: The 'missing' plugin could not be found.")
+}
+
+func TestKubernetesTransport_Output(t *testing.T) {
+	api := newFakeKubernetesAPI()
+	api.run = func(cmd []string) (string, string, int, bool) {
+		return "partial output\n", "Error: The 'missing' plugin could not be found.", 1, false
+	}
+	transport := newTestKubernetesTransport(t, api, "wordpress-0", "", "")
+
+	stdout, stderr, err := transport.Output(context.Background(), "wp", "plugin", "activate", "missing")
+	assert.Equal(t, 1, exitCode(err))
+	assert.Equal(t, "partial output\n", string(stdout))
+	assert.Equal(t, "Error: The 'missing' plugin could not be found.", string(stderr))
+}
+
+func TestKubernetesTransport_Stdin(t *testing.T) {
+	api := newFakeKubernetesAPI()
+	transport := newTestKubernetesTransport(t, api, "wordpress-0", "", "")
+
+	ctx := withStdin(context.Background(), []byte("s3cret\n"))
+	_, _, err := transport.Output(ctx, "wp", "eval", "echo 1;")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret\n", api.exec(0).Stdin)
+	assert.NotContains(t, api.exec(0).Command, "s3cret\n")
+}
+
+func TestKubernetesTransport_SPDYFallback(t *testing.T) {
+	api := newFakeKubernetesAPI()
+	api.spdyOnly = true
+	api.run = func(cmd []string) (string, string, int, bool) {
+		return "partial output\n", "Error: The 'missing' plugin could not be found.", 1, false
+	}
+	transport := newTestKubernetesTransport(t, api, "wordpress-0", "", "")
+
+	ctx := withStdin(context.Background(), []byte("s3cret\n"))
+	stdout, stderr, err := transport.Output(ctx, "wp", "plugin", "activate", "missing")
+	assert.Equal(t, 1, exitCode(err))
+	assert.Equal(t, "partial output\n", string(stdout))
+	assert.Equal(t, "Error: The 'missing' plugin could not be found.", string(stderr))
+
+	exec := api.exec(0)
+	assert.Equal(t, remotecommandconsts.StreamProtocolV4Name, exec.Protocol)
+	assert.Equal(t, "s3cret\n", exec.Stdin)
+	assert.Equal(t, []string{"/bin/sh", "-c", pidWrapper, "sh", "wp", "plugin", "activate", "missing"}, exec.Command)
+}
+
+func TestKubernetesTransport_CancelKillsCommand(t *testing.T) {
+	api := newFakeKubernetesAPI()
+	api.run = func(cmd []string) (string, string, int, bool) {
+		return "", "", 0, cmd[0] == "wp"
+	}
+	transport := newTestKubernetesTransport(t, api, "wordpress-0", "", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := transport.CombinedOutput(ctx, "wp", "plugin", "install", "akismet")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	select {
+	case <-api.killed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the command was not killed")
+	}
+	assert.Equal(t, []string{"kill", "-KILL", "4242"}, api.exec(1).Command)
+}
+
+func TestKubernetesTransport_Upload(t *testing.T) {
+	api := newFakeKubernetesAPI()
+	transport := newTestKubernetesTransport(t, api, "wordpress-0", "", "")
+
+	local := filepath.Join(t.TempDir(), "plugin.zip")
+	require.NoError(t, os.WriteFile(local, []byte("zip data"), 0o600))
+
+	require.NoError(t, transport.Upload(context.Background(), local, "/tmp/terraform-wordpress-1.zip"))
+	exec := api.exec(0)
+	assert.Equal(t, []string{"/bin/sh", "-c", `cat > "$0"`, "/tmp/terraform-wordpress-1.zip"}, exec.Command)
+	assert.Equal(t, "zip data", exec.Stdin)
+}
+
+func TestNewKubernetesTransport_Kubeconfig(t *testing.T) {
+	api := newFakeKubernetesAPI()
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: default
+clusters:
+- name: test
+  cluster:
+    server: %s
+contexts:
+- name: default
+  context:
+    cluster: test
+    namespace: default
+- name: staging
+  context:
+    cluster: test
+    namespace: staging
+`, server.URL)), 0o600))
+
+	transport, err := newKubernetesTransport(&kubernetesTransportModel{
+		Kubeconfig: types.StringValue(kubeconfig),
+		Context:    types.StringValue("staging"),
+		Pod:        types.StringValue("wordpress-0"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "staging", transport.namespace)
+
+	_, err = transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", api.exec(0).Namespace)
+
+	transport, err = newKubernetesTransport(&kubernetesTransportModel{
+		Kubeconfig: types.StringValue(kubeconfig),
+		Namespace:  types.StringValue("wordpress"),
+		Pod:        types.StringValue("wordpress-0"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "wordpress", transport.namespace)
+
+	_, err = newKubernetesTransport(&kubernetesTransportModel{
+		Kubeconfig: types.StringValue(kubeconfig),
+		Context:    types.StringValue("missing"),
+		Pod:        types.StringValue("wordpress-0"),
+	})
+	assert.ErrorContains(t, err, "unable to load Kubernetes configuration")
+
+	_, err = newKubernetesTransport(&kubernetesTransportModel{Kubeconfig: types.StringValue(kubeconfig)})
+	assert.ErrorContains(t, err, "exactly one of pod and label_selector must be set")
+}
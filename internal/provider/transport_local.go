@@ -0,0 +1,147 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ Commander = &localTransport{}
+
+// localTransportModel is the provider's local block.
+type localTransportModel struct {
+	WPPath  types.String `tfsdk:"wp_path"`
+	PHPPath types.String `tfsdk:"php_path"`
+	Workdir types.String `tfsdk:"workdir"`
+	User    types.String `tfsdk:"user"`
+}
+
+// localTransportAttribute returns the schema of the provider's local block.
+func localTransportAttribute() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Optional:            true,
+		MarkdownDescription: "Settings for the `local` transport.",
+		Attributes: map[string]schema.Attribute{
+			"wp_path": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The path to the `wp` executable or `wp-cli.phar`. Defaults to `wp` on the `PATH`. When `php_path` is set, a name without a directory is looked up on the `PATH` of the user running Terraform.",
+			},
+			"php_path": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The PHP binary to run `wp_path` with, e.g. `/usr/bin/php8.2`. Defaults to running `wp_path` directly.",
+			},
+			"workdir": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The working directory for `wp`. Defaults to `remote_path`.",
+			},
+			"user": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The user to run `wp` as through `sudo -n -u`, e.g. `www-data`. Defaults to the user running Terraform. Plugin archives given as a local `source` path must be readable by this user.",
+			},
+		},
+	}
+}
+
+// localTransport runs wp on the machine Terraform runs on, without --ssh.
+type localTransport struct {
+	wpPath  string
+	phpPath string
+	workdir string
+	user    string
+
+	// env holds NAME=value pairs set for wp commands.
+	env []string
+}
+
+// newLocalTransport returns the transport for the local block, which may be nil.
+func newLocalTransport(m *localTransportModel, remotePath string) (*localTransport, error) {
+	if m == nil {
+		m = &localTransportModel{}
+	}
+	t := &localTransport{
+		wpPath:  defaultStringIfUnset(m.WPPath, "wp"),
+		phpPath: defaultStringIfUnset(m.PHPPath, ""),
+		workdir: defaultStringIfUnset(m.Workdir, remotePath),
+		user:    defaultStringIfUnset(m.User, ""),
+	}
+
+	// PHP opens its script as a file relative to workdir rather than
+	// looking it up on the PATH, so a bare name is resolved here
+	if t.phpPath != "" && filepath.Base(t.wpPath) == t.wpPath {
+		wpPath, err := exec.LookPath(t.wpPath)
+		if err != nil {
+			return nil, fmt.Errorf("wp_path %q must be found on the PATH when php_path is set; set wp_path to the path of wp-cli.phar: %w", t.wpPath, err)
+		}
+		t.wpPath = wpPath
+	}
+	return t, nil
+}
+
+// argv returns the command line that runs name with args locally, and the
+// stdin to run it with.
+func (t *localTransport) argv(name string, args []string, stdin io.Reader) ([]string, io.Reader) {
+	argv := append([]string{name}, args...)
+	if name == "wp" {
+		argv[0] = t.wpPath
+		if t.phpPath != "" {
+			argv = append([]string{t.phpPath}, argv...)
+		}
+		// sudo resets the environment, so the variables are exported after
+		// it from stdin
+		if t.user != "" && len(t.env) > 0 {
+			argv, stdin = envCommand(t.env, argv, stdin)
+		}
+	}
+	if t.user != "" {
+		argv = append([]string{"sudo", "-n", "-u", t.user, "--"}, argv...)
+	}
+	return argv, stdin
+}
+
+// target names the machine commands run on.
+func (t *localTransport) target() string {
+	return "localhost"
+}
+
+// CombinedOutput runs the command and returns combined stdout and stderr.
+// The process is stopped when the context is done.
+func (t *localTransport) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return t.command(ctx, name, args).CombinedOutput()
+}
+
+// Output runs the command and returns stdout and stderr separately.
+func (t *localTransport) Output(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	return runSeparated(t.command(ctx, name, args))
+}
+
+// command returns the process that runs name with args locally.
+func (t *localTransport) command(ctx context.Context, name string, args []string) *exec.Cmd {
+	argv, stdin := t.argv(name, args, commandStdin(ctx))
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = t.workdir
+	cmd.WaitDelay = commandWaitDelay
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	if name == "wp" && t.user == "" && len(t.env) > 0 {
+		cmd.Env = append(os.Environ(), t.env...)
+	}
+	if t.user != "" {
+		// sudo relays SIGTERM to the command but cannot relay SIGKILL;
+		// WaitDelay still kills sudo if the command ignores it
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(syscall.SIGTERM)
+		}
+	}
+	return cmd
+}
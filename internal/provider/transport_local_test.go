@@ -0,0 +1,199 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFakeWP writes a shell script standing in for the wp binary.
+func writeFakeWP(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "wp")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755))
+	return path
+}
+
+// mustLocalTransport returns the transport for m, failing the test when m is
+// invalid.
+func mustLocalTransport(t *testing.T, m *localTransportModel, remotePath string) *localTransport {
+	transport, err := newLocalTransport(m, remotePath)
+	require.NoError(t, err)
+	return transport
+}
+
+func TestLocalTransport_Argv(t *testing.T) {
+	cases := map[string]struct {
+		transport localTransport
+		name      string
+		expected  []string
+		stdin     string
+	}{
+		"defaults": {
+			transport: localTransport{wpPath: "wp"},
+			name:      "wp",
+			expected:  []string{"wp", "plugin", "list"},
+		},
+		"wp path": {
+			transport: localTransport{wpPath: "/usr/local/bin/wp"},
+			name:      "wp",
+			expected:  []string{"/usr/local/bin/wp", "plugin", "list"},
+		},
+		"php path": {
+			transport: localTransport{wpPath: "/opt/wp-cli.phar", phpPath: "/usr/bin/php8.2"},
+			name:      "wp",
+			expected:  []string{"/usr/bin/php8.2", "/opt/wp-cli.phar", "plugin", "list"},
+		},
+		"run as user": {
+			transport: localTransport{wpPath: "wp", user: "www-data"},
+			name:      "wp",
+			expected:  []string{"sudo", "-n", "-u", "www-data", "--", "wp", "plugin", "list"},
+		},
+		"env is set in the environment": {
+			transport: localTransport{wpPath: "/opt/wp-cli.phar", phpPath: "/usr/bin/php8.2", env: []string{"WP_DEBUG=1"}},
+			name:      "wp",
+			expected:  []string{"/usr/bin/php8.2", "/opt/wp-cli.phar", "plugin", "list"},
+		},
+		"env survives sudo on stdin": {
+			transport: localTransport{wpPath: "wp", user: "www-data", env: []string{"WP_DEBUG=1"}},
+			name:      "wp",
+			expected:  []string{"sudo", "-n", "-u", "www-data", "--", "sh", "-c", envPrelude, "sh", "wp", "plugin", "list"},
+			stdin:     "WP_DEBUG=1\n\n",
+		},
+		"other commands keep their name": {
+			transport: localTransport{wpPath: "/opt/wp-cli.phar", phpPath: "/usr/bin/php8.2", user: "www-data"},
+			name:      "rm",
+			expected:  []string{"sudo", "-n", "-u", "www-data", "--", "rm", "plugin", "list"},
+		},
+	}
+	for name, tc := range cases {
+		argv, stdin := tc.transport.argv(tc.name, []string{"plugin", "list"}, nil)
+		assert.Equal(t, tc.expected, argv, name)
+		if tc.stdin == "" {
+			assert.Nil(t, stdin, name)
+			continue
+		}
+		data, err := io.ReadAll(stdin)
+		require.NoError(t, err)
+		assert.Equal(t, tc.stdin, string(data), name)
+	}
+}
+
+func TestLocalTransport_Env(t *testing.T) {
+	transport := mustLocalTransport(t, &localTransportModel{
+		WPPath: types.StringValue(writeFakeWP(t, `echo "$WP_DB_PASSWORD $*"`)),
+	}, t.TempDir())
+	transport.env = []string{"WP_DB_PASSWORD=hunter2"}
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2 cli version\n", string(output))
+}
+
+func TestLocalTransport_CombinedOutput(t *testing.T) {
+	workdir := t.TempDir()
+	transport := mustLocalTransport(t, &localTransportModel{
+		WPPath: types.StringValue(writeFakeWP(t, `echo "$PWD $*"; echo warning >&2`)),
+	}, workdir)
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "--path="+workdir, "plugin", "list")
+	require.NoError(t, err)
+	assert.Equal(t, workdir+" --path="+workdir+" plugin list\nwarning\n", string(output))
+}
+
+func TestLocalTransport_PHPPath(t *testing.T) {
+	script := writeFakeWP(t, `echo "via $0: $*"`)
+	require.NoError(t, os.Chmod(script, 0o644))
+
+	transport := mustLocalTransport(t, &localTransportModel{
+		WPPath:  types.StringValue(script),
+		PHPPath: types.StringValue("/bin/sh"),
+		Workdir: types.StringValue(t.TempDir()),
+	}, "/var/www/html")
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "via "+script+": cli version\n", string(output))
+}
+
+func TestLocalTransport_PHPPathLooksUpWP(t *testing.T) {
+	script := writeFakeWP(t, `echo "via $0: $*"`)
+	t.Setenv("PATH", filepath.Dir(script))
+
+	// php would otherwise open ./wp in workdir
+	transport := mustLocalTransport(t, &localTransportModel{
+		PHPPath: types.StringValue("/bin/sh"),
+		Workdir: types.StringValue(t.TempDir()),
+	}, "")
+	assert.Equal(t, script, transport.wpPath)
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "via "+script+": cli version\n", string(output))
+
+	t.Setenv("PATH", t.TempDir())
+	_, err = newLocalTransport(&localTransportModel{PHPPath: types.StringValue("/bin/sh")}, "")
+	assert.ErrorContains(t, err, "set wp_path to the path of wp-cli.phar")
+
+	// A path is used as given
+	transport = mustLocalTransport(t, &localTransportModel{
+		WPPath:  types.StringValue("bin/wp-cli.phar"),
+		PHPPath: types.StringValue("/bin/sh"),
+	}, "")
+	assert.Equal(t, "bin/wp-cli.phar", transport.wpPath)
+}
+
+func TestLocalTransport_ExitCode(t *testing.T) {
+	transport := mustLocalTransport(t, &localTransportModel{
+		WPPath: types.StringValue(writeFakeWP(t, `echo "Error: The 'missing' plugin could not be found."; exit 1`)),
+	}, t.TempDir())
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "plugin", "activate", "missing")
+	assert.EqualError(t, err, "exit status 1")
+	assert.Equal(t, "Error: The 'missing' plugin could not be found.\n", string(output))
+}
+
+func TestLocalTransport_Output(t *testing.T) {
+	transport := mustLocalTransport(t, &localTransportModel{
+		WPPath: types.StringValue(writeFakeWP(t, `echo "[]"; echo "PHP Deprecated: strlen()" >&2`)),
+	}, t.TempDir())
+
+	stdout, stderr, err := transport.Output(context.Background(), "wp", "plugin", "list", "--format=json")
+	require.NoError(t, err)
+	assert.Equal(t, "[]\n", string(stdout))
+	assert.Equal(t, "PHP Deprecated: strlen()\n", string(stderr))
+}
+
+func TestLocalTransport_Stdin(t *testing.T) {
+	transport := mustLocalTransport(t, &localTransportModel{
+		WPPath: types.StringValue(writeFakeWP(t, `read line; echo "got $line"`)),
+	}, t.TempDir())
+
+	ctx := withStdin(context.Background(), []byte("s3cret\n"))
+	stdout, _, err := transport.Output(ctx, "wp", "eval", "echo 1;")
+	require.NoError(t, err)
+	assert.Equal(t, "got s3cret\n", string(stdout))
+}
+
+func TestLocalTransport_Cancel(t *testing.T) {
+	transport := mustLocalTransport(t, &localTransportModel{
+		WPPath: types.StringValue(writeFakeWP(t, `exec sleep 10`)),
+	}, t.TempDir())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := transport.CombinedOutput(ctx, "wp", "plugin", "install", "akismet")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,544 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const defaultSSHPort = 22
+
+var (
+	_ Commander    = &sshTransport{}
+	_ fileUploader = &sshTransport{}
+)
+
+// sshTransportModel is the provider's ssh block.
+type sshTransportModel struct {
+	Host           types.String      `tfsdk:"host"`
+	Port           types.Int64       `tfsdk:"port"`
+	User           types.String      `tfsdk:"user"`
+	PrivateKey     types.String      `tfsdk:"private_key"`
+	PrivateKeyPath types.String      `tfsdk:"private_key_path"`
+	Agent          types.Bool        `tfsdk:"agent"`
+	KnownHostsPath types.String      `tfsdk:"known_hosts_path"`
+	JumpHost       *sshJumpHostModel `tfsdk:"jump_host"`
+}
+
+// sshJumpHostModel is the jump_host block inside the provider's ssh block.
+type sshJumpHostModel struct {
+	Host           types.String `tfsdk:"host"`
+	Port           types.Int64  `tfsdk:"port"`
+	User           types.String `tfsdk:"user"`
+	PrivateKey     types.String `tfsdk:"private_key"`
+	PrivateKeyPath types.String `tfsdk:"private_key_path"`
+}
+
+// sshHostAttributes returns the connection attributes shared by the ssh block and its jump host.
+func sshHostAttributes(kind string) map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"host": schema.StringAttribute{
+			Required:            true,
+			MarkdownDescription: "The hostname or IP address of the " + kind + ".",
+		},
+		"port": schema.Int64Attribute{
+			Optional:            true,
+			MarkdownDescription: "The SSH port of the " + kind + ". Defaults to `22`.",
+		},
+		"user": schema.StringAttribute{
+			Optional:            true,
+			MarkdownDescription: "The user to log in to the " + kind + " as. Defaults to the current local user.",
+		},
+		"private_key": schema.StringAttribute{
+			Optional:            true,
+			Sensitive:           true,
+			MarkdownDescription: "The PEM encoded private key used to authenticate to the " + kind + ".",
+		},
+		"private_key_path": schema.StringAttribute{
+			Optional:            true,
+			MarkdownDescription: "The path to a private key file used to authenticate to the " + kind + ".",
+		},
+	}
+}
+
+// sshTransportAttribute returns the schema of the provider's ssh block.
+func sshTransportAttribute() schema.SingleNestedAttribute {
+	attributes := sshHostAttributes("WordPress host")
+	attributes["agent"] = schema.BoolAttribute{
+		Optional: true,
+		MarkdownDescription: "Whether to authenticate with the SSH agent listening on `SSH_AUTH_SOCK`. " +
+			"Defaults to `true` when no private key is configured.",
+	}
+	attributes["known_hosts_path"] = schema.StringAttribute{
+		Optional:            true,
+		MarkdownDescription: "The known_hosts file used to verify host keys. Defaults to `~/.ssh/known_hosts`. Only the key types it lists for the host are negotiated.",
+	}
+	jumpAttributes := sshHostAttributes("jump host")
+	jumpAttributes["private_key"] = schema.StringAttribute{
+		Optional:            true,
+		Sensitive:           true,
+		MarkdownDescription: "The PEM encoded private key used to authenticate to the jump host. Defaults to the credentials of the WordPress host.",
+	}
+	attributes["jump_host"] = schema.SingleNestedAttribute{
+		Optional:            true,
+		MarkdownDescription: "A jump host (bastion) to connect through, like `ssh -J`.",
+		Attributes:          jumpAttributes,
+	}
+
+	return schema.SingleNestedAttribute{
+		Optional:            true,
+		MarkdownDescription: "Connection settings for the `ssh` transport.",
+		Attributes:          attributes,
+	}
+}
+
+// sshTransport runs commands on the WordPress host over SSH with the native Go client.
+// Commands share one connection, each running in its own session.
+type sshTransport struct {
+	addr   string
+	config *ssh.ClientConfig
+	jump   *sshTransport
+
+	// env holds NAME=value pairs set for wp commands.
+	env []string
+
+	// mu guards the shared connection, which is dialed on first use.
+	mu        sync.Mutex
+	client    *ssh.Client
+	closeConn func()
+}
+
+// newSSHTransport validates the ssh block and prepares authentication and host key verification.
+func newSSHTransport(m *sshTransportModel) (*sshTransport, error) {
+	knownHostsPath := defaultStringIfUnset(m.KnownHostsPath, "")
+	if knownHostsPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to locate known_hosts: %w", err)
+		}
+		knownHostsPath = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read known_hosts file %s: %w", knownHostsPath, err)
+	}
+
+	auth, err := sshAuthMethods(m.PrivateKey, m.PrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	if defaultBoolIfUnset(m.Agent, len(auth) == 0) {
+		agentAuth, err := sshAgentAuth()
+		if err != nil {
+			return nil, err
+		}
+		auth = append(auth, agentAuth)
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("no SSH credentials configured: set private_key, private_key_path or agent")
+	}
+
+	t, err := newSSHHost(m.Host, m.Port, m.User, auth, hostKeyCallback)
+	if err != nil {
+		return nil, err
+	}
+
+	if j := m.JumpHost; j != nil {
+		jumpAuth, err := sshAuthMethods(j.PrivateKey, j.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(jumpAuth) == 0 {
+			jumpAuth = auth
+		}
+		t.jump, err = newSSHHost(j.Host, j.Port, j.User, jumpAuth, hostKeyCallback)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// newSSHHost builds the transport for a single host.
+func newSSHHost(host types.String, port types.Int64, login types.String, auth []ssh.AuthMethod, hostKeyCallback ssh.HostKeyCallback) (*sshTransport, error) {
+	name := defaultStringIfUnset(host, "")
+	if name == "" {
+		return nil, fmt.Errorf("SSH host must not be empty")
+	}
+
+	p := int64(defaultSSHPort)
+	if !port.IsNull() && !port.IsUnknown() {
+		p = port.ValueInt64()
+	}
+
+	username := defaultStringIfUnset(login, "")
+	if username == "" {
+		current, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine SSH user for %s: %w", name, err)
+		}
+		username = current.Username
+	}
+
+	addr := net.JoinHostPort(name, strconv.FormatInt(p, 10))
+	return &sshTransport{
+		addr: addr,
+		config: &ssh.ClientConfig{
+			User:              username,
+			Auth:              auth,
+			HostKeyCallback:   hostKeyCallback,
+			HostKeyAlgorithms: knownHostKeyAlgorithms(hostKeyCallback, addr),
+		},
+	}, nil
+}
+
+// placeholderHostKey is a host key no known_hosts file lists, used to ask a
+// known_hosts callback which keys it has for a host.
+type placeholderHostKey struct{}
+
+func (placeholderHostKey) Type() string                        { return "placeholder" }
+func (placeholderHostKey) Marshal() []byte                     { return []byte("placeholder") }
+func (placeholderHostKey) Verify([]byte, *ssh.Signature) error { return errors.New("placeholder key") }
+
+// knownHostKeyAlgorithms returns the host key algorithms for the keys
+// known_hosts lists for addr, so the handshake does not settle on a key type
+// the server has but known_hosts does not, which would fail verification. It
+// returns nil, keeping the default algorithms, when no key is listed.
+func knownHostKeyAlgorithms(hostKeyCallback ssh.HostKeyCallback, addr string) []string {
+	var keyErr *knownhosts.KeyError
+	err := hostKeyCallback(addr, &net.TCPAddr{IP: net.IPv4zero}, placeholderHostKey{})
+	if !errors.As(err, &keyErr) {
+		return nil
+	}
+
+	var algorithms []string
+	seen := map[string]bool{}
+	for _, known := range keyErr.Want {
+		keyAlgorithms := []string{known.Key.Type()}
+		if known.Key.Type() == ssh.KeyAlgoRSA {
+			// An RSA key signs with any of these
+			keyAlgorithms = []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+		}
+		for _, algorithm := range keyAlgorithms {
+			if !seen[algorithm] {
+				seen[algorithm] = true
+				algorithms = append(algorithms, algorithm)
+			}
+		}
+	}
+	return algorithms
+}
+
+// sshAuthMethods loads the private key given inline or by path, if any.
+func sshAuthMethods(key, keyPath types.String) ([]ssh.AuthMethod, error) {
+	pem := []byte(defaultStringIfUnset(key, ""))
+	if p := defaultStringIfUnset(keyPath, ""); p != "" {
+		if len(pem) > 0 {
+			return nil, fmt.Errorf("only one of private_key and private_key_path may be set")
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read SSH private key: %w", err)
+		}
+		pem = data
+	}
+	if len(pem) == 0 {
+		return nil, nil
+	}
+
+	signer, err := ssh.ParsePrivateKey(pem)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse SSH private key: %w", err)
+	}
+	return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+}
+
+// sshAgentAuth authenticates with the keys held by the SSH agent. The agent
+// connection stays open for the life of the provider, since signing happens
+// on every handshake.
+func sshAgentAuth() (ssh.AuthMethod, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, fmt.Errorf("SSH agent authentication requested but SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to SSH agent: %w", err)
+	}
+	return ssh.PublicKeysCallback(agent.NewClient(conn).Signers), nil
+}
+
+// dial connects to the host, through the jump host if one is configured.
+// The returned function closes the connection and any jump connection.
+func (t *sshTransport) dial(ctx context.Context) (*ssh.Client, func(), error) {
+	var conn net.Conn
+	closeJump := func() {}
+
+	if t.jump != nil {
+		jumpClient, closeFn, err := t.jump.dial(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("jump host %s: %w", t.jump.addr, err)
+		}
+		conn, err = jumpClient.DialContext(ctx, "tcp", t.addr)
+		if err != nil {
+			closeFn()
+			return nil, nil, fmt.Errorf("unable to reach %s through jump host %s: %w", t.addr, t.jump.addr, err)
+		}
+		closeJump = closeFn
+	} else {
+		var d net.Dialer
+		var err error
+		conn, err = d.DialContext(ctx, "tcp", t.addr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to connect to %s: %w", t.addr, err)
+		}
+	}
+
+	// Abort the handshake when the context is canceled
+	handshakeDone := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-handshakeDone:
+		}
+	}()
+	c, chans, reqs, err := ssh.NewClientConn(conn, t.addr, t.config)
+	close(handshakeDone)
+	if err != nil {
+		conn.Close()
+		closeJump()
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, fmt.Errorf("SSH handshake with %s failed: %w", t.addr, err)
+	}
+
+	client := ssh.NewClient(c, chans, reqs)
+	return client, func() {
+		client.Close()
+		closeJump()
+	}, nil
+}
+
+// CombinedOutput runs the command on the remote host and returns combined
+// stdout and stderr. The remote process is killed when the context is done.
+func (t *sshTransport) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	// sshd rejects most variables sent with a session, so they are
+	// exported on the host from stdin instead
+	name, args, stdin := withEnv(t.env, name, args, commandStdin(ctx))
+	var output syncBuffer
+	err := t.run(ctx, shellJoin(append([]string{name}, args...)), stdin, &output, &output)
+	return output.Bytes(), err
+}
+
+// Output runs the command on the remote host and returns stdout and stderr
+// separately.
+func (t *sshTransport) Output(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	name, args, stdin := withEnv(t.env, name, args, commandStdin(ctx))
+	var stdout, stderr syncBuffer
+	err := t.run(ctx, shellJoin(append([]string{name}, args...)), stdin, &stdout, &stderr)
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// Upload streams a local file to remotePath on the host.
+func (t *sshTransport) Upload(ctx context.Context, localPath, remotePath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var output syncBuffer
+	if err := t.run(ctx, "cat > "+shellQuote(remotePath), file, &output, &output); err != nil {
+		return fmt.Errorf("uploading %s to %s failed: %v: %s", localPath, remotePath, err, output.Bytes())
+	}
+	return nil
+}
+
+// connection returns the connection shared by all commands, dialing it if
+// there is none yet.
+func (t *sshTransport) connection(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client == nil {
+		start := time.Now()
+		client, closeFn, err := t.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		t.client, t.closeConn = client, closeFn
+		tflog.SubsystemDebug(ctx, logTransport, "Connected to SSH host", map[string]interface{}{
+			logFieldTarget:   t.target(),
+			logFieldDuration: time.Since(start).String(),
+		})
+	}
+	return t.client, nil
+}
+
+// target names the user and host commands run as and on.
+func (t *sshTransport) target() string {
+	return t.config.User + "@" + t.addr
+}
+
+// disconnect closes client if it is still the shared connection, so that
+// the next command dials a new one.
+func (t *sshTransport) disconnect(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client == client {
+		t.closeConn()
+		t.client, t.closeConn = nil, nil
+	}
+}
+
+// Close closes the shared connection.
+func (t *sshTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client != nil {
+		t.closeConn()
+		t.client, t.closeConn = nil, nil
+	}
+	return nil
+}
+
+// session opens a session on the shared connection. A connection that was
+// lost, e.g. to an idle timeout on a bastion, is replaced once. When the
+// host refuses further sessions on it (see MaxSessions in sshd_config), the
+// session gets a connection of its own. The returned function closes the
+// session and any connection opened for it.
+func (t *sshTransport) session(ctx context.Context) (*ssh.Session, func(), error) {
+	for attempt := 1; ; attempt++ {
+		client, err := t.connection(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		session, err := client.NewSession()
+		if err == nil {
+			return session, func() { session.Close() }, nil
+		}
+
+		var openErr *ssh.OpenChannelError
+		if errors.As(err, &openErr) {
+			tflog.SubsystemDebug(ctx, logTransport, "SSH host refused another session, opening a dedicated connection", map[string]interface{}{
+				logFieldTarget: t.target(),
+				logFieldError:  err.Error(),
+			})
+			return t.dedicatedSession(ctx)
+		}
+		tflog.SubsystemDebug(ctx, logTransport, "SSH connection lost, reconnecting", map[string]interface{}{
+			logFieldTarget: t.target(),
+			logFieldError:  err.Error(),
+		})
+		t.disconnect(client)
+		if attempt == 2 {
+			return nil, nil, fmt.Errorf("unable to open SSH session on %s: %w", t.addr, err)
+		}
+	}
+}
+
+// dedicatedSession opens a session on a new connection that is closed
+// together with the session.
+func (t *sshTransport) dedicatedSession(ctx context.Context) (*ssh.Session, func(), error) {
+	client, closeFn, err := t.dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		closeFn()
+		return nil, nil, fmt.Errorf("unable to open SSH session on %s: %w", t.addr, err)
+	}
+	return session, func() {
+		session.Close()
+		closeFn()
+	}, nil
+}
+
+// run executes command in a new session.
+func (t *sshTransport) run(ctx context.Context, command string, stdin io.Reader, stdout, stderr io.Writer) error {
+	session, closeFn, err := t.session(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = stderr
+	if err := session.Start(command); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		_ = session.Signal(ssh.SIGKILL)
+		return ctx.Err()
+	}
+}
+
+// syncBuffer is a bytes.Buffer that is safe to write from the stdout and
+// stderr copying goroutines at once.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
+
+// shellJoin quotes args for a POSIX shell and joins them into a command line.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes s for a POSIX shell, leaving plain words unchanged.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,+@%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,698 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSSHExec handles an exec request on the test server and returns the exit status.
+// ctx is canceled when the client sends a signal.
+type testSSHExec func(ctx context.Context, command string, stdin io.Reader, out io.Writer) uint32
+
+// testSSHServer is an in-process SSH server that runs commands through a Go callback.
+type testSSHServer struct {
+	addr    string
+	hostKey ssh.Signer
+
+	mu       sync.Mutex
+	commands []string
+	signals  []string
+	conns    []*ssh.ServerConn
+	// sessionLimit, when set, caps the concurrent sessions per connection
+	// like MaxSessions in sshd_config.
+	sessionLimit int
+}
+
+func newTestSSHKey(t *testing.T) (ssh.Signer, string) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	require.NoError(t, err)
+	return signer, string(pem.EncodeToMemory(block))
+}
+
+// newTestSSHServer starts a server that accepts clientKey and runs exec for
+// each command. It offers an ed25519 host key, followed by any extraHostKeys.
+func newTestSSHServer(t *testing.T, clientKey ssh.PublicKey, exec testSSHExec, extraHostKeys ...ssh.Signer) *testSSHServer {
+	hostKey, _ := newTestSSHKey(t)
+	s := &testSSHServer{hostKey: hostKey}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown public key")
+		},
+	}
+	config.AddHostKey(hostKey)
+	for _, key := range extraHostKeys {
+		config.AddHostKey(key)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	s.addr = listener.Addr().String()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, config, exec)
+		}
+	}()
+	return s
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig, exec testSSHExec) {
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	s.mu.Lock()
+	s.conns = append(s.conns, serverConn)
+	limit := s.sessionLimit
+	s.mu.Unlock()
+	go ssh.DiscardRequests(reqs)
+
+	var open atomic.Int32
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "session":
+			if limit > 0 && int(open.Load()) >= limit {
+				_ = newChannel.Reject(ssh.Prohibited, "open failed")
+				continue
+			}
+			open.Add(1)
+			go func() {
+				defer open.Add(-1)
+				s.serveSession(newChannel, exec)
+			}()
+		case "direct-tcpip":
+			go serveDirectTCPIP(newChannel)
+		default:
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+func (s *testSSHServer) serveSession(newChannel ssh.NewChannel, exec testSSHExec) {
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for req := range requests {
+		switch req.Type {
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			s.mu.Lock()
+			s.commands = append(s.commands, payload.Command)
+			s.mu.Unlock()
+			_ = req.Reply(true, nil)
+
+			go func() {
+				status := exec(ctx, payload.Command, channel, channel)
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+				channel.Close()
+			}()
+		case "signal":
+			var payload struct{ Signal string }
+			_ = ssh.Unmarshal(req.Payload, &payload)
+			s.mu.Lock()
+			s.signals = append(s.signals, payload.Signal)
+			s.mu.Unlock()
+			cancel()
+		default:
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+// serveDirectTCPIP forwards a jump host connection to its destination.
+func serveDirectTCPIP(newChannel ssh.NewChannel) {
+	var payload struct {
+		Host     string
+		Port     uint32
+		OrigHost string
+		OrigPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		target.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	go func() {
+		_, _ = io.Copy(target, channel)
+		target.Close()
+	}()
+	_, _ = io.Copy(channel, target)
+	channel.Close()
+}
+
+// connections returns how many SSH connections the server accepted.
+func (s *testSSHServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// dropConnections closes every connection, like a bastion timing out.
+func (s *testSSHServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *testSSHServer) receivedCommands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// writeKnownHosts writes a known_hosts file trusting the given servers.
+func writeKnownHosts(t *testing.T, servers ...*testSSHServer) string {
+	var lines []string
+	for _, s := range servers {
+		lines = append(lines, knownhosts.Line([]string{knownhosts.Normalize(s.addr)}, s.hostKey.PublicKey()))
+	}
+	p := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(p, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+	return p
+}
+
+// testSSHModel returns an ssh block connecting to s with the given private key.
+func testSSHModel(s *testSSHServer, privateKey, knownHosts string) *sshTransportModel {
+	host, port, _ := net.SplitHostPort(s.addr)
+	p, _ := strconv.ParseInt(port, 10, 64)
+	return &sshTransportModel{
+		Host:           types.StringValue(host),
+		Port:           types.Int64Value(p),
+		User:           types.StringValue("deploy"),
+		PrivateKey:     types.StringValue(privateKey),
+		KnownHostsPath: types.StringValue(knownHosts),
+	}
+}
+
+func echoExec(_ context.Context, command string, _ io.Reader, out io.Writer) uint32 {
+	if strings.Contains(command, "fail") {
+		fmt.Fprint(out, "Error: something failed")
+		return 1
+	}
+	fmt.Fprintf(out, "ran: %s", command)
+	return 0
+}
+
+func TestSSHTransport_CombinedOutput(t *testing.T) {
+	clientKey, clientPEM := newTestSSHKey(t)
+	server := newTestSSHServer(t, clientKey.PublicKey(), echoExec)
+
+	transport, err := newSSHTransport(testSSHModel(server, clientPEM, writeKnownHosts(t, server)))
+	require.NoError(t, err)
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "--path=/var/www/html", "option", "update", "blogname", "It's mine")
+	require.NoError(t, err)
+	assert.Equal(t, `ran: wp --path=/var/www/html option update blogname 'It'\''s mine'`, string(output))
+
+	output, err = transport.CombinedOutput(context.Background(), "wp", "fail")
+	var exitErr *ssh.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 1, exitErr.ExitStatus())
+	assert.Equal(t, "Error: something failed", string(output))
+}
+
+func TestSSHTransport_Output(t *testing.T) {
+	clientKey, clientPEM := newTestSSHKey(t)
+	server := newTestSSHServer(t, clientKey.PublicKey(), func(_ context.Context, _ string, _ io.Reader, out io.Writer) uint32 {
+		fmt.Fprint(out.(ssh.Channel).Stderr(), "PHP Warning: Undefined index")
+		fmt.Fprint(out, "[]")
+		return 0
+	})
+
+	transport, err := newSSHTransport(testSSHModel(server, clientPEM, writeKnownHosts(t, server)))
+	require.NoError(t, err)
+
+	stdout, stderr, err := transport.Output(context.Background(), "wp", "plugin", "list", "--format=json")
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(stdout))
+	assert.Equal(t, "PHP Warning: Undefined index", string(stderr))
+}
+
+func TestSSHTransport_Stdin(t *testing.T) {
+	clientKey, clientPEM := newTestSSHKey(t)
+	server := newTestSSHServer(t, clientKey.PublicKey(), func(_ context.Context, _ string, stdin io.Reader, out io.Writer) uint32 {
+		line, _ := bufio.NewReader(stdin).ReadString('\n')
+		fmt.Fprint(out, "got "+line)
+		return 0
+	})
+
+	transport, err := newSSHTransport(testSSHModel(server, clientPEM, writeKnownHosts(t, server)))
+	require.NoError(t, err)
+
+	ctx := withStdin(context.Background(), []byte("s3cret\n"))
+	stdout, _, err := transport.Output(ctx, "wp", "eval", "echo 1;")
+	require.NoError(t, err)
+	assert.Equal(t, "got s3cret\n", string(stdout))
+	assert.NotContains(t, server.receivedCommands()[0], "s3cret")
+}
+
+func TestSSHTransport_Env(t *testing.T) {
+	clientKey, clientPEM := newTestSSHKey(t)
+	server := newTestSSHServer(t, clientKey.PublicKey(), func(_ context.Context, _ string, stdin io.Reader, out io.Writer) uint32 {
+		data, _ := io.ReadAll(stdin)
+		fmt.Fprint(out, string(data))
+		return 0
+	})
+
+	transport, err := newSSHTransport(testSSHModel(server, clientPEM, writeKnownHosts(t, server)))
+	require.NoError(t, err)
+	transport.env = []string{"WP_CLI_CACHE_DIR=/tmp/wp cache", "WP_DB_PASSWORD=hunter2"}
+
+	ctx := withStdin(context.Background(), []byte("s3cret\n"))
+	output, err := transport.CombinedOutput(ctx, "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "WP_CLI_CACHE_DIR=/tmp/wp cache\nWP_DB_PASSWORD=hunter2\n\ns3cret\n", string(output))
+	assert.Equal(t, shellJoin([]string{"sh", "-c", envPrelude, "sh", "wp", "cli", "version"}), server.receivedCommands()[0])
+	assert.NotContains(t, server.receivedCommands()[0], "hunter2")
+}
+
+func TestSSHTransport_ReusesConnection(t *testing.T) {
+	clientKey, clientPEM := newTestSSHKey(t)
+	server := newTestSSHServer(t, clientKey.PublicKey(), echoExec)
+
+	transport, err := newSSHTransport(testSSHModel(server, clientPEM, writeKnownHosts(t, server)))
+	require.NoError(t, err)
+	defer transport.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := transport.CombinedOutput(context.Background(), "wp", "plugin", "list")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, server.connections())
+
+	// A dropped connection is replaced on the next command
+	server.dropConnections()
+	output, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "ran: wp cli version", string(output))
+	assert.Equal(t, 2, server.connections())
+}
+
+func TestSSHTransport_SessionLimit(t *testing.T) {
+	release := make(chan struct{})
+	clientKey, clientPEM := newTestSSHKey(t)
+	server := newTestSSHServer(t, clientKey.PublicKey(), func(ctx context.Context, command string, stdin io.Reader, out io.Writer) uint32 {
+		if strings.Contains(command, "slow") {
+			<-release
+		}
+		return echoExec(ctx, command, stdin, out)
+	})
+	server.sessionLimit = 1
+
+	transport, err := newSSHTransport(testSSHModel(server, clientPEM, writeKnownHosts(t, server)))
+	require.NoError(t, err)
+	defer transport.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := transport.CombinedOutput(context.Background(), "wp", "slow")
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return len(server.receivedCommands()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// The host refuses a second session, so the command gets its own connection
+	output, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "ran: wp cli version", string(output))
+	assert.Equal(t, 2, server.connections())
+
+	close(release)
+	require.NoError(t, <-done)
+}
+
+func TestSSHTransport_ThroughWPConfig(t *testing.T) {
+	clientKey, clientPEM := newTestSSHKey(t)
+	server := newTestSSHServer(t, clientKey.PublicKey(), echoExec)
+
+	transport, err := newSSHTransport(testSSHModel(server, clientPEM, writeKnownHosts(t, server)))
+	require.NoError(t, err)
+
+	cfg := &WPConfig{RemotePath: "/var/www/html", Exec: transport}
+	output, err := runWPWithOutput(context.Background(), cfg, "plugin", "list")
+	require.NoError(t, err)
+	assert.Equal(t, "ran: wp --path=/var/www/html plugin list", output)
+}
+
+func TestSSHTransport_UnknownHostKey(t *testing.T) {
+	clientKey, clientPEM := newTestSSHKey(t)
+	server := newTestSSHServer(t, clientKey.PublicKey(), echoExec)
+	other := newTestSSHServer(t, clientKey.PublicKey(), echoExec)
+
+	// known_hosts lists a different key for the server's address
+	line := knownhosts.Line([]string{knownhosts.Normalize(server.addr)}, other.hostKey.PublicKey())
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(knownHosts, []byte(line+"\n"), 0o600))
+
+	transport, err := newSSHTransport(testSSHModel(server, clientPEM, knownHosts))
+	require.NoError(t, err)
+
+	_, err = transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	assert.ErrorContains(t, err, "SSH handshake with "+server.addr+" failed")
+	var keyErr *knownhosts.KeyError
+	assert.ErrorAs(t, err, &keyErr)
+	assert.Empty(t, server.receivedCommands())
+}
+
+func TestSSHTransport_HostKeyAlgorithms(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecdsaSigner, err := ssh.NewSignerFromKey(ecdsaKey)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaSigner, err := ssh.NewSignerFromKey(rsaKey)
+	require.NoError(t, err)
+
+	// The client prefers ECDSA and RSA host keys to ed25519 by default, but
+	// known_hosts only lists the server's ed25519 key
+	clientKey, clientPEM := newTestSSHKey(t)
+	server := newTestSSHServer(t, clientKey.PublicKey(), echoExec, ecdsaSigner, rsaSigner)
+	knownHosts := writeKnownHosts(t, server)
+
+	transport, err := newSSHTransport(testSSHModel(server, clientPEM, knownHosts))
+	require.NoError(t, err)
+	assert.Equal(t, []string{ssh.KeyAlgoED25519}, transport.config.HostKeyAlgorithms)
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "ran: wp cli version", string(output))
+}
+
+func TestKnownHostKeyAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPublic, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	edKey, _ := newTestSSHKey(t)
+
+	lines := knownhosts.Line([]string{"wp.example.com"}, rsaPublic) + "\n" +
+		knownhosts.Line([]string{"wp.example.com"}, edKey.PublicKey()) + "\n"
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(knownHosts, []byte(lines), 0o600))
+	callback, err := knownhosts.New(knownHosts)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA, ssh.KeyAlgoED25519},
+		knownHostKeyAlgorithms(callback, "wp.example.com:22"))
+
+	// Unknown hosts keep the default algorithms and fail verification later
+	assert.Nil(t, knownHostKeyAlgorithms(callback, "other.example.com:22"))
+}
+
+func TestSSHTransport_JumpHost(t *testing.T) {
+	clientKey, clientPEM := newTestSSHKey(t)
+	target := newTestSSHServer(t, clientKey.PublicKey(), echoExec)
+	jump := newTestSSHServer(t, clientKey.PublicKey(), echoExec)
+
+	model := testSSHModel(target, clientPEM, writeKnownHosts(t, target, jump))
+	jumpHost, jumpPort, _ := net.SplitHostPort(jump.addr)
+	port, _ := strconv.ParseInt(jumpPort, 10, 64)
+	model.JumpHost = &sshJumpHostModel{
+		Host: types.StringValue(jumpHost),
+		Port: types.Int64Value(port),
+		User: types.StringValue("bastion"),
+	}
+
+	transport, err := newSSHTransport(model)
+	require.NoError(t, err)
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "ran: wp cli version", string(output))
+	assert.Equal(t, []string{"wp cli version"}, target.receivedCommands())
+	assert.Empty(t, jump.receivedCommands())
+}
+
+func TestSSHTransport_CancelKillsCommand(t *testing.T) {
+	clientKey, clientPEM := newTestSSHKey(t)
+	server := newTestSSHServer(t, clientKey.PublicKey(), func(ctx context.Context, _ string, _ io.Reader, _ io.Writer) uint32 {
+		<-ctx.Done()
+		return 137
+	})
+
+	transport, err := newSSHTransport(testSSHModel(server, clientPEM, writeKnownHosts(t, server)))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = transport.CombinedOutput(ctx, "wp", "plugin", "install", "akismet")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.signals) == 1 && server.signals[0] == string(ssh.SIGKILL)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSSHTransport_Upload(t *testing.T) {
+	var mu sync.Mutex
+	uploaded := map[string]string{}
+
+	clientKey, clientPEM := newTestSSHKey(t)
+	server := newTestSSHServer(t, clientKey.PublicKey(), func(_ context.Context, command string, stdin io.Reader, _ io.Writer) uint32 {
+		target, ok := strings.CutPrefix(command, "cat > ")
+		if !ok {
+			return 127
+		}
+		data, _ := io.ReadAll(stdin)
+		mu.Lock()
+		uploaded[target] = string(data)
+		mu.Unlock()
+		return 0
+	})
+
+	transport, err := newSSHTransport(testSSHModel(server, clientPEM, writeKnownHosts(t, server)))
+	require.NoError(t, err)
+
+	local := filepath.Join(t.TempDir(), "plugin.zip")
+	require.NoError(t, os.WriteFile(local, []byte("zip data"), 0o600))
+
+	require.NoError(t, transport.Upload(context.Background(), local, "/tmp/plugin.zip"))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]string{"/tmp/plugin.zip": "zip data"}, uploaded)
+}
+
+func TestSSHTransport_Agent(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	clientKey, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: priv}))
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_ = agent.ServeAgent(keyring, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+
+	server := newTestSSHServer(t, clientKey.PublicKey(), echoExec)
+	model := testSSHModel(server, "", writeKnownHosts(t, server))
+	model.PrivateKey = types.StringNull()
+
+	transport, err := newSSHTransport(model)
+	require.NoError(t, err)
+
+	output, err := transport.CombinedOutput(context.Background(), "wp", "cli", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "ran: wp cli version", string(output))
+}
+
+func TestNewSSHTransport_Errors(t *testing.T) {
+	_, clientPEM := newTestSSHKey(t)
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(knownHosts, nil, 0o600))
+
+	base := func() *sshTransportModel {
+		return &sshTransportModel{
+			Host:           types.StringValue("example.com"),
+			PrivateKey:     types.StringValue(clientPEM),
+			KnownHostsPath: types.StringValue(knownHosts),
+		}
+	}
+
+	noCredentials := base()
+	noCredentials.PrivateKey = types.StringNull()
+	noCredentials.Agent = types.BoolValue(false)
+
+	badKey := base()
+	badKey.PrivateKey = types.StringValue("not a key")
+
+	bothKeys := base()
+	bothKeys.PrivateKeyPath = types.StringValue("/home/deploy/.ssh/id_ed25519")
+
+	noAgent := base()
+	noAgent.PrivateKey = types.StringNull()
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	missingKnownHosts := base()
+	missingKnownHosts.KnownHostsPath = types.StringValue(filepath.Join(t.TempDir(), "missing"))
+
+	cases := map[string]struct {
+		model *sshTransportModel
+		err   string
+	}{
+		"no credentials":      {noCredentials, "no SSH credentials configured"},
+		"bad key":             {badKey, "unable to parse SSH private key"},
+		"both keys":           {bothKeys, "only one of private_key and private_key_path"},
+		"agent without sock":  {noAgent, "SSH_AUTH_SOCK is not set"},
+		"missing known_hosts": {missingKnownHosts, "unable to read known_hosts file"},
+	}
+	for name, tc := range cases {
+		_, err := newSSHTransport(tc.model)
+		assert.ErrorContains(t, err, tc.err, name)
+	}
+
+	transport, err := newSSHTransport(base())
+	require.NoError(t, err)
+	assert.Equal(t, "example.com:22", transport.addr)
+	assert.NotEmpty(t, transport.config.User)
+}
+
+func TestShellQuote(t *testing.T) {
+	cases := map[string]string{
+		"wp":                    "wp",
+		"--path=/var/www/html":  "--path=/var/www/html",
+		"":                      "''",
+		"It's":                  `'It'\''s'`,
+		"two words":             "'two words'",
+		"$(rm -rf /)":           "'$(rm -rf /)'",
+		"a;b":                   "'a;b'",
+		`{"key":"value"}`:       `'{"key":"value"}'`,
+		"--url=https://x.test/": "--url=https://x.test/",
+	}
+	for input, expected := range cases {
+		assert.Equal(t, expected, shellQuote(input), input)
+	}
+	assert.Equal(t, "wp option get 'my option'", shellJoin([]string{"wp", "option", "get", "my option"}))
+}
+
+func TestShellJoin_HostileArgs(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no POSIX shell available")
+	}
+
+	hostile := []string{
+		"$(touch pwned)",
+		"`id`",
+		"a'b",
+		"'",
+		`"; rm -rf / #`,
+		"foo --exec=phpinfo()",
+		"line\nbreak",
+		"tab\tseparated",
+		"*",
+		"~root",
+		"$HOME",
+		"a|b&c>d<e",
+		`back\slash`,
+		"",
+		" padded ",
+	}
+	for _, arg := range hostile {
+		t.Run(arg, func(t *testing.T) {
+			// the remote shell runs the joined command line, so it must
+			// see exactly the arguments it was given
+			out, err := exec.Command("/bin/sh", "-c", `printf '%s\0' `+shellJoin([]string{arg, "next"})).Output()
+			require.NoError(t, err)
+			assert.Equal(t, []string{arg, "next", ""}, strings.Split(string(out), "\x00"))
+		})
+	}
+}
+
+func TestSyncBuffer(t *testing.T) {
+	var b syncBuffer
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = b.Write([]byte("x"))
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, b.Bytes(), 10)
+}
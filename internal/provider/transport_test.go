@@ -0,0 +1,220 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTransport(t *testing.T) {
+	sshBlock := &sshTransportModel{Host: types.StringValue("example.com")}
+
+	cases := map[string]struct {
+		data     WordpressProviderModel
+		expected []string
+	}{
+		"default with ssh_target": {
+			data: WordpressProviderModel{Transport: types.StringNull(), SSHTarget: types.StringValue("docker:wp")},
+		},
+		"default without ssh_target": {
+			data:     WordpressProviderModel{Transport: types.StringNull(), SSHTarget: types.StringNull()},
+			expected: []string{"Missing ssh_target"},
+		},
+		"wp-cli with ssh block": {
+			data:     WordpressProviderModel{Transport: types.StringValue("wp-cli"), SSHTarget: types.StringValue("docker:wp"), SSH: sshBlock},
+			expected: []string{"Unexpected ssh block"},
+		},
+		"ssh": {
+			data: WordpressProviderModel{Transport: types.StringValue("ssh"), SSHTarget: types.StringNull(), SSH: sshBlock},
+		},
+		"ssh without block": {
+			data:     WordpressProviderModel{Transport: types.StringValue("ssh"), SSHTarget: types.StringNull()},
+			expected: []string{"Missing ssh block"},
+		},
+		"ssh with ssh_target": {
+			data:     WordpressProviderModel{Transport: types.StringValue("ssh"), SSHTarget: types.StringValue("user@host"), SSH: sshBlock},
+			expected: []string{"Unexpected ssh_target"},
+		},
+		"docker": {
+			data: WordpressProviderModel{Transport: types.StringValue("docker"), SSHTarget: types.StringNull(), Docker: &dockerTransportModel{}},
+		},
+		"docker without block": {
+			data:     WordpressProviderModel{Transport: types.StringValue("docker"), SSHTarget: types.StringNull(), SSH: sshBlock},
+			expected: []string{"Unexpected ssh block", "Missing docker block"},
+		},
+		"kubernetes": {
+			data: WordpressProviderModel{Transport: types.StringValue("kubernetes"), SSHTarget: types.StringNull(), Kubernetes: &kubernetesTransportModel{}},
+		},
+		"kubernetes without block": {
+			data:     WordpressProviderModel{Transport: types.StringValue("kubernetes"), SSHTarget: types.StringNull(), Docker: &dockerTransportModel{}},
+			expected: []string{"Unexpected docker block", "Missing kubernetes block"},
+		},
+		"local": {
+			data: WordpressProviderModel{Transport: types.StringValue("local"), SSHTarget: types.StringNull(), RemotePath: types.StringValue("/var/www/html")},
+		},
+		"local with ssh_target": {
+			data:     WordpressProviderModel{Transport: types.StringValue("local"), SSHTarget: types.StringValue("user@host"), RemotePath: types.StringValue("/var/www/html")},
+			expected: []string{"Unexpected ssh_target"},
+		},
+		"local without remote_path": {
+			data:     WordpressProviderModel{Transport: types.StringValue("local"), SSHTarget: types.StringNull(), RemotePath: types.StringNull(), SSH: sshBlock},
+			expected: []string{"Unexpected ssh block", "Missing remote_path"},
+		},
+		"unknown transport": {
+			data: WordpressProviderModel{Transport: types.StringUnknown(), SSHTarget: types.StringNull()},
+		},
+	}
+	for name, tc := range cases {
+		var diags diag.Diagnostics
+		validateTransport(tc.data, &diags)
+
+		var summaries []string
+		for _, d := range diags {
+			summaries = append(summaries, d.Summary())
+		}
+		assert.Equal(t, tc.expected, summaries, name)
+	}
+}
+
+func TestNewTransport_Default(t *testing.T) {
+	var diags diag.Diagnostics
+	assert.Nil(t, newTransport(WordpressProviderModel{Transport: types.StringNull()}, &diags))
+	assert.False(t, diags.HasError())
+	assert.Equal(t, cmdExec, (&WPConfig{}).commander())
+}
+
+func TestNewTransport_InvalidSSH(t *testing.T) {
+	var diags diag.Diagnostics
+	newTransport(WordpressProviderModel{
+		Transport: types.StringValue("ssh"),
+		SSH: &sshTransportModel{
+			Host:           types.StringValue("example.com"),
+			PrivateKey:     types.StringValue("not a key"),
+			KnownHostsPath: types.StringValue("/dev/null"),
+		},
+	}, &diags)
+	assert.True(t, diags.HasError())
+	assert.Equal(t, "Invalid SSH configuration", diags[0].Summary())
+}
+
+func TestNewTransport_Docker(t *testing.T) {
+	var diags diag.Diagnostics
+	transport := newTransport(WordpressProviderModel{
+		Transport:  types.StringValue("docker"),
+		RemotePath: types.StringValue("/var/www/html"),
+		Docker: &dockerTransportModel{
+			Container: types.StringValue("wordpress"),
+			Host:      types.StringValue("tcp://127.0.0.1:2375"),
+		},
+	}, &diags)
+	assert.False(t, diags.HasError())
+	if assert.IsType(t, &dockerTransport{}, transport) {
+		assert.Equal(t, "/var/www/html", transport.(*dockerTransport).workdir)
+	}
+
+	newTransport(WordpressProviderModel{
+		Transport: types.StringValue("docker"),
+		Docker:    &dockerTransportModel{Container: types.StringValue("wordpress"), Host: types.StringValue("ftp://docker")},
+	}, &diags)
+	assert.Equal(t, "Invalid Docker configuration", diags[0].Summary())
+}
+
+func TestNewTransport_InvalidKubernetes(t *testing.T) {
+	var diags diag.Diagnostics
+	newTransport(WordpressProviderModel{
+		Transport: types.StringValue("kubernetes"),
+		Kubernetes: &kubernetesTransportModel{
+			Kubeconfig: types.StringValue(filepath.Join(t.TempDir(), "missing")),
+			Pod:        types.StringValue("wordpress-0"),
+		},
+	}, &diags)
+	assert.True(t, diags.HasError())
+	assert.Equal(t, "Invalid Kubernetes configuration", diags[0].Summary())
+}
+
+func TestNewTransport_Local(t *testing.T) {
+	var diags diag.Diagnostics
+	transport := newTransport(WordpressProviderModel{
+		Transport:  types.StringValue("local"),
+		RemotePath: types.StringValue("/var/www/html"),
+	}, &diags)
+	assert.False(t, diags.HasError())
+	assert.Equal(t, &localTransport{wpPath: "wp", workdir: "/var/www/html"}, transport)
+}
+
+func TestNewTransport_Env(t *testing.T) {
+	env := types.MapValueMust(types.StringType, map[string]attr.Value{
+		"WP_DEBUG":         types.StringValue("1"),
+		"WP_CLI_CACHE_DIR": types.StringValue("/tmp/wp-cli"),
+	})
+
+	var diags diag.Diagnostics
+	transport := newTransport(WordpressProviderModel{Transport: types.StringNull(), Env: env}, &diags)
+	assert.Equal(t, defaultCommander{env: []string{"WP_CLI_CACHE_DIR=/tmp/wp-cli", "WP_DEBUG=1"}}, transport)
+
+	transport = newTransport(WordpressProviderModel{Transport: types.StringValue("local"), Env: env}, &diags)
+	assert.Equal(t, []string{"WP_CLI_CACHE_DIR=/tmp/wp-cli", "WP_DEBUG=1"}, transport.(*localTransport).env)
+	assert.False(t, diags.HasError())
+}
+
+func TestWithEnv(t *testing.T) {
+	name, args, stdin := withEnv([]string{"A=1", "B=two words"}, "wp", []string{"plugin", "list"}, strings.NewReader("s3cret\n"))
+	assert.Equal(t, "sh", name)
+	assert.Equal(t, []string{"-c", envPrelude, "sh", "wp", "plugin", "list"}, args)
+	assert.NotContains(t, strings.Join(args, " "), "two words")
+
+	// The prelude exports the variables and leaves the rest of stdin to
+	// the command, here a shell standing in for wp
+	args[3] = "sh"
+	args = append(args[:4], "-c", `read line; echo "$A|$B|$line"`)
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = stdin
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "1|two words|s3cret\n", string(output))
+
+	name, args, stdin = withEnv([]string{"A=1"}, "rm", []string{"-f", "/tmp/x"}, nil)
+	assert.Equal(t, "rm", name)
+	assert.Equal(t, []string{"-f", "/tmp/x"}, args)
+	assert.Nil(t, stdin)
+
+	name, args, stdin = withEnv(nil, "wp", []string{"plugin", "list"}, nil)
+	assert.Equal(t, "wp", name)
+	assert.Equal(t, []string{"plugin", "list"}, args)
+	assert.Nil(t, stdin)
+
+	_, _, stdin = withEnv([]string{"A=1"}, "wp", []string{"plugin", "list"}, nil)
+	data, err := io.ReadAll(stdin)
+	require.NoError(t, err)
+	assert.Equal(t, "A=1\n\n", string(data))
+}
+
+func TestPIDCapture(t *testing.T) {
+	var output bytes.Buffer
+	p := &pidCapture{w: &output}
+	assert.Equal(t, "", p.PID())
+
+	for _, chunk := range []string{"42", "42\nfirst ", "second"} {
+		n, err := p.Write([]byte(chunk))
+		assert.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+	assert.Equal(t, "4242", p.PID())
+	assert.Equal(t, "first second", output.String())
+
+	notPID := &pidCapture{w: &output}
+	_, _ = notPID.Write([]byte("sh: not found\n"))
+	assert.Equal(t, "", notPID.PID())
+}
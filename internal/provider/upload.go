@@ -0,0 +1,135 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// remoteTempDir is where uploaded files are staged on the WordPress host.
+const remoteTempDir = "/tmp"
+
+// parsedSSHTarget is a parsed WP-CLI --ssh value:
+// [<scheme>:][<user>@]<host|container>[:<port>][<path>].
+type parsedSSHTarget struct {
+	Scheme string
+	User   string
+	Host   string
+	Port   string
+}
+
+// parseSSHTarget splits a WP-CLI --ssh value into its components.
+func parseSSHTarget(target string) parsedSSHTarget {
+	var t parsedSSHTarget
+
+	if scheme, rest, ok := strings.Cut(target, ":"); ok {
+		switch scheme {
+		case "ssh", "docker", "docker-compose", "docker-compose-run", "vagrant":
+			t.Scheme = scheme
+			target = rest
+		}
+	}
+	if t.Scheme == "" {
+		t.Scheme = "ssh"
+	}
+
+	if user, rest, ok := strings.Cut(target, "@"); ok {
+		t.User = user
+		target = rest
+	}
+
+	// Drop the optional path; it is passed to WP-CLI separately
+	if i := strings.Index(target, "/"); i >= 0 {
+		target = target[:i]
+	}
+
+	if host, port, ok := strings.Cut(target, ":"); ok {
+		t.Host = host
+		t.Port = port
+	} else {
+		t.Host = target
+	}
+	return t
+}
+
+// uploadFile copies a local file to the host WP-CLI runs against and returns
+// the remote path along with a function that removes it again.
+func uploadFile(ctx context.Context, cfg *WPConfig, localPath string) (string, func(), error) {
+	noop := func() {}
+
+	uploader, native := cfg.commander().(fileUploader)
+
+	// WP-CLI runs locally, so it can read the file directly
+	if !native && cfg.SSHTarget == "" {
+		return localPath, noop, nil
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", noop, err
+	}
+	remotePath := fmt.Sprintf("%s/terraform-wordpress-%s.zip", remoteTempDir, hex.EncodeToString(suffix))
+
+	// Remove the file even when the operation itself was canceled
+	cleanupCtx := context.WithoutCancel(ctx)
+
+	if native {
+		tflog.SubsystemDebug(withLogging(ctx), logTransport, "Uploading file", map[string]interface{}{
+			logFieldTarget: cfg.target(),
+			"local_path":   localPath,
+			"remote_path":  remotePath,
+		})
+		if err := uploader.Upload(ctx, localPath, remotePath); err != nil {
+			return "", noop, err
+		}
+		cleanup := func() {
+			_, _ = runCommand(cleanupCtx, cfg, "rm", "-f", remotePath)
+		}
+		return remotePath, cleanup, nil
+	}
+
+	target := parseSSHTarget(cfg.SSHTarget)
+	switch target.Scheme {
+	case "docker":
+		copyArgs := []string{"cp", localPath, target.Host + ":" + remotePath}
+		if _, err := runCommand(ctx, cfg, "docker", copyArgs...); err != nil {
+			return "", noop, err
+		}
+		cleanup := func() {
+			_, _ = runCommand(cleanupCtx, cfg, "docker", "exec", target.Host, "rm", "-f", remotePath)
+		}
+		return remotePath, cleanup, nil
+
+	case "ssh":
+		host := target.Host
+		if target.User != "" {
+			host = target.User + "@" + host
+		}
+
+		copyArgs := []string{}
+		removeArgs := []string{}
+		if target.Port != "" {
+			copyArgs = append(copyArgs, "-P", target.Port)
+			removeArgs = append(removeArgs, "-p", target.Port)
+		}
+		copyArgs = append(copyArgs, localPath, host+":"+remotePath)
+		removeArgs = append(removeArgs, host, "rm", "-f", remotePath)
+
+		if _, err := runCommand(ctx, cfg, "scp", copyArgs...); err != nil {
+			return "", noop, err
+		}
+		cleanup := func() {
+			_, _ = runCommand(cleanupCtx, cfg, "ssh", removeArgs...)
+		}
+		return remotePath, cleanup, nil
+	}
+
+	return "", noop, fmt.Errorf("uploading files is not supported for %s targets", target.Scheme)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSSHTarget(t *testing.T) {
+	cases := map[string]parsedSSHTarget{
+		"docker:wordpress":                {Scheme: "docker", Host: "wordpress"},
+		"docker:www-data@wordpress":       {Scheme: "docker", User: "www-data", Host: "wordpress"},
+		"user@example.com":                {Scheme: "ssh", User: "user", Host: "example.com"},
+		"ssh:deploy@example.com:2222/var": {Scheme: "ssh", User: "deploy", Host: "example.com", Port: "2222"},
+		"example.com:2222":                {Scheme: "ssh", Host: "example.com", Port: "2222"},
+		"vagrant:default":                 {Scheme: "vagrant", Host: "default"},
+	}
+	for input, expected := range cases {
+		assert.Equal(t, expected, parseSSHTarget(input), input)
+	}
+}
+
+func TestUploadFile_Local(t *testing.T) {
+	remote, cleanup, err := uploadFile(context.Background(), &WPConfig{}, "/tmp/plugin.zip")
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/plugin.zip", remote)
+	cleanup()
+}
+
+func TestUploadFile_Docker(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+	rec := &recordingCommander{}
+	cmdExec = rec
+
+	remote, cleanup, err := uploadFile(context.Background(), &WPConfig{SSHTarget: "docker:wp-1"}, "/local/plugin.zip")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(remote, "/tmp/terraform-wordpress-"))
+	cleanup()
+
+	assert.Equal(t, [][]string{
+		{"docker", "cp", "/local/plugin.zip", "wp-1:" + remote},
+		{"docker", "exec", "wp-1", "rm", "-f", remote},
+	}, rec.calls)
+}
+
+func TestUploadFile_SSH(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+	rec := &recordingCommander{}
+	cmdExec = rec
+
+	remote, cleanup, err := uploadFile(context.Background(), &WPConfig{SSHTarget: "deploy@example.com:2222"}, "/local/plugin.zip")
+	assert.NoError(t, err)
+	cleanup()
+
+	assert.Equal(t, [][]string{
+		{"scp", "-P", "2222", "/local/plugin.zip", "deploy@example.com:" + remote},
+		{"ssh", "-p", "2222", "deploy@example.com", "rm", "-f", remote},
+	}, rec.calls)
+}
+
+func TestUploadFile_Failure(t *testing.T) {
+	prev := cmdExec
+	defer func() { cmdExec = prev }()
+	cmdExec = mockCommander{output: []byte("No such container"), err: assert.AnError}
+
+	_, cleanup, err := uploadFile(context.Background(), &WPConfig{SSHTarget: "docker:missing"}, "/local/plugin.zip")
+	cleanup()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "No such container")
+}
+
+func TestUploadFile_Unsupported(t *testing.T) {
+	_, _, err := uploadFile(context.Background(), &WPConfig{SSHTarget: "vagrant:default"}, "/local/plugin.zip")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "vagrant")
+}
+
+// uploadingCommander records uploads and commands like a native transport.
+type uploadingCommander struct {
+	recordingCommander
+	uploads [][2]string
+}
+
+func (u *uploadingCommander) Upload(_ context.Context, localPath, remotePath string) error {
+	u.uploads = append(u.uploads, [2]string{localPath, remotePath})
+	return nil
+}
+
+func TestUploadFile_NativeTransport(t *testing.T) {
+	transport := &uploadingCommander{}
+	cfg := &WPConfig{Exec: transport}
+
+	remote, cleanup, err := uploadFile(context.Background(), cfg, "/local/plugin.zip")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(remote, "/tmp/terraform-wordpress-"))
+	cleanup()
+
+	assert.Equal(t, [][2]string{{"/local/plugin.zip", remote}}, transport.uploads)
+	assert.Equal(t, [][]string{{"rm", "-f", remote}}, transport.calls)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+)
+
+var _ validator.String = durationValidator{}
+
+// durationValidator checks that a string is a non-negative Go duration such as "500ms" or "2m".
+type durationValidator struct{}
+
+func (v durationValidator) Description(_ context.Context) string {
+	return "value must be a non-negative duration string such as 500ms, 30s or 2m"
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v durationValidator) ValidateString(_ context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if _, err := parseDurationIfSet(req.ConfigValue); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid duration", err.Error())
+	}
+}
+
+// slugPattern matches plugin and theme slugs, the names of their directories
+// under wp-content.
+var slugPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]*$`)
+
+// loginPattern matches the user names WordPress accepts, which only contain
+// letters, digits, spaces and the characters _ . - @.
+var loginPattern = regexp.MustCompile(`^[A-Za-z0-9_.@][A-Za-z0-9 _.@-]*$`)
+
+// rolePattern matches role names such as 'editor' or 'shop_manager'.
+var rolePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_-]*$`)
+
+// emailPattern loosely matches an email address. WordPress validates the
+// address itself; this only keeps it from being read as a WP‑CLI flag.
+var emailPattern = regexp.MustCompile(`^[^-\s@][^\s@]*@[^\s@]+$`)
+
+// optionNamePattern matches option names. WordPress allows almost any name,
+// so it only rules out leading dashes, whitespace and control characters.
+var optionNamePattern = regexp.MustCompile(`^[^-\s\p{Cc}][^\s\p{Cc}]*$`)
+
+// notFlagPattern matches values that cannot be mistaken for a command line
+// flag.
+var notFlagPattern = regexp.MustCompile(`^[^-]`)
+
+// globalFlagPattern matches WP‑CLI global flags such as --debug or
+// --require=path.
+var globalFlagPattern = regexp.MustCompile(`^--[A-Za-z][A-Za-z0-9_-]*(=.*)?$`)
+
+// envNamePattern matches environment variable names.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// singleLinePattern matches values without line breaks, which transports
+// send env values as lines of stdin.
+var singleLinePattern = regexp.MustCompile(`^[^\r\n]*$`)
@@ -0,0 +1,106 @@
+// Copyright (c) Avishay Bar
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDurationValidator(t *testing.T) {
+	cases := []struct {
+		value   types.String
+		wantErr bool
+	}{
+		{types.StringNull(), false},
+		{types.StringUnknown(), false},
+		{types.StringValue("1s"), false},
+		{types.StringValue("1m30s"), false},
+		{types.StringValue("10"), true},
+		{types.StringValue("-5s"), true},
+	}
+	for _, tc := range cases {
+		resp := &validator.StringResponse{}
+		durationValidator{}.ValidateString(context.Background(), validator.StringRequest{
+			Path:        path.Root("poll_interval"),
+			ConfigValue: tc.value,
+		}, resp)
+		assert.Equal(t, tc.wantErr, resp.Diagnostics.HasError(), tc.value.String())
+	}
+}
+
+func TestArgumentPatterns(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern *regexp.Regexp
+		value   string
+		valid   bool
+	}{
+		{"plugin slug", slugPattern, "akismet", true},
+		{"plugin slug with dots", slugPattern, "wp-super-cache.1", true},
+		{"single file plugin", slugPattern, "hello", true},
+		{"flag", slugPattern, "--activate-network", false},
+		{"short flag", slugPattern, "-h", false},
+		{"flag after slug", slugPattern, "foo --exec=phpinfo()", false},
+		{"command substitution", slugPattern, "$(touch /tmp/pwned)", false},
+		{"path traversal", slugPattern, "../../wp-config", false},
+		{"hidden directory", slugPattern, ".git", false},
+		{"plugin file", slugPattern, "akismet/akismet.php", false},
+		{"empty slug", slugPattern, "", false},
+		{"newline", slugPattern, "akismet\n--skip-plugins", false},
+
+		{"login", loginPattern, "jane.doe@example", true},
+		{"login with space", loginPattern, "Jane Doe", true},
+		{"login flag", loginPattern, "--role=administrator", false},
+		{"login shell", loginPattern, "bob;id", false},
+		{"login quote", loginPattern, "bob'", false},
+
+		{"role", rolePattern, "shop_manager", true},
+		{"role flag", rolePattern, "--network", false},
+		{"role space", rolePattern, "editor administrator", false},
+
+		{"email", emailPattern, "jane+wp@example.com", true},
+		{"email flag", emailPattern, "--user_pass=x@example.com", false},
+		{"email space", emailPattern, "jane@example.com --role=administrator", false},
+		{"not an email", emailPattern, "jane", false},
+
+		{"option", optionNamePattern, "blogname", true},
+		{"transient", optionNamePattern, "_transient_feed_mod_abc", true},
+		{"widget option", optionNamePattern, "widget_recent-posts", true},
+		{"option flag", optionNamePattern, "--autoload=no", false},
+		{"option space", optionNamePattern, "blogname --exec=x", false},
+		{"option NUL", optionNamePattern, "blogname\x00", false},
+
+		{"source URL", notFlagPattern, "https://example.com/my-plugin.zip", true},
+		{"source path", notFlagPattern, "./build/my-plugin.zip", true},
+		{"source flag", notFlagPattern, "--exec=phpinfo()", false},
+
+		{"global flag", globalFlagPattern, "--skip-packages", true},
+		{"global flag with value", globalFlagPattern, "--skip-plugins=akismet,jetpack", true},
+		{"positional extra arg", globalFlagPattern, "plugin", false},
+		{"short flag extra arg", globalFlagPattern, "-v", false},
+		{"separator extra arg", globalFlagPattern, "--", false},
+
+		{"env name", envNamePattern, "WP_CLI_CACHE_DIR", true},
+		{"env option", envNamePattern, "-i", false},
+		{"env assignment", envNamePattern, "A=B", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &validator.StringResponse{}
+			stringvalidator.RegexMatches(tc.pattern, "").ValidateString(context.Background(), validator.StringRequest{
+				Path:        path.Root("name"),
+				ConfigValue: types.StringValue(tc.value),
+			}, resp)
+			assert.Equal(t, tc.valid, !resp.Diagnostics.HasError(), "%q", tc.value)
+		})
+	}
+}